@@ -0,0 +1,145 @@
+package gomatrix
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+func (c *Client) WhoAmI(ctx context.Context) (string, error) {
+	c.mux.RLock()
+	userID := c.userID
+	c.mux.RUnlock()
+	if userID != "" {
+		return userID, nil
+	}
+
+	resp, err := c.whoAmI(ctx)
+	return resp.UserID, err
+}
+
+// DeviceID returns the ID of the device the client is logged in with.
+func (c *Client) DeviceID(ctx context.Context) (string, error) {
+	c.mux.RLock()
+	deviceID := c.deviceID
+	c.mux.RUnlock()
+	if deviceID != "" {
+		return deviceID, nil
+	}
+
+	resp, err := c.whoAmI(ctx)
+	return resp.DeviceID, err
+}
+
+func (c *Client) whoAmI(ctx context.Context) (apiWhoAmIResp, error) {
+	var respData apiWhoAmIResp
+	err := c.doJSON(ctx, http.MethodGet, "/_matrix/client/v3/account/whoami", nil, &respData)
+	if err != nil {
+		return apiWhoAmIResp{}, fmt.Errorf("failed to get the current user: %w", err)
+	}
+
+	c.mux.Lock()
+	c.userID = respData.UserID
+	if respData.DeviceID != "" {
+		c.deviceID = respData.DeviceID
+	}
+	c.mux.Unlock()
+
+	return respData, nil
+}
+
+// GetAccountData decodes the global account data of the given type into v.
+// Use IsNotFound to check whether the data has never been set.
+func (c *Client) GetAccountData(ctx context.Context, dataType string, v any) error {
+	path, err := c.accountDataPath(ctx, "", dataType)
+	if err != nil {
+		return err
+	}
+
+	err = c.doJSON(ctx, http.MethodGet, path, nil, v)
+	if err != nil {
+		return fmt.Errorf("failed to get %s account data: %w", dataType, err)
+	}
+	return nil
+}
+
+func (c *Client) SetAccountData(ctx context.Context, dataType string, v any) error {
+	path, err := c.accountDataPath(ctx, "", dataType)
+	if err != nil {
+		return err
+	}
+
+	err = c.doJSON(ctx, http.MethodPut, path, v, nil)
+	if err != nil {
+		return fmt.Errorf("failed to set %s account data: %w", dataType, err)
+	}
+	return nil
+}
+
+// GetRoomAccountData decodes the room account data of the given type into v.
+// Use IsNotFound to check whether the data has never been set.
+func (c *Client) GetRoomAccountData(ctx context.Context, roomID, dataType string, v any) error {
+	path, err := c.accountDataPath(ctx, roomID, dataType)
+	if err != nil {
+		return err
+	}
+
+	err = c.doJSON(ctx, http.MethodGet, path, nil, v)
+	if err != nil {
+		return fmt.Errorf("failed to get %s room account data: %w", dataType, err)
+	}
+	return nil
+}
+
+func (c *Client) SetRoomAccountData(ctx context.Context, roomID, dataType string, v any) error {
+	path, err := c.accountDataPath(ctx, roomID, dataType)
+	if err != nil {
+		return err
+	}
+
+	err = c.doJSON(ctx, http.MethodPut, path, v, nil)
+	if err != nil {
+		return fmt.Errorf("failed to set %s room account data: %w", dataType, err)
+	}
+	return nil
+}
+
+func (c *Client) accountDataPath(ctx context.Context, roomID, dataType string) (string, error) {
+	userID, err := c.WhoAmI(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if roomID == "" {
+		return fmt.Sprintf("/_matrix/client/v3/user/%s/account_data/%s", url.PathEscape(userID), url.PathEscape(dataType)), nil
+	}
+
+	return fmt.Sprintf(
+		"/_matrix/client/v3/user/%s/rooms/%s/account_data/%s",
+		url.PathEscape(userID), url.PathEscape(roomID), url.PathEscape(dataType),
+	), nil
+}
+
+// OpenIDToken proves the user identity to third-party services such as identity servers.
+type OpenIDToken struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	MatrixServerName string `json:"matrix_server_name"`
+	ExpiresIn        int    `json:"expires_in"`
+}
+
+func (c *Client) RequestOpenIDToken(ctx context.Context) (OpenIDToken, error) {
+	userID, err := c.WhoAmI(ctx)
+	if err != nil {
+		return OpenIDToken{}, err
+	}
+
+	var token OpenIDToken
+	path := fmt.Sprintf("/_matrix/client/v3/user/%s/openid/request_token", url.PathEscape(userID))
+	err = c.doJSON(ctx, http.MethodPost, path, struct{}{}, &token)
+	if err != nil {
+		return OpenIDToken{}, fmt.Errorf("failed to request an OpenID token: %w", err)
+	}
+	return token, nil
+}
@@ -0,0 +1,75 @@
+package gomatrix
+
+import (
+	"context"
+	"slices"
+)
+
+const (
+	// BreadcrumbsAccountDataType is the list of the recently viewed rooms kept by Element and the clients following it.
+	BreadcrumbsAccountDataType = "im.vector.setting.breadcrumbs"
+	maxBreadcrumbs             = 20
+)
+
+// AccountData is the global account data of a type, usually namespaced by the application, e.g. com.example.settings,
+// decoded into T.
+type AccountData[T any] struct {
+	client   *Client
+	dataType string
+}
+
+func NewAccountData[T any](client *Client, dataType string) AccountData[T] {
+	return AccountData[T]{client: client, dataType: dataType}
+}
+
+// Get returns the account data, the zero T if it has never been set.
+func (d AccountData[T]) Get(ctx context.Context) (T, error) {
+	var v T
+	err := d.client.GetAccountData(ctx, d.dataType, &v)
+	if err != nil && !IsNotFound(err) {
+		return v, err
+	}
+	return v, nil
+}
+
+func (d AccountData[T]) Set(ctx context.Context, v T) error {
+	return d.client.SetAccountData(ctx, d.dataType, v)
+}
+
+// Update changes the account data with fn and stores it. The other devices of the user may change it in between,
+// their changes being lost then.
+func (d AccountData[T]) Update(ctx context.Context, fn func(v *T) error) error {
+	v, err := d.Get(ctx)
+	if err != nil {
+		return err
+	}
+	err = fn(&v)
+	if err != nil {
+		return err
+	}
+	return d.Set(ctx, v)
+}
+
+type BreadcrumbsContent struct {
+	// RecentRooms are the IDs of the recently viewed rooms, most recent first.
+	RecentRooms []string `json:"recent_rooms"`
+}
+
+// GetRecentRooms returns the rooms the user recently viewed on any of the devices, most recent first.
+func (c *Client) GetRecentRooms(ctx context.Context) ([]string, error) {
+	breadcrumbs, err := c.breadcrumbs().Get(ctx)
+	return breadcrumbs.RecentRooms, err
+}
+
+// AddRecentRoom moves the room to the top of the recently viewed rooms, keeping the 20 most recent ones.
+func (c *Client) AddRecentRoom(ctx context.Context, roomID string) error {
+	return c.breadcrumbs().Update(ctx, func(breadcrumbs *BreadcrumbsContent) error {
+		rooms := slices.DeleteFunc(breadcrumbs.RecentRooms, func(id string) bool { return id == roomID })
+		breadcrumbs.RecentRooms = slices.Insert(rooms, 0, roomID)[:min(len(rooms)+1, maxBreadcrumbs)]
+		return nil
+	})
+}
+
+func (c *Client) breadcrumbs() AccountData[BreadcrumbsContent] {
+	return NewAccountData[BreadcrumbsContent](c, BreadcrumbsAccountDataType)
+}
@@ -0,0 +1,83 @@
+package gomatrix
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+type AccountStatus string
+
+const (
+	AccountActive AccountStatus = "active"
+	// AccountLocked accounts are logged out of every request until a server administrator unlocks them.
+	// https://spec.matrix.org/v1.13/client-server-api/#account-locking
+	AccountLocked AccountStatus = "locked"
+	// AccountSuspended accounts can read but can't send, join or change anything.
+	// https://spec.matrix.org/v1.13/client-server-api/#account-suspension
+	AccountSuspended AccountStatus = "suspended"
+)
+
+// AccountStatusError is returned for the requests rejected because the account is locked or suspended.
+// The requests failing so are not retried and don't trigger a new login, so the client doesn't hammer the server.
+type AccountStatusError struct {
+	Status AccountStatus
+	Err    *HTTPError
+}
+
+func (e *AccountStatusError) Error() string {
+	return fmt.Sprintf("the account is %s: %s", e.Status, e.Err.Message)
+}
+
+func (e *AccountStatusError) Unwrap() error {
+	return e.Err
+}
+
+// IsAccountRestricted reports whether the request failed because the account is locked or suspended.
+func IsAccountRestricted(err error) bool {
+	var statusErr *AccountStatusError
+	return errors.As(err, &statusErr)
+}
+
+// newAccountStatusError returns nil unless the error is of a locked or suspended account.
+func newAccountStatusError(httpErr *HTTPError) *AccountStatusError {
+	switch httpErr.ErrCode {
+	case "M_USER_LOCKED":
+		return &AccountStatusError{Status: AccountLocked, Err: httpErr}
+	case "M_USER_SUSPENDED":
+		return &AccountStatusError{Status: AccountSuspended, Err: httpErr}
+	}
+	return nil
+}
+
+// AccountStatus returns the status of the account as of the latest request, active until told otherwise.
+func (c *Client) AccountStatus() AccountStatus {
+	c.statusMux.Lock()
+	defer c.statusMux.Unlock()
+	return c.accountStatus
+}
+
+// setAccountStatus records the status and reports its changes to Config.OnAccountStatus.
+func (c *Client) setAccountStatus(status AccountStatus, err error) {
+	c.statusMux.Lock()
+	changed := c.accountStatus != status
+	c.accountStatus = status
+	c.statusMux.Unlock()
+
+	if changed && c.onAccountStatus != nil {
+		c.onAccountStatus(status, err)
+	}
+}
+
+// observeSuccess marks the restricted account active again once a request proves it.
+func (c *Client) observeSuccess(method string) {
+	switch c.AccountStatus() {
+	case AccountLocked:
+		c.setAccountStatus(AccountActive, nil)
+	case AccountSuspended:
+		// a suspended account can still read
+		if method != http.MethodGet {
+			c.setAccountStatus(AccountActive, nil)
+		}
+	}
+}
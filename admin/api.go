@@ -0,0 +1,14 @@
+package admin
+
+type apiDeleteRoomReq struct {
+	NewRoomUserID string `json:"new_room_user_id,omitempty"`
+	RoomName      string `json:"room_name,omitempty"`
+	Message       string `json:"message,omitempty"`
+	Block         bool   `json:"block"`
+	Purge         bool   `json:"purge"`
+	ForcePurge    bool   `json:"force_purge,omitempty"`
+}
+
+type apiDeleteRoomResp struct {
+	DeleteID string `json:"delete_id"`
+}
@@ -0,0 +1,80 @@
+// Package admin is a client of the Synapse admin API, used by the server administrators to moderate
+// the rooms of the homeserver. The access token must belong to a server admin.
+// https://element-hq.github.io/synapse/latest/usage/administration/admin_api/
+package admin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	gomatrix "github.com/beldeveloper/go-matrix"
+)
+
+const requestTimeout = time.Minute
+
+type Config struct {
+	// Server is the base URL of the homeserver, e.g. https://matrix.example.org.
+	Server      string
+	AccessToken string
+	HttpClient  *http.Client
+}
+
+type Client struct {
+	server     string
+	token      string
+	httpClient *http.Client
+}
+
+func NewClient(cfg Config) *Client {
+	if cfg.HttpClient == nil {
+		cfg.HttpClient = &http.Client{Timeout: requestTimeout}
+	}
+	return &Client{server: strings.TrimRight(cfg.Server, "/"), token: cfg.AccessToken, httpClient: cfg.HttpClient}
+}
+
+func (c *Client) do(ctx context.Context, method, path string, reqData, respData any) error {
+	var body io.Reader
+	if reqData != nil {
+		payload, err := json.Marshal(reqData)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request payload: %w", err)
+		}
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.server+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create a request: %w", err)
+	}
+	if reqData != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to do a request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return gomatrix.NewHTTPError(resp, respBody)
+	}
+
+	if respData == nil {
+		return nil
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(respData)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const defaultDeletePollInterval = 2 * time.Second
+
+type DeleteRoomOptions struct {
+	// Block prevents the local users from joining the room again.
+	Block bool
+	// Purge removes the room and its history from the database once all the local users left it.
+	Purge bool
+	// ForcePurge purges the room even if some local users could not be made to leave it.
+	ForcePurge bool
+	// Message is posted to the new room the local members are moved to; they are only made to leave without it.
+	Message string
+	// NewRoomUserID creates the room the members are moved to as this user, the room being named RoomName.
+	NewRoomUserID string
+	RoomName      string
+	// PollInterval is how often DeleteRoom checks the deletion status, 2 seconds by default.
+	PollInterval time.Duration
+}
+
+type DeleteState string
+
+const (
+	DeleteScheduled DeleteState = "scheduled"
+	DeleteActive    DeleteState = "active"
+	DeleteComplete  DeleteState = "complete"
+	DeleteFailed    DeleteState = "failed"
+)
+
+type DeleteStatus struct {
+	DeleteID string      `json:"delete_id"`
+	RoomID   string      `json:"room_id"`
+	Status   DeleteState `json:"status"`
+	// Error tells why the deletion failed.
+	Error        string         `json:"error,omitempty"`
+	ShutdownRoom ShutdownResult `json:"shutdown_room"`
+}
+
+type ShutdownResult struct {
+	KickedUsers       []string `json:"kicked_users"`
+	FailedToKickUsers []string `json:"failed_to_kick_users"`
+	LocalAliases      []string `json:"local_aliases"`
+	NewRoomID         string   `json:"new_room_id,omitempty"`
+}
+
+// DeleteRoom makes the local users leave the room, optionally blocking and purging it, and waits for
+// the deletion to complete. The deletion carries on in the background if the context is done first;
+// its status is then available with DeleteRoomStatus.
+// https://element-hq.github.io/synapse/latest/admin_api/rooms.html#version-2-new-version
+func (c *Client) DeleteRoom(ctx context.Context, roomID string, opts DeleteRoomOptions) (DeleteStatus, error) {
+	deleteID, err := c.StartDeleteRoom(ctx, roomID, opts)
+	if err != nil {
+		return DeleteStatus{}, err
+	}
+
+	interval := opts.PollInterval
+	if interval <= 0 {
+		interval = defaultDeletePollInterval
+	}
+	for {
+		status, err := c.DeleteRoomStatus(ctx, deleteID)
+		if err != nil {
+			return DeleteStatus{}, err
+		}
+		switch status.Status {
+		case DeleteComplete:
+			return status, nil
+		case DeleteFailed:
+			return status, fmt.Errorf("failed to delete the room: %s", status.Error)
+		}
+
+		select {
+		case <-ctx.Done():
+			return status, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// StartDeleteRoom schedules the deletion of the room and returns the ID of the deletion, without waiting for it.
+func (c *Client) StartDeleteRoom(ctx context.Context, roomID string, opts DeleteRoomOptions) (string, error) {
+	if opts.Message != "" && opts.NewRoomUserID == "" {
+		return "", errors.New("the message is posted to the new room, which needs NewRoomUserID")
+	}
+
+	var respData apiDeleteRoomResp
+	err := c.do(ctx, http.MethodDelete, "/_synapse/admin/v2/rooms/"+url.PathEscape(roomID), apiDeleteRoomReq{
+		NewRoomUserID: opts.NewRoomUserID,
+		RoomName:      opts.RoomName,
+		Message:       opts.Message,
+		Block:         opts.Block,
+		Purge:         opts.Purge,
+		ForcePurge:    opts.ForcePurge,
+	}, &respData)
+	if err != nil {
+		return "", fmt.Errorf("failed to delete the room: %w", err)
+	}
+	return respData.DeleteID, nil
+}
+
+// DeleteRoomStatus returns the progress of the room deletion.
+func (c *Client) DeleteRoomStatus(ctx context.Context, deleteID string) (DeleteStatus, error) {
+	var status DeleteStatus
+	err := c.do(ctx, http.MethodGet, "/_synapse/admin/v2/rooms/delete_status/"+url.PathEscape(deleteID), nil, &status)
+	if err != nil {
+		return DeleteStatus{}, fmt.Errorf("failed to get the room deletion status: %w", err)
+	}
+	if status.DeleteID == "" {
+		status.DeleteID = deleteID
+	}
+	return status, nil
+}
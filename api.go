@@ -1,21 +1,325 @@
 package gomatrix
 
 type apiLoginReq struct {
-	Type     string `json:"type"`
-	User     string `json:"user"`
-	Password string `json:"password"`
+	Type         string `json:"type"`
+	User         string `json:"user"`
+	Password     string `json:"password"`
+	RefreshToken bool   `json:"refresh_token,omitempty"`
+}
+
+type apiLoginResp struct {
+	UserID       string `json:"user_id"`
+	AccessToken  string `json:"access_token"`
+	DeviceID     string `json:"device_id"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresInMs  int64  `json:"expires_in_ms,omitempty"`
+}
+
+type apiRefreshReq struct {
+	RefreshToken string `json:"refresh_token"`
 }
 
 type apiSendMsgReq struct {
-	RoomID        string `json:"-"`
-	Type          string `json:"msgtype"`
-	Body          string `json:"body,omitempty"`
-	Format        string `json:"format,omitempty"`
-	FormattedBody string `json:"formatted_body,omitempty"`
-	Filename      string `json:"filename,omitempty"`
-	URL           string `json:"url,omitempty"`
+	RoomID        string     `json:"-"`
+	Type          string     `json:"msgtype"`
+	Body          string     `json:"body,omitempty"`
+	Format        string     `json:"format,omitempty"`
+	FormattedBody string     `json:"formatted_body,omitempty"`
+	Filename      string     `json:"filename,omitempty"`
+	URL           string     `json:"url,omitempty"`
+	Info          *MediaInfo `json:"info,omitempty"`
+
+	Mentions   *Mentions      `json:"m.mentions,omitempty"`
+	NewContent *apiSendMsgReq `json:"m.new_content,omitempty"`
+	RelatesTo  *Relation      `json:"m.relates_to,omitempty"`
+}
+
+type apiReactionReq struct {
+	RelatesTo Relation `json:"m.relates_to"`
 }
 
 type apiUploadResp struct {
-	URI string `json:"content_uri"`
+	URI MXCURI `json:"content_uri"`
+}
+
+type apiSendEventResp struct {
+	EventID string `json:"event_id"`
+}
+
+type apiWhoAmIResp struct {
+	UserID   string `json:"user_id"`
+	DeviceID string `json:"device_id"`
+}
+
+type apiSchedules struct {
+	Schedules []Schedule `json:"schedules"`
+}
+
+type apiJoinRoomResp struct {
+	RoomID string `json:"room_id"`
+}
+
+type apiMembershipReq struct {
+	UserID string `json:"user_id"`
+	Reason string `json:"reason,omitempty"`
+}
+
+type apiInviteThirdPartyReq struct {
+	IDServer      string `json:"id_server"`
+	IDAccessToken string `json:"id_access_token"`
+	Medium        string `json:"medium"`
+	Address       string `json:"address"`
+}
+
+type apiRedactReq struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+type apiJoinedRoomsResp struct {
+	JoinedRooms []string `json:"joined_rooms"`
+}
+
+type apiJoinedMembersResp struct {
+	Joined map[string]JoinedMember `json:"joined"`
+}
+
+type apiRoomMembersResp struct {
+	Chunk []Event `json:"chunk"`
+}
+
+type apiCreateFilterResp struct {
+	FilterID string `json:"filter_id"`
+}
+
+type apiReceiptReq struct {
+	ThreadID string `json:"thread_id,omitempty"`
+}
+
+type apiReadMarkersReq struct {
+	FullyRead string `json:"m.fully_read,omitempty"`
+	Read      string `json:"m.read,omitempty"`
+}
+
+type apiMarkedUnread struct {
+	Unread bool `json:"unread"`
+}
+
+type apiCreateMediaResp struct {
+	URI             MXCURI `json:"content_uri"`
+	UnusedExpiresAt int64  `json:"unused_expires_at"`
+}
+
+type apiMediaInfo struct {
+	MimeType      string         `json:"mimetype,omitempty"`
+	Size          int64          `json:"size,omitempty"`
+	Width         int            `json:"w,omitempty"`
+	Height        int            `json:"h,omitempty"`
+	Duration      int64          `json:"duration,omitempty"`
+	ThumbnailURL  string         `json:"thumbnail_url,omitempty"`
+	ThumbnailInfo *ThumbnailInfo `json:"thumbnail_info,omitempty"`
+	BlurHash      string         `json:"xyz.amorgan.blurhash,omitempty"`
+}
+
+type apiAppservicePingReq struct {
+	TransactionID string `json:"transaction_id,omitempty"`
+}
+
+type apiAppservicePingResp struct {
+	DurationMS int64 `json:"duration_ms"`
+}
+
+type apiCapabilitiesResp struct {
+	Capabilities Capabilities `json:"capabilities"`
+}
+
+type apiSetPresenceReq struct {
+	Presence  Presence `json:"presence"`
+	StatusMsg string   `json:"status_msg,omitempty"`
+}
+
+type apiKeysUploadResp struct {
+	OneTimeKeyCounts map[string]int `json:"one_time_key_counts"`
+}
+
+type apiKeysQueryReq struct {
+	DeviceKeys map[string][]string `json:"device_keys"`
+	Timeout    int64               `json:"timeout,omitempty"`
+}
+
+type apiKeysClaimReq struct {
+	OneTimeKeys map[string]map[string]string `json:"one_time_keys"`
+	Timeout     int64                        `json:"timeout,omitempty"`
+}
+
+type apiKeyChangesResp struct {
+	Changed []string `json:"changed"`
+	Left    []string `json:"left"`
+}
+
+type apiSendToDeviceReq struct {
+	Messages map[string]map[string]any `json:"messages"`
+}
+
+type apiRoomKeyRequest struct {
+	Action             string                 `json:"action"`
+	Body               *apiRoomKeyRequestBody `json:"body,omitempty"`
+	RequestID          string                 `json:"request_id"`
+	RequestingDeviceID string                 `json:"requesting_device_id"`
+}
+
+type apiRoomKeyRequestBody struct {
+	Algorithm string `json:"algorithm"`
+	RoomID    string `json:"room_id"`
+	SessionID string `json:"session_id"`
+	SenderKey string `json:"sender_key,omitempty"`
+}
+
+type apiSignaturesUploadResp struct {
+	Failures map[string]any `json:"failures,omitempty"`
+}
+
+type apiRoomKeyContent struct {
+	Algorithm string `json:"algorithm"`
+	RoomID    string `json:"room_id"`
+	SessionID string `json:"session_id"`
+}
+
+type apiKeyBackupVersionReq struct {
+	Algorithm string            `json:"algorithm"`
+	AuthData  KeyBackupAuthData `json:"auth_data"`
+}
+
+type apiKeyBackupVersionResp struct {
+	Version string `json:"version"`
+}
+
+type apiRoomKeysReq struct {
+	Rooms map[string]apiRoomKeyBackup `json:"rooms"`
+}
+
+type apiRoomKeyBackup struct {
+	Sessions map[string]apiKeyBackupData `json:"sessions"`
+}
+
+type apiKeyBackupData struct {
+	FirstMessageIndex int                     `json:"first_message_index"`
+	ForwardedCount    int                     `json:"forwarded_count"`
+	IsVerified        bool                    `json:"is_verified"`
+	SessionData       apiEncryptedSessionData `json:"session_data"`
+}
+
+type apiEncryptedSessionData struct {
+	Ephemeral  string `json:"ephemeral"`
+	Ciphertext string `json:"ciphertext"`
+	MAC        string `json:"mac"`
+}
+
+type apiBackupSessionData struct {
+	Algorithm                    string            `json:"algorithm"`
+	ForwardingCurve25519KeyChain []string          `json:"forwarding_curve25519_key_chain"`
+	SenderClaimedKeys            map[string]string `json:"sender_claimed_keys"`
+	SenderKey                    string            `json:"sender_key"`
+	SessionKey                   string            `json:"session_key"`
+}
+
+type apiOAuthRegistrationResp struct {
+	ClientID string `json:"client_id"`
+}
+
+type apiRendezvousResp struct {
+	URL string `json:"url"`
+}
+
+type apiQRLoginMessage struct {
+	Type                     string                       `json:"type"`
+	Protocols                []string                     `json:"protocols,omitempty"`
+	Protocol                 string                       `json:"protocol,omitempty"`
+	Homeserver               string                       `json:"homeserver,omitempty"`
+	DeviceAuthorizationGrant *apiDeviceAuthorizationGrant `json:"device_authorization_grant,omitempty"`
+	DeviceID                 string                       `json:"device_id,omitempty"`
+	Reason                   string                       `json:"reason,omitempty"`
+	CrossSigning             *QRLoginCrossSigning         `json:"cross_signing,omitempty"`
+	Backup                   *QRLoginBackup               `json:"backup,omitempty"`
+}
+
+type apiDeviceAuthorizationGrant struct {
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+}
+
+type apiWellKnownClient struct {
+	Homeserver struct {
+		BaseURL string `json:"base_url"`
+	} `json:"m.homeserver"`
+}
+
+type apiErrorResp struct {
+	ErrCode      string `json:"errcode"`
+	Error        string `json:"error"`
+	RetryAfterMs int64  `json:"retry_after_ms"`
+}
+
+type apiRoomAliasResp struct {
+	RoomID  string   `json:"room_id"`
+	Servers []string `json:"servers"`
+}
+
+type apiRegisterReq struct {
+	Username                 string         `json:"username,omitempty"`
+	Password                 string         `json:"password,omitempty"`
+	DeviceID                 string         `json:"device_id,omitempty"`
+	InitialDeviceDisplayName string         `json:"initial_device_display_name,omitempty"`
+	InhibitLogin             bool           `json:"inhibit_login,omitempty"`
+	RefreshToken             bool           `json:"refresh_token,omitempty"`
+	Auth                     map[string]any `json:"auth,omitempty"`
+}
+
+type apiRegistrationTokenValidityResp struct {
+	Valid bool `json:"valid"`
+}
+
+type apiRequestTokenReq struct {
+	ClientSecret string `json:"client_secret"`
+	SendAttempt  int    `json:"send_attempt"`
+	Email        string `json:"email,omitempty"`
+	Country      string `json:"country,omitempty"`
+	PhoneNumber  string `json:"phone_number,omitempty"`
+	NextLink     string `json:"next_link,omitempty"`
+}
+
+type apiRequestTokenResp struct {
+	SID       string `json:"sid"`
+	SubmitURL string `json:"submit_url"`
+}
+
+type apiSubmitTokenReq struct {
+	SID          string `json:"sid"`
+	ClientSecret string `json:"client_secret"`
+	Token        string `json:"token"`
+}
+
+type apiSubmitTokenResp struct {
+	Success bool `json:"success"`
+}
+
+type apiPasswordResetReq struct {
+	NewPassword   string         `json:"new_password"`
+	LogoutDevices bool           `json:"logout_devices"`
+	Auth          map[string]any `json:"auth,omitempty"`
+}
+
+type apiStickerReq struct {
+	Body string     `json:"body"`
+	URL  string     `json:"url"`
+	Info *MediaInfo `json:"info"`
+}
+
+type apiPublicRoomsReq struct {
+	Filter *apiPublicRoomsFilter `json:"filter,omitempty"`
+	Limit  int                   `json:"limit,omitempty"`
+	Since  string                `json:"since,omitempty"`
+}
+
+type apiPublicRoomsFilter struct {
+	GenericSearchTerm string `json:"generic_search_term,omitempty"`
 }
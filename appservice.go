@@ -0,0 +1,22 @@
+package gomatrix
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// PingAppservice asks the homeserver to ping the application service the client authenticates as,
+// checking the connectivity in both directions. It returns the duration of the homeserver to appservice request.
+// https://spec.matrix.org/v1.13/client-server-api/#post_matrixclientv1appserviceappserviceidping
+func (c *Client) PingAppservice(ctx context.Context, appserviceID, txnID string) (time.Duration, error) {
+	var respData apiAppservicePingResp
+	path := fmt.Sprintf("/_matrix/client/v1/appservice/%s/ping", url.PathEscape(appserviceID))
+	err := c.doJSON(ctx, http.MethodPost, path, apiAppservicePingReq{TransactionID: txnID}, &respData)
+	if err != nil {
+		return 0, fmt.Errorf("failed to ping the appservice: %w", err)
+	}
+	return time.Duration(respData.DurationMS) * time.Millisecond, nil
+}
@@ -0,0 +1,35 @@
+package appservice
+
+import gomatrix "github.com/beldeveloper/go-matrix"
+
+type apiTransaction struct {
+	Events []gomatrix.Event `json:"events"`
+
+	Ephemeral         []gomatrix.Event `json:"ephemeral"`
+	UnstableEphemeral []gomatrix.Event `json:"de.sorunome.msc2409.ephemeral"`
+	ToDevice          []gomatrix.Event `json:"to_device"`
+	UnstableToDevice  []gomatrix.Event `json:"de.sorunome.msc2409.to_device"`
+}
+
+func (t apiTransaction) ephemeral() []gomatrix.Event {
+	if len(t.Ephemeral) > 0 {
+		return t.Ephemeral
+	}
+	return t.UnstableEphemeral
+}
+
+func (t apiTransaction) toDevice() []gomatrix.Event {
+	if len(t.ToDevice) > 0 {
+		return t.ToDevice
+	}
+	return t.UnstableToDevice
+}
+
+type apiPingReq struct {
+	TransactionID string `json:"transaction_id"`
+}
+
+type apiError struct {
+	ErrCode string `json:"errcode"`
+	Error   string `json:"error"`
+}
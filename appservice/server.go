@@ -0,0 +1,220 @@
+// Package appservice implements the homeserver to application service API,
+// so bridges can receive the events pushed by the homeserver instead of syncing.
+// https://spec.matrix.org/v1.13/application-service-api/
+package appservice
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	gomatrix "github.com/beldeveloper/go-matrix"
+)
+
+const defaultTxnHistory = 1000
+
+type Config struct {
+	// HSToken is the token the homeserver authenticates its requests with.
+	HSToken string
+	// OnEvent is called for every event of a transaction, in order.
+	OnEvent gomatrix.EventHandler
+	// OnEphemeral is called for the ephemeral events (typing, receipts, presence) if the registration enables them.
+	OnEphemeral gomatrix.EventHandler
+	// OnToDevice is called for the to-device events if the registration enables them.
+	OnToDevice gomatrix.EventHandler
+	// QueryUser reports whether the user in the application service namespace exists, creating it if needed.
+	QueryUser func(ctx context.Context, userID string) (bool, error)
+	// QueryAlias reports whether the room alias in the application service namespace exists, creating it if needed.
+	QueryAlias func(ctx context.Context, alias string) (bool, error)
+	// OnPing is called when the homeserver checks the connectivity, see Client.PingAppservice.
+	OnPing func(ctx context.Context, txnID string)
+	// TxnHistory is the number of the latest transaction IDs remembered for deduplication, 1000 by default.
+	TxnHistory int
+}
+
+type Server struct {
+	cfg Config
+	mux *http.ServeMux
+
+	txnMux      sync.Mutex
+	txnSeen     map[string]struct{}
+	txnRing     []string
+	txnNext     int
+	txnInFlight map[string]chan struct{}
+}
+
+func NewServer(cfg Config) *Server {
+	if cfg.TxnHistory <= 0 {
+		cfg.TxnHistory = defaultTxnHistory
+	}
+
+	s := &Server{
+		cfg:         cfg,
+		mux:         http.NewServeMux(),
+		txnSeen:     make(map[string]struct{}, cfg.TxnHistory),
+		txnRing:     make([]string, cfg.TxnHistory),
+		txnInFlight: make(map[string]chan struct{}),
+	}
+
+	s.mux.HandleFunc("PUT /_matrix/app/v1/transactions/{txnId}", s.handleTransaction)
+	s.mux.HandleFunc("GET /_matrix/app/v1/users/{userId}", s.handleUserQuery)
+	s.mux.HandleFunc("GET /_matrix/app/v1/rooms/{roomAlias}", s.handleAliasQuery)
+	s.mux.HandleFunc("POST /_matrix/app/v1/ping", s.handlePing)
+
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		// deprecated query parameter still sent by older homeservers
+		token = r.URL.Query().Get("access_token")
+	}
+
+	if token == "" {
+		writeError(w, http.StatusUnauthorized, "M_UNAUTHORIZED", "missing token")
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.cfg.HSToken)) != 1 {
+		writeError(w, http.StatusForbidden, "M_FORBIDDEN", "invalid token")
+		return
+	}
+
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) handleTransaction(w http.ResponseWriter, r *http.Request) {
+	txnID := r.PathValue("txnId")
+	ctx := r.Context()
+
+	// a retry of the transaction arriving while it's still handled waits for it instead of dispatching it twice
+	for {
+		seen, inFlight := s.begin(txnID)
+		if seen {
+			writeJSON(w, http.StatusOK, struct{}{})
+			return
+		}
+		if inFlight == nil {
+			break
+		}
+		select {
+		case <-inFlight:
+		case <-ctx.Done():
+			return
+		}
+	}
+
+	handled := false
+	defer func() { s.end(txnID, handled) }()
+
+	var txn apiTransaction
+	err := json.NewDecoder(r.Body).Decode(&txn)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "M_NOT_JSON", err.Error())
+		return
+	}
+
+	dispatch(ctx, s.cfg.OnEvent, txn.Events)
+	dispatch(ctx, s.cfg.OnEphemeral, txn.ephemeral())
+	dispatch(ctx, s.cfg.OnToDevice, txn.toDevice())
+
+	handled = true
+	writeJSON(w, http.StatusOK, struct{}{})
+}
+
+func (s *Server) handleUserQuery(w http.ResponseWriter, r *http.Request) {
+	s.handleQuery(w, r, s.cfg.QueryUser, r.PathValue("userId"))
+}
+
+func (s *Server) handleAliasQuery(w http.ResponseWriter, r *http.Request) {
+	s.handleQuery(w, r, s.cfg.QueryAlias, r.PathValue("roomAlias"))
+}
+
+func (s *Server) handleQuery(
+	w http.ResponseWriter, r *http.Request, query func(ctx context.Context, id string) (bool, error), id string,
+) {
+	if query == nil {
+		writeError(w, http.StatusNotFound, "M_NOT_FOUND", "not found")
+		return
+	}
+
+	exists, err := query(r.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "M_UNKNOWN", err.Error())
+		return
+	}
+	if !exists {
+		writeError(w, http.StatusNotFound, "M_NOT_FOUND", "not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, struct{}{})
+}
+
+func (s *Server) handlePing(w http.ResponseWriter, r *http.Request) {
+	var req apiPingReq
+	_ = json.NewDecoder(r.Body).Decode(&req)
+
+	if s.cfg.OnPing != nil {
+		s.cfg.OnPing(r.Context(), req.TransactionID)
+	}
+
+	writeJSON(w, http.StatusOK, struct{}{})
+}
+
+// begin marks the transaction in flight unless it's handled already or being handled, returning the channel
+// closed once the other handling ends in the latter case.
+func (s *Server) begin(txnID string) (bool, <-chan struct{}) {
+	s.txnMux.Lock()
+	defer s.txnMux.Unlock()
+
+	if _, ok := s.txnSeen[txnID]; ok {
+		return true, nil
+	}
+	if inFlight, ok := s.txnInFlight[txnID]; ok {
+		return false, inFlight
+	}
+	s.txnInFlight[txnID] = make(chan struct{})
+	return false, nil
+}
+
+// end releases the transaction in flight, remembering it if it was handled.
+func (s *Server) end(txnID string, handled bool) {
+	s.txnMux.Lock()
+	defer s.txnMux.Unlock()
+
+	close(s.txnInFlight[txnID])
+	delete(s.txnInFlight, txnID)
+	if !handled {
+		return
+	}
+
+	if old := s.txnRing[s.txnNext]; old != "" {
+		delete(s.txnSeen, old)
+	}
+	s.txnRing[s.txnNext] = txnID
+	s.txnSeen[txnID] = struct{}{}
+	s.txnNext = (s.txnNext + 1) % len(s.txnRing)
+}
+
+func dispatch(ctx context.Context, handler gomatrix.EventHandler, events []gomatrix.Event) {
+	if handler == nil {
+		return
+	}
+	for _, ev := range events {
+		handler(ctx, ev)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code, msg string) {
+	writeJSON(w, status, apiError{ErrCode: code, Error: msg})
+}
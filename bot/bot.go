@@ -0,0 +1,211 @@
+// Package bot is a command framework for chatops bots built on the client sync loop:
+//
+//	b := bot.New(client, bot.Config{})
+//	b.Handle("deploy status", "Show the deployment status", func(ctx context.Context, req *bot.Request) error {
+//		return req.Reply(ctx, "all green")
+//	}).Args("[env]")
+//	err := b.Run(ctx)
+//
+// The room invitations are accepted by the client, following the policy of its Config.AutoJoin.
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	gomatrix "github.com/beldeveloper/go-matrix"
+)
+
+const defaultPrefix = "!"
+
+type HandlerFunc func(ctx context.Context, req *Request) error
+
+type Config struct {
+	// Prefix starts every command, "!" by default.
+	Prefix string
+	// Permission is checked for the commands without their own permission; everyone is allowed by default.
+	Permission Permission
+	// OnError is called when a command handler or a reply fails.
+	OnError func(ev gomatrix.Event, err error)
+}
+
+type Bot struct {
+	client *gomatrix.Client
+	cfg    Config
+	root   *Command
+	userID string
+}
+
+func New(client *gomatrix.Client, cfg Config) *Bot {
+	if cfg.Prefix == "" {
+		cfg.Prefix = defaultPrefix
+	}
+	if cfg.Permission == nil {
+		cfg.Permission = Everyone
+	}
+
+	b := &Bot{
+		client: client,
+		cfg:    cfg,
+		root:   &Command{subcommands: make(map[string]*Command)},
+	}
+	b.Handle("help", "Show the available commands", b.help).Args("[command...]")
+
+	return b
+}
+
+// Handle registers the handler for the command path, e.g. "deploy status" for "!deploy status".
+func (b *Bot) Handle(path, description string, handler HandlerFunc) *Command {
+	cmd := b.root
+	for _, name := range strings.Fields(path) {
+		sub, ok := cmd.subcommands[name]
+		if !ok {
+			sub = &Command{
+				path:        strings.TrimSpace(strings.Join(append(slices.Clone(cmd.pathParts()), name), " ")),
+				subcommands: make(map[string]*Command),
+			}
+			cmd.subcommands[name] = sub
+		}
+		cmd = sub
+	}
+
+	cmd.description = description
+	cmd.handler = handler
+	return cmd
+}
+
+func (b *Bot) Run(ctx context.Context) error {
+	userID, err := b.client.WhoAmI(ctx)
+	if err != nil {
+		return err
+	}
+	b.userID = userID
+
+	return b.client.Listen(ctx, func(ctx context.Context, ev gomatrix.Event) {
+		err := b.HandleEvent(ctx, ev)
+		if err != nil && b.cfg.OnError != nil {
+			b.cfg.OnError(ev, err)
+		}
+	})
+}
+
+func (b *Bot) HandleEvent(ctx context.Context, ev gomatrix.Event) error {
+	if ev.Sender == b.userID && b.userID != "" {
+		return nil
+	}
+
+	if ev.Type == "m.room.message" {
+		return b.handleMessage(ctx, ev)
+	}
+	return nil
+}
+
+func (b *Bot) handleMessage(ctx context.Context, ev gomatrix.Event) error {
+	var msg gomatrix.MessageContent
+	if ev.ParseContent(&msg) != nil || msg.MsgType != string(gomatrix.Text) {
+		return nil
+	}
+
+	line, ok := strings.CutPrefix(strings.TrimSpace(msg.Body), b.cfg.Prefix)
+	if !ok {
+		return nil
+	}
+
+	words, err := SplitArgs(line)
+	if err != nil || len(words) == 0 {
+		return nil
+	}
+
+	cmd, args := b.root.find(words)
+	if cmd == b.root {
+		return nil
+	}
+
+	req := &Request{Client: b.client, Event: ev, Command: cmd}
+
+	if cmd.handler == nil {
+		return req.Reply(ctx, b.usage(cmd))
+	}
+
+	permission := cmd.permission
+	if permission == nil {
+		permission = b.cfg.Permission
+	}
+	allowed, err := permission(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to check the permission: %w", err)
+	}
+	if !allowed {
+		return req.Reply(ctx, "You are not allowed to run this command.")
+	}
+
+	req.Args, err = cmd.bind(args)
+	if err != nil {
+		return req.Reply(ctx, fmt.Sprintf("%s\n%s", err, b.usage(cmd)))
+	}
+
+	err = cmd.handler(ctx, req)
+	if err != nil {
+		return errors.Join(err, req.Reply(ctx, "Error: "+err.Error()))
+	}
+
+	return nil
+}
+
+func (b *Bot) help(ctx context.Context, req *Request) error {
+	if topic := req.Args.Rest("command"); len(topic) > 0 {
+		cmd, rest := b.root.find(topic)
+		if cmd == b.root || len(rest) > 0 {
+			return req.Reply(ctx, fmt.Sprintf("Unknown command %q.", strings.Join(topic, " ")))
+		}
+		return req.Reply(ctx, b.usage(cmd))
+	}
+
+	return req.Reply(ctx, "Commands:\n"+strings.Join(b.lines(b.root), "\n"))
+}
+
+func (b *Bot) usage(cmd *Command) string {
+	lines := b.lines(cmd)
+	if cmd.handler != nil {
+		lines = append([]string{b.line(cmd)}, lines...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// lines lists the runnable descendants of the command.
+func (b *Bot) lines(cmd *Command) []string {
+	names := make([]string, 0, len(cmd.subcommands))
+	for name := range cmd.subcommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var lines []string
+	for _, name := range names {
+		sub := cmd.subcommands[name]
+		if sub.handler != nil {
+			lines = append(lines, b.line(sub))
+		}
+		lines = append(lines, b.lines(sub)...)
+	}
+	return lines
+}
+
+func (b *Bot) line(cmd *Command) string {
+	line := b.cfg.Prefix + cmd.path
+	for _, spec := range cmd.args {
+		if strings.HasPrefix(spec, "[") {
+			line += " " + spec
+		} else {
+			line += " <" + spec + ">"
+		}
+	}
+	if cmd.description != "" {
+		line += " - " + cmd.description
+	}
+	return line
+}
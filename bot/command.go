@@ -0,0 +1,157 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type Command struct {
+	path        string
+	description string
+	args        []string
+	permission  Permission
+	handler     HandlerFunc
+	subcommands map[string]*Command
+}
+
+// Args declares the arguments of the command: "name" is required, "[name]" is optional
+// and "[name...]" or "name..." collects the remaining arguments and must be the last one.
+func (c *Command) Args(args ...string) *Command {
+	c.args = args
+	return c
+}
+
+// Permission overrides the bot permission for the command.
+func (c *Command) Permission(permission Permission) *Command {
+	c.permission = permission
+	return c
+}
+
+func (c *Command) pathParts() []string {
+	return strings.Fields(c.path)
+}
+
+// find returns the deepest command matching the words and the remaining words as arguments.
+func (c *Command) find(words []string) (*Command, []string) {
+	cmd := c
+	for i, word := range words {
+		sub, ok := cmd.subcommands[word]
+		if !ok {
+			return cmd, words[i:]
+		}
+		cmd = sub
+	}
+	return cmd, nil
+}
+
+func (c *Command) bind(values []string) (Args, error) {
+	args := Args{values: make(map[string][]string)}
+
+	for i, spec := range c.args {
+		name, optional, variadic := parseArgSpec(spec)
+
+		if variadic {
+			if len(values) == 0 && !optional {
+				return Args{}, fmt.Errorf("missing argument %s", name)
+			}
+			args.values[name] = values
+			return args, nil
+		}
+
+		if len(values) == 0 {
+			if optional {
+				continue
+			}
+			return Args{}, fmt.Errorf("missing argument %s", name)
+		}
+
+		args.values[name] = values[:1]
+		values = values[1:]
+
+		if i == len(c.args)-1 && len(values) > 0 {
+			return Args{}, errors.New("too many arguments")
+		}
+	}
+
+	if len(c.args) == 0 && len(values) > 0 {
+		return Args{}, errors.New("too many arguments")
+	}
+
+	return args, nil
+}
+
+func parseArgSpec(spec string) (string, bool, bool) {
+	name, optional := strings.CutPrefix(spec, "[")
+	if optional {
+		name = strings.TrimSuffix(name, "]")
+	}
+	name, variadic := strings.CutSuffix(name, "...")
+	return name, optional, variadic
+}
+
+type Args struct {
+	values map[string][]string
+}
+
+// Get returns the value of the argument or an empty string if it is not passed.
+func (a Args) Get(name string) string {
+	if values := a.values[name]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (a Args) Has(name string) bool {
+	_, ok := a.values[name]
+	return ok
+}
+
+// Rest returns all values of the variadic argument.
+func (a Args) Rest(name string) []string {
+	return a.values[name]
+}
+
+// SplitArgs splits the command line into words honoring single and double quotes and backslash escapes.
+func SplitArgs(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	var quote rune
+	inWord, escaped := false, false
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inWord = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	if quote != 0 || escaped {
+		return nil, errors.New("unterminated quote or escape")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+
+	return words, nil
+}
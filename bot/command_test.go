@@ -0,0 +1,116 @@
+package bot
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    []string
+		wantErr bool
+	}{
+		{name: "empty", line: "", want: nil},
+		{name: "blank", line: " \t\n", want: nil},
+		{name: "words", line: "deploy status prod", want: []string{"deploy", "status", "prod"}},
+		{name: "repeated spaces", line: "  a \t b\n c ", want: []string{"a", "b", "c"}},
+		{name: "double quotes", line: `say "hello world"`, want: []string{"say", "hello world"}},
+		{name: "single quotes", line: `say 'hello world'`, want: []string{"say", "hello world"}},
+		{name: "quotes inside a word", line: `a"b c"d`, want: []string{"ab cd"}},
+		{name: "empty quotes", line: `a "" b`, want: []string{"a", "", "b"}},
+		{name: "other quote kept", line: `"it's" '"x"'`, want: []string{"it's", `"x"`}},
+		{name: "escaped space", line: `a\ b c`, want: []string{"a b", "c"}},
+		{name: "escaped quote", line: `\"a`, want: []string{`"a`}},
+		{name: "escape in double quotes", line: `"a\"b"`, want: []string{`a"b`}},
+		{name: "no escape in single quotes", line: `'a\b'`, want: []string{`a\b`}},
+		{name: "unicode", line: "привет 'мир'", want: []string{"привет", "мир"}},
+		{name: "unterminated double quote", line: `say "hello`, wantErr: true},
+		{name: "unterminated single quote", line: `say 'hello`, wantErr: true},
+		{name: "trailing escape", line: `say \`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SplitArgs(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SplitArgs(%q) error = %v, want error %t", tt.line, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("SplitArgs(%q) = %q, want %q", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandBind(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		values  []string
+		want    map[string][]string
+		wantErr bool
+	}{
+		{name: "no args", values: nil, want: map[string][]string{}},
+		{name: "no args with values", values: []string{"x"}, wantErr: true},
+		{name: "required", args: []string{"env"}, values: []string{"prod"}, want: map[string][]string{"env": {"prod"}}},
+		{name: "required missing", args: []string{"env"}, values: nil, wantErr: true},
+		{name: "too many", args: []string{"env"}, values: []string{"prod", "x"}, wantErr: true},
+		{name: "optional passed", args: []string{"[env]"}, values: []string{"prod"}, want: map[string][]string{"env": {"prod"}}},
+		{name: "optional missing", args: []string{"[env]"}, values: nil, want: map[string][]string{}},
+		{
+			name:   "required and optional",
+			args:   []string{"service", "[env]"},
+			values: []string{"api"},
+			want:   map[string][]string{"service": {"api"}},
+		},
+		{
+			name:   "variadic",
+			args:   []string{"service", "hosts..."},
+			values: []string{"api", "a", "b"},
+			want:   map[string][]string{"service": {"api"}, "hosts": {"a", "b"}},
+		},
+		{name: "variadic missing", args: []string{"service", "hosts..."}, values: []string{"api"}, wantErr: true},
+		{
+			name:   "optional variadic missing",
+			args:   []string{"service", "[hosts...]"},
+			values: []string{"api"},
+			want:   map[string][]string{"service": {"api"}, "hosts": {}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &Command{args: tt.args}
+			got, err := cmd.bind(tt.values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("bind(%q) error = %v, want error %t", tt.values, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got.values, tt.want) {
+				t.Fatalf("bind(%q) = %q, want %q", tt.values, got.values, tt.want)
+			}
+		})
+	}
+}
+
+func TestArgs(t *testing.T) {
+	cmd := &Command{args: []string{"service", "[env]", "[hosts...]"}}
+	args, err := cmd.bind([]string{"api", "prod", "a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := args.Get("service"); got != "api" {
+		t.Fatalf("Get(service) = %q, want api", got)
+	}
+	if !args.Has("env") || args.Has("missing") {
+		t.Fatal("Has reports the wrong arguments")
+	}
+	if got := args.Get("missing"); got != "" {
+		t.Fatalf("Get(missing) = %q, want empty", got)
+	}
+	if got := args.Rest("hosts"); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Fatalf("Rest(hosts) = %q, want [a b]", got)
+	}
+}
@@ -0,0 +1,66 @@
+package bot
+
+import (
+	"context"
+	"slices"
+)
+
+// Permission reports whether the sender of the request is allowed to run the command in the room.
+type Permission func(ctx context.Context, req *Request) (bool, error)
+
+func Everyone(context.Context, *Request) (bool, error) {
+	return true, nil
+}
+
+func Users(userIDs ...string) Permission {
+	return func(_ context.Context, req *Request) (bool, error) {
+		return slices.Contains(userIDs, req.Sender()), nil
+	}
+}
+
+// Rooms allows running the command in the given rooms only.
+func Rooms(roomIDs ...string) Permission {
+	return func(_ context.Context, req *Request) (bool, error) {
+		return slices.Contains(roomIDs, req.RoomID()), nil
+	}
+}
+
+// PowerLevel requires the sender to have at least the given power level in the room.
+func PowerLevel(level int) Permission {
+	return func(ctx context.Context, req *Request) (bool, error) {
+		levels, err := req.Client.GetPowerLevels(ctx, req.RoomID())
+		if err != nil {
+			return false, err
+		}
+		return levels.UserLevel(req.Sender()) >= level, nil
+	}
+}
+
+// All requires every permission to be granted.
+func All(permissions ...Permission) Permission {
+	return func(ctx context.Context, req *Request) (bool, error) {
+		for _, permission := range permissions {
+			allowed, err := permission(ctx, req)
+			if err != nil || !allowed {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+}
+
+// Any requires at least one permission to be granted.
+func Any(permissions ...Permission) Permission {
+	return func(ctx context.Context, req *Request) (bool, error) {
+		for _, permission := range permissions {
+			allowed, err := permission(ctx, req)
+			if err != nil {
+				return false, err
+			}
+			if allowed {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+}
@@ -0,0 +1,28 @@
+package bot
+
+import (
+	"context"
+
+	gomatrix "github.com/beldeveloper/go-matrix"
+)
+
+type Request struct {
+	Client  *gomatrix.Client
+	Event   gomatrix.Event
+	Command *Command
+	Args    Args
+}
+
+func (r *Request) RoomID() string {
+	return r.Event.RoomID
+}
+
+func (r *Request) Sender() string {
+	return r.Event.Sender
+}
+
+// Reply sends a notice replying to the command message.
+func (r *Request) Reply(ctx context.Context, text string) error {
+	_, err := gomatrix.NewMessage().Notice().Text(text).ReplyTo(r.Event).Send(ctx, r.Client, r.Event.RoomID)
+	return err
+}
@@ -0,0 +1,73 @@
+package gomatrix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultBroadcastConcurrency = 4
+	defaultBroadcastInterval    = 100 * time.Millisecond
+)
+
+type BroadcastConfig struct {
+	// Concurrency limits the number of rooms being sent to at the same time.
+	Concurrency int
+	// Interval is the minimal delay between two consecutive sends shared by all workers.
+	// A negative value disables the limit.
+	Interval time.Duration
+}
+
+type BroadcastResult struct {
+	RoomID  string
+	EventID string
+	Err     error
+}
+
+// Broadcast sends the message to every room and returns the per-room results in the order of roomIDs.
+// The returned error joins all per-room failures.
+func (c *Client) Broadcast(ctx context.Context, roomIDs []string, msg Message) ([]BroadcastResult, error) {
+	results := make([]BroadcastResult, len(roomIDs))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for range min(c.broadcast.Concurrency, len(roomIDs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = c.broadcastTo(ctx, roomIDs[i], msg)
+			}
+		}()
+	}
+
+	for i := range roomIDs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	var errs []error
+	for _, res := range results {
+		if res.Err != nil {
+			errs = append(errs, fmt.Errorf("room %s: %w", res.RoomID, res.Err))
+		}
+	}
+
+	return results, errors.Join(errs...)
+}
+
+func (c *Client) broadcastTo(ctx context.Context, roomID string, msg Message) BroadcastResult {
+	res := BroadcastResult{RoomID: roomID}
+
+	res.Err = c.broadcastLimiter.Wait(ctx)
+	if res.Err != nil {
+		return res
+	}
+
+	res.EventID, res.Err = c.Send(ctx, roomID, msg)
+	return res
+}
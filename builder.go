@@ -0,0 +1,100 @@
+package gomatrix
+
+import (
+	"context"
+	"slices"
+)
+
+// MessageBuilder builds a Message step by step:
+//
+//	NewMessage().Text("deployed").ReplyTo(ev).Mention(ev.Sender).Send(ctx, client, roomID)
+type MessageBuilder struct {
+	msg Message
+}
+
+func NewMessage() *MessageBuilder {
+	return &MessageBuilder{}
+}
+
+func (b *MessageBuilder) Text(text string) *MessageBuilder {
+	b.msg.Text = text
+	return b
+}
+
+// HTML sets the formatted body; the Text is used as the plain text fallback.
+func (b *MessageBuilder) HTML(html string) *MessageBuilder {
+	b.msg.HTML = html
+	return b
+}
+
+func (b *MessageBuilder) Notice() *MessageBuilder {
+	b.msg.Type = Notice
+	return b
+}
+
+func (b *MessageBuilder) Emote() *MessageBuilder {
+	b.msg.Type = Emote
+	return b
+}
+
+func (b *MessageBuilder) Media(media Media) *MessageBuilder {
+	b.msg.Media = &media
+	return b
+}
+
+// ReplyTo makes the message a reply to the event and mentions its sender as recommended by the spec.
+func (b *MessageBuilder) ReplyTo(ev Event) *MessageBuilder {
+	b.msg.ReplyTo = ev.ID
+	if ev.Sender != "" {
+		b.Mention(ev.Sender)
+	}
+	return b
+}
+
+func (b *MessageBuilder) InThread(rootEventID string) *MessageBuilder {
+	b.msg.ThreadRoot = rootEventID
+	return b
+}
+
+func (b *MessageBuilder) Mention(userIDs ...string) *MessageBuilder {
+	mentions := b.mentions()
+	for _, userID := range userIDs {
+		if !slices.Contains(mentions.UserIDs, userID) {
+			mentions.UserIDs = append(mentions.UserIDs, userID)
+		}
+	}
+	return b
+}
+
+// NotifyRoom makes the message notify the whole room, like @room.
+func (b *MessageBuilder) NotifyRoom() *MessageBuilder {
+	b.mentions().Room = true
+	return b
+}
+
+// NoMentions explicitly marks the message as notifying nobody.
+func (b *MessageBuilder) NoMentions() *MessageBuilder {
+	b.msg.Mentions = &Mentions{}
+	return b
+}
+
+func (b *MessageBuilder) Build() Message {
+	msg := b.msg
+	if msg.Mentions != nil {
+		mentions := *msg.Mentions
+		mentions.UserIDs = slices.Clone(mentions.UserIDs)
+		msg.Mentions = &mentions
+	}
+	return msg
+}
+
+func (b *MessageBuilder) Send(ctx context.Context, client *Client, roomID string) (string, error) {
+	return client.Send(ctx, roomID, b.Build())
+}
+
+func (b *MessageBuilder) mentions() *Mentions {
+	if b.msg.Mentions == nil {
+		b.msg.Mentions = &Mentions{}
+	}
+	return b.msg.Mentions
+}
@@ -0,0 +1,83 @@
+package gomatrix
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+)
+
+type RoomVersionStability string
+
+const (
+	RoomVersionStable   RoomVersionStability = "stable"
+	RoomVersionUnstable RoomVersionStability = "unstable"
+)
+
+type RoomVersionsCapability struct {
+	Default   string                          `json:"default"`
+	Available map[string]RoomVersionStability `json:"available"`
+}
+
+// Stable returns the stable room versions sorted.
+func (c RoomVersionsCapability) Stable() []string {
+	versions := make([]string, 0, len(c.Available))
+	for version, stability := range c.Available {
+		if stability == RoomVersionStable {
+			versions = append(versions, version)
+		}
+	}
+	slices.SortFunc(versions, compareRoomVersions)
+	return versions
+}
+
+type BooleanCapability struct {
+	Enabled bool `json:"enabled"`
+}
+
+// https://spec.matrix.org/v1.13/client-server-api/#capabilities-negotiation
+type Capabilities struct {
+	RoomVersions   *RoomVersionsCapability `json:"m.room_versions,omitempty"`
+	ChangePassword *BooleanCapability      `json:"m.change_password,omitempty"`
+	SetDisplayName *BooleanCapability      `json:"m.set_displayname,omitempty"`
+	SetAvatarURL   *BooleanCapability      `json:"m.set_avatar_url,omitempty"`
+	ThreePIDChange *BooleanCapability      `json:"m.3pid_changes,omitempty"`
+}
+
+func (c *Client) GetCapabilities(ctx context.Context) (Capabilities, error) {
+	var respData apiCapabilitiesResp
+	err := c.doJSON(ctx, http.MethodGet, "/_matrix/client/v3/capabilities", nil, &respData)
+	if err != nil {
+		return Capabilities{}, fmt.Errorf("failed to get the capabilities: %w", err)
+	}
+	return respData.Capabilities, nil
+}
+
+// compareRoomVersions orders the numeric versions numerically and places them before the others.
+func compareRoomVersions(a, b string) int {
+	if len(a) != len(b) && isDigits(a) && isDigits(b) {
+		return len(a) - len(b)
+	}
+	if isDigits(a) != isDigits(b) {
+		if isDigits(a) {
+			return -1
+		}
+		return 1
+	}
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -4,11 +4,15 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
-	"strconv"
+	"reflect"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,6 +20,14 @@ import (
 
 const requestTimeout = time.Minute
 
+type MessageType string
+
+const (
+	Text   MessageType = "m.text"
+	Notice MessageType = "m.notice"
+	Emote  MessageType = "m.emote"
+)
+
 type MediaType string
 
 // https://spec.matrix.org/v1.13/client-server-api/#room-events
@@ -27,35 +39,255 @@ const (
 )
 
 type Credentials struct {
-	Server   string
+	Server string
+	// User is the user to log in as, or the user ID the AccessToken belongs to.
 	User     string
 	Password string
+	// Guest registers a guest account instead of logging in, the user and password are ignored.
+	// Guests can read world-readable rooms and join the rooms allowing guest access. The guest keeps its account
+	// by refreshing its token; the requests fail with ErrGuestExpired once it can't.
+	Guest bool
+	// RefreshTokens makes the password login ask for an expiring access token renewed with a refresh token.
+	RefreshTokens bool
+	// AccessToken is a token obtained elsewhere the client uses instead of logging in, e.g. of an appservice bot.
+	// Without a password the client can't log in again once the token is rejected. It takes the place of a stored
+	// session unless the session started from it, e.g. refreshed it.
+	AccessToken string
+	// DeviceID is the device the AccessToken belongs to, looked up with WhoAmI when needed if empty.
+	DeviceID string
+	// OAuth replaces the password login with the tokens of a next-gen auth login, refreshed when they expire.
+	OAuth *OAuthCredentials
+}
+
+type OAuthCredentials struct {
+	Client *OAuthClient
+	Token  OAuthToken
 }
 
 type Client struct {
 	credentials Credentials
 	httpClient  *http.Client
+	logger      *slog.Logger
 
 	mux            sync.RWMutex
 	token          string
+	userID         string
+	deviceID       string
+	refreshToken   string
+	expiresAt      time.Time
 	sessionStorage SessionStorage
+
+	statusMux       sync.Mutex
+	accountStatus   AccountStatus
+	onAccountStatus func(status AccountStatus, err error)
+
+	resolved         ResolvedServer
+	servers          *serverPool
+	headers          http.Header
+	compression      CompressionConfig
+	limiter          *requestLimiter
+	sendQueue        *sendQueue
+	broadcast        BroadcastConfig
+	broadcastLimiter *rateLimiter
+	syncFilter       string
+	syncStore        SyncStore
+	toDeviceStore    SyncStore
+	syncTimeout      time.Duration
+	syncPresence     Presence
+	fullState        atomic.Bool
+	backfillLimit    int
+	skipNotices      bool
+	strict           bool
+	workers          WorkerPoolConfig
+	autoJoinPolicy   *AutoJoinPolicy
+	onMembership     MembershipChangeHandler
+	stripImageMeta   bool
+	thumbnailer      ThumbnailEncoder
+	identityServer   IdentityServer
+	directMux        sync.Mutex
+	authMetadata     authMetadataCache
+	presence         *PresenceStore
+	members          *memberCache
+	stateStore       StateStore
+	summaries        *roomSummaries
+	oneTimeKeys      *oneTimeKeyManager
+	decryption       DecryptionConfig
+	withheld         *withheldKeys
+	keyRequests      *keyRequests
+	keyBackup        *keyBackup
+	lifecycle        *lifecycle
+	logoutOnClose    bool
 }
 
 type Config struct {
 	Credentials    Credentials
 	SessionStorage SessionStorage
 	HttpClient     *http.Client
+	// Logger receives the diagnostics of the client, e.g. the failovers and the retried syncs; nothing is logged by default.
+	Logger *slog.Logger
+	// Transport tunes the HTTP client created when HttpClient is not set.
+	Transport TransportConfig
+	Broadcast BroadcastConfig
+	RateLimit RateLimitConfig
+	// UserAgent identifies the client in the User-Agent header of the requests to the homeserver.
+	UserAgent string
+	// Headers are added to every request to the homeserver, unless the request sets them itself.
+	Headers http.Header
+	// Compression enables the compression of the large request bodies; the responses are always accepted gzipped.
+	Compression CompressionConfig
+	// Discovery resolves Credentials.Server given as a server name, e.g. example.org, into the client API endpoint.
+	Discovery *ServerDiscovery
+	// Failover lists the other base URLs of the homeserver the client switches to when Credentials.Server is unreachable.
+	Failover FailoverConfig
+	// OrderedSends makes the concurrent sends to the same room appear in the order they were made.
+	OrderedSends bool
+	// SyncFilter is applied to the syncs made by Listen, e.g. LazyLoadMembersFilter.
+	SyncFilter *Filter
+	// SyncTimeout is how long the syncs of Listen wait for new events, 30 seconds by default.
+	// Keep it below the timeout of the HTTP client.
+	SyncTimeout time.Duration
+	// SyncPresence is the presence the syncs of Listen set, online by default.
+	SyncPresence Presence
+	// SyncStore persists the sync position of Listen, so a restarted client resumes where it left off.
+	SyncStore SyncStore
+	// ToDeviceSyncStore persists the sync position of ListenToDevice, which moves independently of the one
+	// of Listen, so it can't be SyncStore.
+	ToDeviceSyncStore SyncStore
+	// AutoJoin makes Listen accept the invitations allowed by the policy, recording the direct chats in m.direct.
+	AutoJoin *AutoJoinPolicy
+	// OnMembershipChange is called by Listen for the membership changes of the joined rooms, and of the rooms
+	// the user left or was removed from.
+	OnMembershipChange MembershipChangeHandler
+	// StrictEvents validates the contents of the events sent and received against the schemas of their types,
+	// see ValidateContent. The invalid events are not sent, and the invalid events received are logged and not
+	// passed to the Listen handler.
+	StrictEvents bool
+	// Workers runs the Listen handler on a pool of workers, so a slow handler doesn't hold back the sync loop.
+	// The events of a room are handled in order.
+	Workers WorkerPoolConfig
+	// LogoutOnClose makes Close log the client out once it has shut down, e.g. for the short-lived
+	// services logging in with a password at every start.
+	LogoutOnClose bool
+	// SkipServerNotices stops Listen from passing the events of the server notices room to the handler,
+	// so the operator notices are not taken for user commands. See IsServerNoticeRoom.
+	SkipServerNotices bool
+	// OnAccountStatus is called when the account gets locked or suspended by the server administrators,
+	// and when it's found active again, e.g. to alert the operators.
+	OnAccountStatus func(status AccountStatus, err error)
+	// BackfillLimit caps the number of events Listen recovers from the room history when the server
+	// omits some of them from a sync response, 100 by default. A negative value disables the recovery.
+	BackfillLimit int
+	// StripImageMetadata removes EXIF and other metadata from JPEG, PNG and WebP images before uploading them.
+	StripImageMetadata bool
+	// ThumbnailEncoder makes NewMediaFromFile and NewMediaFromReader attach thumbnails to images and videos,
+	// e.g. ImageThumbnailer for images only or FFmpegThumbnailer for both. The media it fails on is sent without one.
+	ThumbnailEncoder ThumbnailEncoder
+	// IdentityServer is the identity server InviteByEmail asks the homeserver to use, e.g. *identity.Client.
+	IdentityServer IdentityServer
+	// PresenceStore is kept up to date by Listen with the presence of the users sharing rooms with the client.
+	PresenceStore *PresenceStore
+	// StateStore is kept up to date by Listen with the state of the rooms the client is in.
+	StateStore StateStore
+	// OneTimeKeys makes Listen keep the one-time and fallback keys of the device published.
+	OneTimeKeys OneTimeKeysConfig
+	// Decryption plugs the decryption of the encrypted events into Listen.
+	Decryption DecryptionConfig
+	// KeyBackup makes Listen upload the received Megolm sessions to the server-side key backup.
+	KeyBackup KeyBackupConfig
 }
 
 func NewClientWithConfig(cfg Config) (*Client, error) {
+	if cfg.ToDeviceSyncStore != nil && reflect.TypeOf(cfg.ToDeviceSyncStore).Comparable() &&
+		cfg.ToDeviceSyncStore == cfg.SyncStore {
+		return nil, errors.New("the sync stores of Listen and ListenToDevice must differ")
+	}
+	if cfg.SyncTimeout <= 0 {
+		cfg.SyncTimeout = defaultSyncTimeout
+	}
 	if cfg.HttpClient == nil {
-		cfg.HttpClient = &http.Client{Timeout: requestTimeout}
+		if cfg.Transport.ResponseHeaderTimeout <= 0 {
+			cfg.Transport.ResponseHeaderTimeout = cfg.SyncTimeout + requestTimeout
+		}
+		// no overall timeout, it would cut the long uploads, downloads and syncs; the dial, the TLS handshake
+		// and the response headers are bounded by the transport, the rest by the context of the request
+		cfg.HttpClient = &http.Client{Transport: cfg.Transport.newTransport()}
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	if cfg.BackfillLimit == 0 {
+		cfg.BackfillLimit = defaultBackfillLimit
+	}
+	if cfg.Broadcast.Concurrency <= 0 {
+		cfg.Broadcast.Concurrency = defaultBroadcastConcurrency
+	}
+	if cfg.Broadcast.Interval == 0 {
+		cfg.Broadcast.Interval = defaultBroadcastInterval
+	}
+
+	syncFilter, err := cfg.SyncFilter.encode()
+	if err != nil {
+		return nil, err
+	}
+
+	var resolved ResolvedServer
+	if cfg.Discovery != nil && !strings.Contains(cfg.Credentials.Server, "://") {
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		resolved, err = cfg.Discovery.Resolve(ctx, cfg.Credentials.Server)
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+		cfg.Credentials.Server = resolved.BaseURL
 	}
 
+	headers := defaultHeaders(cfg.UserAgent, cfg.Headers)
 	c := &Client{
 		credentials:    cfg.Credentials,
 		httpClient:     cfg.HttpClient,
+		logger:         cfg.Logger,
 		sessionStorage: cfg.SessionStorage,
+
+		accountStatus:   AccountActive,
+		onAccountStatus: cfg.OnAccountStatus,
+
+		resolved:         resolved,
+		servers:          newServerPool(cfg.Credentials.Server, cfg.HttpClient, headers, cfg.Failover),
+		headers:          headers,
+		compression:      cfg.Compression,
+		limiter:          newRequestLimiter(cfg.RateLimit),
+		broadcast:        cfg.Broadcast,
+		broadcastLimiter: newRateLimiter(RateLimit{Rate: float64(time.Second) / float64(cfg.Broadcast.Interval)}),
+		syncFilter:       syncFilter,
+		syncStore:        cfg.SyncStore,
+		toDeviceStore:    cfg.ToDeviceSyncStore,
+		syncTimeout:      cfg.SyncTimeout,
+		syncPresence:     cfg.SyncPresence,
+		backfillLimit:    cfg.BackfillLimit,
+		skipNotices:      cfg.SkipServerNotices,
+		strict:           cfg.StrictEvents,
+		workers:          cfg.Workers,
+		autoJoinPolicy:   cfg.AutoJoin,
+		onMembership:     cfg.OnMembershipChange,
+		stripImageMeta:   cfg.StripImageMetadata,
+		thumbnailer:      cfg.ThumbnailEncoder,
+		identityServer:   cfg.IdentityServer,
+		presence:         cfg.PresenceStore,
+		members:          newMemberCache(),
+		stateStore:       cfg.StateStore,
+		summaries:        newRoomSummaries(),
+		decryption:       cfg.Decryption,
+		withheld:         newWithheldKeys(),
+		keyRequests:      newKeyRequests(),
+		lifecycle:        newLifecycle(),
+		logoutOnClose:    cfg.LogoutOnClose,
+	}
+
+	c.oneTimeKeys = newOneTimeKeyManager(c, cfg.OneTimeKeys)
+	c.keyBackup = newKeyBackup(c, cfg.KeyBackup)
+
+	if cfg.OrderedSends {
+		c.sendQueue = newSendQueue()
 	}
 
 	if c.sessionStorage != nil {
@@ -64,8 +296,42 @@ func NewClientWithConfig(cfg Config) (*Client, error) {
 			return nil, err
 		}
 
-		if sess.AccessToken != "" {
-			c.token = sess.AccessToken
+		// a session of another homeserver is of no use, and a session started from a token the configuration
+		// no longer has is replaced by the configured one
+		origin := c.credentials.tokenOrigin()
+		if sess.AccessToken != "" && (sess.Homeserver == "" || sess.Homeserver == c.credentials.Server) &&
+			(origin == "" || sess.TokenOrigin == origin) {
+			if sess.Version < SessionVersion {
+				err = c.setSession(sess)
+			} else {
+				c.restoreSession(sess)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if c.token == "" && cfg.Credentials.AccessToken != "" {
+		sess := Session{AccessToken: cfg.Credentials.AccessToken, DeviceID: cfg.Credentials.DeviceID}
+		if strings.HasPrefix(cfg.Credentials.User, "@") {
+			sess.UserID = cfg.Credentials.User
+		}
+		err := c.setSession(sess)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if oauth := cfg.Credentials.OAuth; c.token == "" && oauth != nil && oauth.Token.AccessToken != "" {
+		err := c.setSession(Session{
+			AccessToken:  oauth.Token.AccessToken,
+			DeviceID:     oauth.Token.DeviceID,
+			RefreshToken: oauth.Token.RefreshToken,
+			ExpiresAt:    expiresAt(time.Duration(oauth.Token.ExpiresIn) * time.Second),
+		})
+		if err != nil {
+			return nil, err
 		}
 	}
 
@@ -83,18 +349,36 @@ func NewClient(cred Credentials) (*Client, error) {
 	return NewClientWithConfig(Config{Credentials: cred})
 }
 
-func (c *Client) SendText(ctx context.Context, roomID, text string) error {
+func (c *Client) SendText(ctx context.Context, roomID, text string) (string, error) {
 	return c.sendMessage(ctx, apiSendMsgReq{
 		RoomID: roomID,
-		Type:   "m.text",
+		Type:   string(Text),
 		Body:   text,
 	})
 }
 
-func (c *Client) SendHTML(ctx context.Context, roomID, html string) error {
+// SendNotice sends an automated message; bots are expected to use notices so other bots don't respond to them.
+func (c *Client) SendNotice(ctx context.Context, roomID, text string) (string, error) {
+	return c.sendMessage(ctx, apiSendMsgReq{
+		RoomID: roomID,
+		Type:   string(Notice),
+		Body:   text,
+	})
+}
+
+// SendEmote sends an action message, like /me in IRC.
+func (c *Client) SendEmote(ctx context.Context, roomID, text string) (string, error) {
+	return c.sendMessage(ctx, apiSendMsgReq{
+		RoomID: roomID,
+		Type:   string(Emote),
+		Body:   text,
+	})
+}
+
+func (c *Client) SendHTML(ctx context.Context, roomID, html string) (string, error) {
 	return c.sendMessage(ctx, apiSendMsgReq{
 		RoomID:        roomID,
-		Type:          "m.text",
+		Type:          string(Text),
 		Format:        "org.matrix.custom.html",
 		Body:          html,
 		FormattedBody: html,
@@ -105,53 +389,73 @@ type Media struct {
 	Type     MediaType
 	Caption  string
 	Filename string
-	URI      string
+	URI      MXCURI
+	Info     *MediaInfo
 }
 
-func (c *Client) SendMedia(ctx context.Context, roomID string, media Media) error {
+func (c *Client) SendMedia(ctx context.Context, roomID string, media Media) (string, error) {
 	return c.sendMessage(ctx, apiSendMsgReq{
 		RoomID:   roomID,
 		Type:     string(media.Type),
 		Body:     media.Caption,
 		Filename: media.Filename,
-		URL:      media.URI,
+		URL:      media.URI.String(),
+		Info:     media.Info,
 	})
 }
 
-func (c *Client) sendMessage(ctx context.Context, msg apiSendMsgReq) error {
-	payload, err := json.Marshal(msg)
+func (c *Client) sendMessage(ctx context.Context, msg apiSendMsgReq) (string, error) {
+	eventID, err := c.sendEvent(ctx, msg.RoomID, "m.room.message", msg)
 	if err != nil {
-		return fmt.Errorf("failed to marshal message payload")
+		return "", fmt.Errorf("failed to send a message: %w", err)
 	}
+	return eventID, nil
+}
 
-	path := fmt.Sprintf("/_matrix/client/v3/rooms/%s/send/m.room.message/%s", msg.RoomID, uuid.NewString())
-	resp, err := c.doRequest(ctx, http.MethodPut, path, payload, func(r *http.Request) {
-		r.Header.Set("Content-Type", "application/json")
-	}, true)
+func (c *Client) sendEvent(ctx context.Context, roomID, eventType string, content any) (string, error) {
+	payload, err := json.Marshal(content)
 	if err != nil {
-		return fmt.Errorf("failed to send a message: %w", err)
+		return "", fmt.Errorf("failed to marshal event payload: %w", err)
+	}
+	if c.strict {
+		err = ValidateContent(eventType, payload)
+		if err != nil {
+			return "", err
+		}
 	}
-	defer resp.Body.Close()
-	return nil
-}
 
-func (c *Client) UploadFile(ctx context.Context, contentType string, data []byte) (string, error) {
-	resp, err := c.doRequest(ctx, http.MethodPost, "/_matrix/media/v3/upload", data, func(r *http.Request) {
-		r.Header.Set("Content-Type", contentType)
-		r.Header.Set("Content-Length", strconv.Itoa(len(data)))
-	}, true)
+	done, err := c.lifecycle.send()
 	if err != nil {
-		return "", fmt.Errorf("failed to upload a file: %w", err)
+		return "", err
 	}
-	defer resp.Body.Close()
+	defer done()
+
+	var respData apiSendEventResp
+	err = c.sendQueue.Do(ctx, roomID, func() error {
+		path := c.roomPath(roomID, "send", eventType, uuid.NewString())
+		resp, err := c.doRequest(ctx, http.MethodPut, path, payload, func(r *http.Request) {
+			r.Header.Set("Content-Type", "application/json")
+		}, true)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
 
-	var respData apiUploadResp
-	err = json.NewDecoder(resp.Body).Decode(&respData)
+		err = json.NewDecoder(resp.Body).Decode(&respData)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal send event response: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to unmarshal upload file response: %w", err)
+		return "", err
 	}
 
-	return respData.URI, nil
+	return respData.EventID, nil
+}
+
+func (c *Client) UploadFile(ctx context.Context, contentType string, data []byte) (MXCURI, error) {
+	return c.UploadStream(ctx, contentType, bytes.NewReader(data), int64(len(data)), UploadOptions{})
 }
 
 func (c *Client) authenticate(prevToken string) error {
@@ -162,43 +466,130 @@ func (c *Client) authenticate(prevToken string) error {
 		return nil
 	}
 
-	payload, err := json.Marshal(apiLoginReq{
-		Type:     "m.login.password",
-		User:     c.credentials.User,
-		Password: c.credentials.Password,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to marshal auth payload: %w", err)
-	}
-
 	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
 	defer cancel()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.credentials.Server+"/_matrix/client/v3/login", bytes.NewReader(payload))
+	if prevToken != "" {
+		c.logger.Info("the access token was rejected or expired, authenticating again")
+	}
+
+	if c.credentials.OAuth != nil {
+		return c.refreshOAuthToken(ctx)
+	}
+
+	if c.refreshToken != "" {
+		err := c.refreshAccessToken(ctx)
+		if err == nil || c.credentials.Password == "" {
+			return err
+		}
+		// the refresh token may have been revoked, logging in again
+	}
+
+	if !c.credentials.Guest && c.credentials.Password == "" {
+		return ErrNoPassword
+	}
+	if c.credentials.Guest && prevToken != "" {
+		return ErrGuestExpired
+	}
+
+	path := "/_matrix/client/v3/login"
+	var reqData any = apiLoginReq{
+		Type:         "m.login.password",
+		User:         c.credentials.User,
+		Password:     c.credentials.Password,
+		RefreshToken: c.credentials.RefreshTokens,
+	}
+	if c.credentials.Guest {
+		// the guest is renewed with the refresh token, a guest can't log in again
+		path, reqData = "/_matrix/client/v3/register?kind=guest", apiRegisterReq{RefreshToken: true}
+	}
+
+	var respData apiLoginResp
+	err := c.postAuth(ctx, path, reqData, &respData)
 	if err != nil {
-		return fmt.Errorf("failed to create an auth request: %w", err)
+		return err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	return c.setSession(Session{
+		AccessToken:  respData.AccessToken,
+		DeviceID:     respData.DeviceID,
+		RefreshToken: respData.RefreshToken,
+		ExpiresAt:    expiresAt(time.Duration(respData.ExpiresInMs) * time.Millisecond),
+		UserID:       respData.UserID,
+	})
+}
+
+// postAuth sends an unauthenticated request obtaining a new access token.
+func (c *Client) postAuth(ctx context.Context, path string, reqData, respData any) error {
+	payload, err := json.Marshal(reqData)
+	if err != nil {
+		return fmt.Errorf("failed to marshal auth payload: %w", err)
+	}
 
-	resp, err := http.DefaultClient.Do(req)
+	body := bytes.NewReader(payload)
+	resp, err := c.sendWithFailover(func(server string) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, server+path, body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create an auth request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	}, func() error {
+		_, err := body.Seek(0, io.SeekStart)
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("failed to do an auth request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
-		respBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("auth - unexpected status code: %d; body: %s", resp.StatusCode, respBody)
+		httpErr := readHTTPError(resp)
+		if statusErr := newAccountStatusError(httpErr); statusErr != nil {
+			c.setAccountStatus(statusErr.Status, statusErr)
+			return fmt.Errorf("auth failed: %w", statusErr)
+		}
+		return fmt.Errorf("auth failed: %w", httpErr)
 	}
 
-	var sess Session
-	err = json.NewDecoder(resp.Body).Decode(&sess)
+	err = json.NewDecoder(resp.Body).Decode(respData)
 	if err != nil {
 		return fmt.Errorf("failed to unmarshal auth session: %w", err)
 	}
+	return nil
+}
 
-	c.token = sess.AccessToken
+// refreshOAuthToken renews the access token with the refresh token of the OAuth login; c.mux must be held.
+func (c *Client) refreshOAuthToken(ctx context.Context) error {
+	if c.refreshToken == "" {
+		return ErrNoRefreshToken
+	}
+
+	token, err := c.credentials.OAuth.Client.Refresh(ctx, c.refreshToken)
+	if err != nil {
+		return err
+	}
+
+	return c.setSession(Session{
+		AccessToken:  token.AccessToken,
+		DeviceID:     c.deviceID,
+		RefreshToken: token.RefreshToken,
+		ExpiresAt:    expiresAt(time.Duration(token.ExpiresIn) * time.Second),
+	})
+}
+
+// setSession switches the client to the session and persists it upgraded to the current version;
+// c.mux must be held if the client is in use.
+func (c *Client) setSession(sess Session) error {
+	sess.Version = SessionVersion
+	sess.Homeserver = c.credentials.Server
+	sess.TokenOrigin = c.credentials.tokenOrigin()
+	if sess.UserID == "" {
+		// the refreshed tokens belong to the same user
+		sess.UserID = c.userID
+	}
+
+	c.restoreSession(sess)
 	if c.sessionStorage != nil {
 		return c.sessionStorage.Set(sess)
 	}
@@ -206,35 +597,102 @@ func (c *Client) authenticate(prevToken string) error {
 	return nil
 }
 
+func (c *Client) restoreSession(sess Session) {
+	c.token = sess.AccessToken
+	c.deviceID = sess.DeviceID
+	c.refreshToken = sess.RefreshToken
+	c.expiresAt = sess.ExpiresAt
+	c.userID = sess.UserID
+}
+
+// expiresAt returns the expiry of a token valid for the duration, zero if it doesn't expire.
+func expiresAt(d time.Duration) time.Time {
+	if d <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(d)
+}
+
 func (c *Client) doRequest(
 	ctx context.Context, method, path string, payload []byte, reqFn func(r *http.Request), tryAuth bool,
 ) (*http.Response, error) {
-	req, err := http.NewRequestWithContext(ctx, method, c.credentials.Server+path, bytes.NewReader(payload))
+	compressed, err := c.compressPayload(payload)
+	if err != nil {
+		return nil, err
+	}
+	if compressed != nil {
+		payload = compressed
+		setHeaders := reqFn
+		reqFn = func(r *http.Request) {
+			if setHeaders != nil {
+				setHeaders(r)
+			}
+			r.Header.Set("Content-Encoding", "gzip")
+		}
+	}
+
+	return c.doBodyRequest(ctx, method, path, bytes.NewReader(payload), reqFn, tryAuth)
+}
+
+// doBodyRequest sends the body as is; the request is retried after re-authentication
+// only if the body can be rewound.
+func (c *Client) doBodyRequest(
+	ctx context.Context, method, path string, body io.Reader, reqFn func(r *http.Request), tryAuth bool,
+) (*http.Response, error) {
+	err := c.limiter.Wait(ctx, path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create a request: %w", err)
+		return nil, err
+	}
+
+	seeker, canRewind := body.(io.Seeker)
+	var start int64
+	if canRewind {
+		start, err = seeker.Seek(0, io.SeekCurrent)
+		canRewind = err == nil
 	}
 
 	token := c.getToken()
-	req.Header.Set("Authorization", "Bearer "+token)
+	newReq := func(server string) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, method, server+path, body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create a request: %w", err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+token)
 
-	if reqFn != nil {
-		reqFn(req)
+		if reqFn != nil {
+			reqFn(req)
+		}
+		return req, nil
+	}
+	var rewind func() error
+	if canRewind {
+		rewind = func() error {
+			_, err := seeker.Seek(start, io.SeekStart)
+			return err
+		}
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := c.sendWithFailover(newReq, rewind)
 	if err != nil {
 		return nil, fmt.Errorf("failed to do a request: %w", err)
 	}
 
 	if resp.StatusCode < 400 {
+		c.observeSuccess(method)
 		return resp, nil
 	}
 
 	defer resp.Body.Close()
 
-	if !tryAuth || resp.StatusCode != http.StatusUnauthorized {
-		respBody, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status code: %d; body: %s", resp.StatusCode, respBody)
+	httpErr := readHTTPError(resp)
+	if statusErr := newAccountStatusError(httpErr); statusErr != nil {
+		c.setAccountStatus(statusErr.Status, statusErr)
+		return nil, statusErr
+	}
+
+	if !tryAuth || !canRewind || resp.StatusCode != http.StatusUnauthorized || isUIAResponse(httpErr) {
+		return nil, httpErr
 	}
 
 	err = c.authenticate(token)
@@ -242,7 +700,44 @@ func (c *Client) doRequest(
 		return nil, err
 	}
 
-	return c.doRequest(ctx, method, path, payload, reqFn, false)
+	_, err = seeker.Seek(start, io.SeekStart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rewind the request body: %w", err)
+	}
+
+	return c.doBodyRequest(ctx, method, path, body, reqFn, false)
+}
+
+func (c *Client) doJSON(ctx context.Context, method, path string, reqData, respData any) error {
+	var payload []byte
+	if reqData != nil {
+		var err error
+		payload, err = json.Marshal(reqData)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request payload: %w", err)
+		}
+	}
+
+	resp, err := c.doRequest(ctx, method, path, payload, func(r *http.Request) {
+		if reqData != nil {
+			r.Header.Set("Content-Type", "application/json")
+		}
+	}, true)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if respData == nil {
+		return nil
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(respData)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return nil
 }
 
 func (c *Client) getToken() string {
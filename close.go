@@ -0,0 +1,145 @@
+package gomatrix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// ErrClosed is returned by Listen once the client is closed, and by the sends made after that.
+var ErrClosed = errors.New("the client is closed")
+
+// lifecycle tracks the sync loops and the sends in flight, so Close can wait for them.
+type lifecycle struct {
+	mux       sync.Mutex
+	closing   bool
+	closed    bool
+	stop      context.Context
+	cancel    context.CancelFunc
+	listening sync.WaitGroup
+	sending   sync.WaitGroup
+}
+
+func newLifecycle() *lifecycle {
+	stop, cancel := context.WithCancel(context.Background())
+	return &lifecycle{stop: stop, cancel: cancel}
+}
+
+// listen registers a sync loop.
+func (l *lifecycle) listen() (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	if l.closing {
+		return nil, ErrClosed
+	}
+	l.listening.Add(1)
+	return l.listening.Done, nil
+}
+
+// stopOnClose returns a context canceled when the client is closed, to end the syncs while the handlers
+// keep the context of Listen.
+func (l *lifecycle) stopOnClose(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	if l == nil {
+		return ctx, cancel
+	}
+
+	stopAfter := context.AfterFunc(l.stop, cancel)
+	return ctx, func() {
+		stopAfter()
+		cancel()
+	}
+}
+
+// send registers a send; the sends are still accepted while the handlers of the stopped sync loops finish.
+func (l *lifecycle) send() (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	if l.closed {
+		return nil, ErrClosed
+	}
+	l.sending.Add(1)
+	return l.sending.Done, nil
+}
+
+// stopped reports whether the context of a sync loop was canceled by Close.
+func (l *lifecycle) stopped() bool {
+	return l != nil && l.stop.Err() != nil
+}
+
+// Close shuts the client down without losing what's in flight: it stops the sync loops of Listen, letting
+// their handlers finish, waits for the pending sends, uploads the queued room keys and one-time keys,
+// then logs out if Config.LogoutOnClose is set. The sends made afterwards fail with ErrClosed.
+// If ctx is done first, Close returns with what's left unfinished.
+func (c *Client) Close(ctx context.Context) error {
+	l := c.lifecycle
+	if l == nil {
+		return nil
+	}
+
+	l.mux.Lock()
+	if l.closing {
+		l.mux.Unlock()
+		return ErrClosed
+	}
+	l.closing = true
+	l.mux.Unlock()
+
+	l.cancel()
+	err := wait(ctx, &l.listening)
+	if err != nil {
+		return fmt.Errorf("failed to stop the sync loops: %w", err)
+	}
+
+	l.mux.Lock()
+	l.closed = true
+	l.mux.Unlock()
+
+	err = wait(ctx, &l.sending)
+	if err != nil {
+		return fmt.Errorf("failed to wait for the pending sends: %w", err)
+	}
+
+	errs := []error{c.oneTimeKeys.flush(ctx), c.keyBackup.flush(ctx)}
+	if c.logoutOnClose {
+		errs = append(errs, c.Logout(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// Logout invalidates the access token of the client and forgets the stored session.
+func (c *Client) Logout(ctx context.Context) error {
+	err := c.doJSON(ctx, http.MethodPost, "/_matrix/client/v3/logout", struct{}{}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to log out: %w", err)
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.setSession(Session{})
+}
+
+func wait(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,180 @@
+// Command matrix-send sends a message and files to a room, e.g. from a cron job:
+//
+//	matrix-send --room '#alerts:example.org' --file report.pdf "build failed"
+//
+// The message is read from the standard input when it's not given as arguments and no file is sent.
+// The credentials are read from a JSON config file, $XDG_CONFIG_HOME/matrix-send/config.json by default:
+//
+//	{"server": "https://matrix.example.org", "user": "@ci:example.org", "access_token": "..."}
+//
+// A password may be given instead of the access token, though every run then logs in as a new device.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	gomatrix "github.com/beldeveloper/go-matrix"
+)
+
+type config struct {
+	Server      string `json:"server"`
+	User        string `json:"user"`
+	Password    string `json:"password"`
+	AccessToken string `json:"access_token"`
+}
+
+type files []string
+
+func (f *files) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *files) Set(path string) error {
+	*f = append(*f, path)
+	return nil
+}
+
+func main() {
+	var attachments files
+	configPath := flag.String("config", defaultConfigPath(), "path of the JSON config file with the credentials")
+	room := flag.String("room", "", "room ID or alias to send to")
+	notice := flag.Bool("notice", false, "send the message as a notice, as bots usually do")
+	html := flag.Bool("html", false, "send the message as HTML")
+	join := flag.Bool("join", false, "join the room before sending")
+	timeout := flag.Duration("timeout", 5*time.Minute, "timeout of the whole run")
+	flag.Var(&attachments, "file", "file to send, may be repeated")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s --room ROOM [--file FILE]... [MESSAGE...]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *room == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	ctx, cancel = context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	err := run(ctx, *configPath, *room, *notice, *html, *join, attachments, strings.Join(flag.Args(), " "))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "matrix-send:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, configPath, room string, notice, html, join bool, attachments []string, text string) error {
+	cfg, err := readConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	if text == "" && len(attachments) == 0 {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read the message: %w", err)
+		}
+		text = strings.TrimSpace(string(data))
+	}
+	if text == "" && len(attachments) == 0 {
+		return errors.New("nothing to send")
+	}
+
+	opts := []gomatrix.Option{gomatrix.WithConfig(func(c *gomatrix.Config) {
+		c.UserAgent = "matrix-send"
+	})}
+	if cfg.AccessToken != "" {
+		opts = append(opts, gomatrix.WithToken(cfg.User, cfg.AccessToken))
+	} else {
+		opts = append(opts, gomatrix.WithPassword(cfg.User, cfg.Password))
+	}
+	client, err := gomatrix.New(cfg.Server, opts...)
+	if err != nil {
+		return err
+	}
+
+	roomID := room
+	if strings.HasPrefix(room, "#") {
+		roomID, _, err = client.ResolveAlias(ctx, room)
+		if err != nil {
+			return err
+		}
+	}
+	if join {
+		roomID, err = client.JoinRoom(ctx, room)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, path := range attachments {
+		media, err := client.NewMediaFromFile(ctx, path)
+		if err != nil {
+			return err
+		}
+		// a single file carries the message as its caption
+		if len(attachments) == 1 && text != "" && !html {
+			media.Caption, text = text, ""
+		}
+
+		_, err = client.SendMedia(ctx, roomID, media)
+		if err != nil {
+			return fmt.Errorf("failed to send file %d: %w", i+1, err)
+		}
+	}
+
+	if text == "" {
+		return nil
+	}
+
+	switch {
+	case html:
+		_, err = client.SendHTML(ctx, roomID, text)
+	case notice:
+		_, err = client.SendNotice(ctx, roomID, text)
+	default:
+		_, err = client.SendText(ctx, roomID, text)
+	}
+	return err
+}
+
+func readConfig(path string) (config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config{}, fmt.Errorf("failed to read the config: %w", err)
+	}
+
+	var cfg config
+	err = json.Unmarshal(data, &cfg)
+	if err != nil {
+		return config{}, fmt.Errorf("failed to parse the config %s: %w", path, err)
+	}
+	if cfg.Server == "" {
+		return config{}, fmt.Errorf("no server in the config %s", path)
+	}
+	if cfg.AccessToken == "" && (cfg.User == "" || cfg.Password == "") {
+		return config{}, fmt.Errorf("neither an access token nor a user and a password in the config %s", path)
+	}
+	return cfg, nil
+}
+
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "matrix-send.json"
+	}
+	return filepath.Join(dir, "matrix-send", "config.json")
+}
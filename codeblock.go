@@ -0,0 +1,51 @@
+package gomatrix
+
+import (
+	"context"
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	backtickRun   = regexp.MustCompile("`{3,}")
+	languageClass = regexp.MustCompile(`^[A-Za-z0-9_+#.-]+$`)
+)
+
+// CodeBlock returns the code as a Markdown fenced block for the plain text body and as a preformatted
+// block for the HTML body. The language, e.g. go, lets clients highlight the syntax; it's dropped
+// unless made of letters, digits and _+#.- only.
+func CodeBlock(language, code string) (text, htmlText string) {
+	if !languageClass.MatchString(language) {
+		language = ""
+	}
+	code = strings.TrimSuffix(code, "\n")
+
+	// the fence must be longer than any backtick run of the code
+	fence := "```"
+	for _, run := range backtickRun.FindAllString(code, -1) {
+		if len(run) >= len(fence) {
+			fence = strings.Repeat("`", len(run)+1)
+		}
+	}
+	text = fence + language + "\n" + code + "\n" + fence
+
+	class := ""
+	if language != "" {
+		class = ` class="language-` + language + `"`
+	}
+	htmlText = "<pre><code" + class + ">" + html.EscapeString(code) + "\n</code></pre>"
+	return text, htmlText
+}
+
+// SendCode sends the code or log as a code block, see CodeBlock.
+func (c *Client) SendCode(ctx context.Context, roomID, language, code string) (string, error) {
+	text, htmlText := CodeBlock(language, code)
+	return c.sendMessage(ctx, apiSendMsgReq{
+		RoomID:        roomID,
+		Type:          string(Text),
+		Format:        "org.matrix.custom.html",
+		Body:          text,
+		FormattedBody: htmlText,
+	})
+}
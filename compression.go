@@ -0,0 +1,76 @@
+package gomatrix
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const defaultGzipThreshold = 32 << 10
+
+type CompressionConfig struct {
+	// GzipRequests compresses the request bodies larger than GzipThreshold. Enable it only if the homeserver,
+	// or the reverse proxy in front of it, accepts the Content-Encoding: gzip requests.
+	GzipRequests bool
+	// GzipThreshold is 32 KiB by default.
+	GzipThreshold int
+}
+
+// compressPayload returns the gzipped payload if it's worth compressing, or nil.
+func (c *Client) compressPayload(payload []byte) ([]byte, error) {
+	if !c.compression.GzipRequests {
+		return nil, nil
+	}
+	threshold := c.compression.GzipThreshold
+	if threshold <= 0 {
+		threshold = defaultGzipThreshold
+	}
+	if len(payload) < threshold {
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write(payload)
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to compress the request payload: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// decompressResponse decodes the gzipped responses net/http hasn't decoded, which happens
+// when the Accept-Encoding header is set explicitly or the transport is not the standard one.
+func decompressResponse(resp *http.Response) error {
+	if resp.Uncompressed || !strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		return nil
+	}
+
+	r, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		resp.Body.Close()
+		return fmt.Errorf("failed to decompress the response: %w", err)
+	}
+
+	resp.Body = gzipBody{Reader: r, body: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
+
+type gzipBody struct {
+	*gzip.Reader
+	body io.ReadCloser
+}
+
+func (b gzipBody) Close() error {
+	_ = b.Reader.Close()
+	return b.body.Close()
+}
@@ -0,0 +1,89 @@
+package gomatrix
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type RoomPreset string
+
+const (
+	PresetPrivateChat        RoomPreset = "private_chat"
+	PresetTrustedPrivateChat RoomPreset = "trusted_private_chat"
+	PresetPublicChat         RoomPreset = "public_chat"
+)
+
+type RoomVisibility string
+
+const (
+	VisibilityPublic  RoomVisibility = "public"
+	VisibilityPrivate RoomVisibility = "private"
+)
+
+type StateEvent struct {
+	Type     string `json:"type"`
+	StateKey string `json:"state_key"`
+	Content  any    `json:"content"`
+}
+
+// https://spec.matrix.org/v1.13/client-server-api/#post_matrixclientv3createroom
+type CreateRoomRequest struct {
+	Name       string         `json:"name,omitempty"`
+	Topic      string         `json:"topic,omitempty"`
+	Preset     RoomPreset     `json:"preset,omitempty"`
+	Visibility RoomVisibility `json:"visibility,omitempty"`
+	// AliasLocalpart is the local part of the canonical alias, e.g. "general" for #general:example.org.
+	AliasLocalpart string   `json:"room_alias_name,omitempty"`
+	Invite         []string `json:"invite,omitempty"`
+	IsDirect       bool     `json:"is_direct,omitempty"`
+	// RoomVersion is checked against the versions the server supports, the server default is used if empty.
+	RoomVersion               string         `json:"room_version,omitempty"`
+	CreationContent           map[string]any `json:"creation_content,omitempty"`
+	InitialState              []StateEvent   `json:"initial_state,omitempty"`
+	PowerLevelContentOverride map[string]any `json:"power_level_content_override,omitempty"`
+}
+
+// UnsupportedRoomVersionError is returned by CreateRoom when the server doesn't support the requested room version.
+type UnsupportedRoomVersionError struct {
+	Version string
+	// Available are the stable versions supported by the server.
+	Available []string
+	Default   string
+}
+
+func (e *UnsupportedRoomVersionError) Error() string {
+	return fmt.Sprintf(
+		"room version %q is not supported; stable versions: %s; default: %s",
+		e.Version, strings.Join(e.Available, ", "), e.Default,
+	)
+}
+
+// CreateRoom creates the room and returns its ID. If a room version is requested, it is validated
+// against the server capabilities first; the unstable versions the server advertises are accepted too.
+func (c *Client) CreateRoom(ctx context.Context, req CreateRoomRequest) (string, error) {
+	if req.RoomVersion != "" {
+		caps, err := c.GetCapabilities(ctx)
+		if err != nil {
+			return "", err
+		}
+		// servers not advertising room versions are left to reject the request themselves
+		if versions := caps.RoomVersions; versions != nil {
+			if _, ok := versions.Available[req.RoomVersion]; !ok {
+				return "", &UnsupportedRoomVersionError{
+					Version:   req.RoomVersion,
+					Available: versions.Stable(),
+					Default:   versions.Default,
+				}
+			}
+		}
+	}
+
+	var respData apiJoinRoomResp
+	err := c.doJSON(ctx, http.MethodPost, "/_matrix/client/v3/createRoom", req, &respData)
+	if err != nil {
+		return "", fmt.Errorf("failed to create the room: %w", err)
+	}
+	return respData.RoomID, nil
+}
@@ -0,0 +1,180 @@
+package gomatrix
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron is a parsed standard five-field cron expression: minute, hour, day of month, month and day of week.
+type Cron struct {
+	spec     string
+	minute   uint64
+	hour     uint64
+	dom      uint64
+	month    uint64
+	dow      uint64
+	anyDom   bool
+	anyDow   bool
+	location *time.Location
+}
+
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = [5]cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12},
+	{name: "day of week", min: 0, max: 7},
+}
+
+func ParseCron(spec string) (Cron, error) {
+	parts := strings.Fields(spec)
+	if len(parts) != len(cronFields) {
+		return Cron{}, fmt.Errorf("cron expression must have %d fields, got %d", len(cronFields), len(parts))
+	}
+
+	var bits [5]uint64
+	for i, part := range parts {
+		var err error
+		bits[i], err = parseCronField(part, cronFields[i])
+		if err != nil {
+			return Cron{}, err
+		}
+	}
+
+	// both 0 and 7 stand for Sunday
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+
+	return Cron{
+		spec:     strings.Join(parts, " "),
+		minute:   bits[0],
+		hour:     bits[1],
+		dom:      bits[2],
+		month:    bits[3],
+		dow:      bits[4],
+		anyDom:   parts[2] == "*",
+		anyDow:   parts[4] == "*",
+		location: time.Local,
+	}, nil
+}
+
+func (c Cron) String() string {
+	return c.spec
+}
+
+// In returns the cron evaluated in the time zone, UTC if loc is nil.
+func (c Cron) In(loc *time.Location) Cron {
+	if loc == nil {
+		loc = time.UTC
+	}
+	c.location = loc
+	return c
+}
+
+// Match reports whether the cron fires in the minute containing t.
+func (c Cron) Match(t time.Time) bool {
+	t = t.In(c.location)
+
+	if c.minute&(1<<t.Minute()) == 0 || c.hour&(1<<t.Hour()) == 0 || c.month&(1<<int(t.Month())) == 0 {
+		return false
+	}
+
+	return c.matchDay(t)
+}
+
+// Next returns the first minute after t when the cron fires.
+// The zero time is returned if the expression never fires (e.g. February 30).
+func (c Cron) Next(t time.Time) time.Time {
+	t = t.In(c.location).Truncate(time.Minute).Add(time.Minute)
+
+	// five years cover every possible day of month and day of week combination
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if c.month&(1<<int(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, c.location)
+			continue
+		}
+		if !c.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, c.location)
+			continue
+		}
+		if c.hour&(1<<t.Hour()) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, c.location)
+			continue
+		}
+		if c.minute&(1<<t.Minute()) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+
+	return time.Time{}
+}
+
+func (c Cron) matchDay(t time.Time) bool {
+	domMatch := c.dom&(1<<t.Day()) != 0
+	dowMatch := c.dow&(1<<int(t.Weekday())) != 0
+
+	// when both day fields are restricted, either of them matching is enough
+	if !c.anyDom && !c.anyDow {
+		return domMatch || dowMatch
+	}
+	return domMatch && dowMatch
+}
+
+func parseCronField(value string, field cronField) (uint64, error) {
+	var bits uint64
+
+	for _, item := range strings.Split(value, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(item, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q in %s field", stepPart, field.name)
+			}
+		}
+
+		from, to := field.min, field.max
+		if rangePart != "*" {
+			fromPart, toPart, isRange := strings.Cut(rangePart, "-")
+
+			var err error
+			from, err = strconv.Atoi(fromPart)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q in %s field", fromPart, field.name)
+			}
+
+			to = from
+			if isRange {
+				to, err = strconv.Atoi(toPart)
+				if err != nil {
+					return 0, fmt.Errorf("invalid value %q in %s field", toPart, field.name)
+				}
+			} else if hasStep {
+				to = field.max
+			}
+		}
+
+		if from < field.min || to > field.max || from > to {
+			return 0, fmt.Errorf("value %q is out of range %d-%d in %s field", item, field.min, field.max, field.name)
+		}
+
+		for i := from; i <= to; i += step {
+			bits |= 1 << i
+		}
+	}
+
+	return bits, nil
+}
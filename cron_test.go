@@ -0,0 +1,98 @@
+package gomatrix_test
+
+import (
+	"testing"
+	"time"
+
+	gomatrix "github.com/beldeveloper/go-matrix"
+)
+
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		wantErr bool
+	}{
+		{name: "every minute", spec: "* * * * *"},
+		{name: "lists ranges and steps", spec: "0,30 9-17 */2 1-12/3 1-5"},
+		{name: "sunday as 7", spec: "0 0 * * 7"},
+		{name: "extra spaces", spec: " 0  12 * * * "},
+		{name: "too few fields", spec: "* * * *", wantErr: true},
+		{name: "too many fields", spec: "* * * * * *", wantErr: true},
+		{name: "minute out of range", spec: "60 * * * *", wantErr: true},
+		{name: "day of month zero", spec: "* * 0 * *", wantErr: true},
+		{name: "month out of range", spec: "* * * 13 *", wantErr: true},
+		{name: "day of week out of range", spec: "* * * * 8", wantErr: true},
+		{name: "reversed range", spec: "* 17-9 * * *", wantErr: true},
+		{name: "zero step", spec: "*/0 * * * *", wantErr: true},
+		{name: "negative step", spec: "*/-5 * * * *", wantErr: true},
+		{name: "not a number", spec: "a * * * *", wantErr: true},
+		{name: "empty list item", spec: "1,,2 * * * *", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := gomatrix.ParseCron(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCron(%q) = %v, want error %t", tt.spec, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// a Wednesday
+	from := time.Date(2025, time.January, 15, 10, 20, 30, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{name: "every minute", spec: "* * * * *", from: from, want: time.Date(2025, time.January, 15, 10, 21, 0, 0, time.UTC)},
+		{name: "on the minute is after", spec: "21 * * * *", from: from.Truncate(time.Minute).Add(time.Minute), want: time.Date(2025, time.January, 15, 11, 21, 0, 0, time.UTC)},
+		{name: "next hour", spec: "0 * * * *", from: from, want: time.Date(2025, time.January, 15, 11, 0, 0, 0, time.UTC)},
+		{name: "step", spec: "*/15 * * * *", from: from, want: time.Date(2025, time.January, 15, 10, 30, 0, 0, time.UTC)},
+		{name: "next day", spec: "0 9 * * *", from: from, want: time.Date(2025, time.January, 16, 9, 0, 0, 0, time.UTC)},
+		{name: "weekday", spec: "0 9 * * 1-5", from: time.Date(2025, time.January, 17, 12, 0, 0, 0, time.UTC), want: time.Date(2025, time.January, 20, 9, 0, 0, 0, time.UTC)},
+		{name: "sunday as 7", spec: "0 0 * * 7", from: from, want: time.Date(2025, time.January, 19, 0, 0, 0, 0, time.UTC)},
+		{name: "sunday as 0", spec: "0 0 * * 0", from: from, want: time.Date(2025, time.January, 19, 0, 0, 0, 0, time.UTC)},
+		{name: "next month", spec: "0 0 1 * *", from: from, want: time.Date(2025, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "next year", spec: "0 0 1 1 *", from: from, want: time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "day of month or day of week", spec: "0 0 20 * 5", from: from, want: time.Date(2025, time.January, 17, 0, 0, 0, 0, time.UTC)},
+		{name: "leap day", spec: "0 0 29 2 *", from: from, want: time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{name: "never", spec: "0 0 30 2 *", from: from, want: time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cron, err := gomatrix.ParseCron(tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := cron.In(time.UTC).Next(tt.from)
+			if !got.Equal(tt.want) {
+				t.Fatalf("Next(%s) = %s, want %s", tt.from, got, tt.want)
+			}
+			if !got.IsZero() && !cron.In(time.UTC).Match(got) {
+				t.Fatalf("Match(%s) = false for the time returned by Next", got)
+			}
+		})
+	}
+}
+
+func TestCronNextInLocation(t *testing.T) {
+	loc := time.FixedZone("UTC+3", 3*60*60)
+	cron, err := gomatrix.ParseCron("0 9 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := cron.In(loc).Next(time.Date(2025, time.January, 15, 5, 0, 0, 0, time.UTC))
+	want := time.Date(2025, time.January, 15, 6, 0, 0, 0, time.UTC)
+	if !got.Equal(want) {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
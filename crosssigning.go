@@ -0,0 +1,205 @@
+package gomatrix
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// https://spec.matrix.org/v1.13/client-server-api/#cross-signing
+const (
+	SecretCrossSigningMaster      = "m.cross_signing.master"
+	SecretCrossSigningSelfSigning = "m.cross_signing.self_signing"
+	SecretCrossSigningUserSigning = "m.cross_signing.user_signing"
+)
+
+type CrossSigningKeys struct {
+	Master      ed25519.PrivateKey
+	SelfSigning ed25519.PrivateKey
+	UserSigning ed25519.PrivateKey
+}
+
+type CrossSigningOptions struct {
+	// Password completes the user-interactive authentication of the key upload, the client credentials password by default.
+	Password string
+	// Interactive completes the other stages of the authentication, e.g. the SSO of the accounts with no password.
+	Interactive UIAStageFunc
+	// SecretStorageKey encrypts the private keys in the secret storage. It's required if the account has
+	// a default key already; a new default key is created otherwise.
+	SecretStorageKey *SecretStorageKey
+}
+
+type CrossSigningResult struct {
+	Keys CrossSigningKeys
+	// SecretStorageKey is the key the private keys are stored with; keep it safe to recover them.
+	SecretStorageKey SecretStorageKey
+}
+
+// BootstrapCrossSigning creates the master, self-signing and user-signing keys of the user, stores the private
+// keys in the secret storage, uploads the public keys and signs the current device with the self-signing key.
+// Any previous cross-signing keys of the user are replaced. Once the keys are generated, they are returned
+// along with the error, so a failed bootstrap can be completed without losing them.
+func (c *Client) BootstrapCrossSigning(ctx context.Context, opts CrossSigningOptions) (CrossSigningResult, error) {
+	if opts.Password == "" {
+		opts.Password = c.credentials.Password
+	}
+
+	userID, err := c.WhoAmI(ctx)
+	if err != nil {
+		return CrossSigningResult{}, err
+	}
+	deviceID, err := c.DeviceID(ctx)
+	if err != nil {
+		return CrossSigningResult{}, err
+	}
+
+	var keys CrossSigningKeys
+	for _, key := range []*ed25519.PrivateKey{&keys.Master, &keys.SelfSigning, &keys.UserSigning} {
+		_, *key, err = ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return CrossSigningResult{}, fmt.Errorf("failed to generate a cross-signing key: %w", err)
+		}
+	}
+
+	master, err := crossSigningKeyJSON(userID, "master", keys.Master, nil)
+	if err != nil {
+		return CrossSigningResult{}, err
+	}
+	selfSigning, err := crossSigningKeyJSON(userID, "self_signing", keys.SelfSigning, keys.Master)
+	if err != nil {
+		return CrossSigningResult{}, err
+	}
+	userSigning, err := crossSigningKeyJSON(userID, "user_signing", keys.UserSigning, keys.Master)
+	if err != nil {
+		return CrossSigningResult{}, err
+	}
+
+	// the private keys are stored first, the public keys uploaded without them would be of no use
+	result := CrossSigningResult{Keys: keys}
+	result.SecretStorageKey, err = c.secretStorageKey(ctx, opts.SecretStorageKey)
+	if err != nil {
+		return result, err
+	}
+	for name, key := range map[string]ed25519.PrivateKey{
+		SecretCrossSigningMaster:      keys.Master,
+		SecretCrossSigningSelfSigning: keys.SelfSigning,
+		SecretCrossSigningUserSigning: keys.UserSigning,
+	} {
+		seed := base64.RawStdEncoding.EncodeToString(key.Seed())
+		err = c.StoreSecret(ctx, name, []byte(seed), result.SecretStorageKey)
+		if err != nil {
+			return result, fmt.Errorf("failed to store the %s key: %w", name, err)
+		}
+	}
+
+	err = c.doJSONWithUIA(ctx, http.MethodPost, "/_matrix/client/v3/keys/device_signing/upload", map[string]any{
+		"master_key":       master,
+		"self_signing_key": selfSigning,
+		"user_signing_key": userSigning,
+	}, UIA{Password: opts.Password, Interactive: opts.Interactive}, nil)
+	if err != nil {
+		return result, fmt.Errorf("failed to upload the cross-signing keys: %w", err)
+	}
+
+	err = c.signOwnDevice(ctx, userID, deviceID, keys.SelfSigning)
+	if err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// secretStorageKey returns the key given after checking it, or a new default key if the account has none;
+// a new default key would make the secrets stored with the current one unreachable.
+func (c *Client) secretStorageKey(ctx context.Context, key *SecretStorageKey) (SecretStorageKey, error) {
+	if key != nil {
+		ok, err := c.CheckSecretStorageKey(ctx, *key)
+		if err != nil {
+			return SecretStorageKey{}, fmt.Errorf("failed to check the secret storage key: %w", err)
+		}
+		if !ok {
+			return SecretStorageKey{}, errors.New("the secret storage key doesn't match its description")
+		}
+		return *key, nil
+	}
+
+	defaultID, err := c.GetDefaultSecretStorageKeyID(ctx)
+	if err != nil {
+		return SecretStorageKey{}, fmt.Errorf("failed to get the default secret storage key: %w", err)
+	}
+	if defaultID != "" {
+		return SecretStorageKey{}, fmt.Errorf("the account has the default secret storage key %s, it must be given", defaultID)
+	}
+
+	newKey, err := GenerateSecretStorageKey()
+	if err != nil {
+		return SecretStorageKey{}, err
+	}
+	err = c.AddSecretStorageKey(ctx, newKey, nil, true)
+	if err != nil {
+		return SecretStorageKey{}, fmt.Errorf("failed to add the secret storage key: %w", err)
+	}
+	return newKey, nil
+}
+
+// signOwnDevice signs the published keys of the device with the self-signing key.
+func (c *Client) signOwnDevice(ctx context.Context, userID, deviceID string, selfSigning ed25519.PrivateKey) error {
+	resp, err := c.QueryKeys(ctx, map[string][]string{userID: {deviceID}}, 0)
+	if err != nil {
+		return err
+	}
+	device, ok := resp.DeviceKeys[userID][deviceID]
+	if !ok {
+		return errors.New("the keys of the device are not published")
+	}
+
+	data, err := json.Marshal(device)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the device keys: %w", err)
+	}
+	signed, err := SignJSON(data, userID, ed25519KeyID(selfSigning.Public().(ed25519.PublicKey)), selfSigning)
+	if err != nil {
+		return err
+	}
+
+	return c.UploadSignatures(ctx, map[string]map[string]json.RawMessage{userID: {deviceID: signed}})
+}
+
+// UploadSignatures publishes the signatures of the device or cross-signing keys, keyed by user ID and
+// device ID or key ID. Each object is the signed key with the new signatures.
+func (c *Client) UploadSignatures(ctx context.Context, signed map[string]map[string]json.RawMessage) error {
+	var respData apiSignaturesUploadResp
+	err := c.doJSON(ctx, http.MethodPost, "/_matrix/client/v3/keys/signatures/upload", signed, &respData)
+	if err != nil {
+		return fmt.Errorf("failed to upload the signatures: %w", err)
+	}
+	if len(respData.Failures) > 0 {
+		return fmt.Errorf("signatures rejected: %v", respData.Failures)
+	}
+	return nil
+}
+
+func crossSigningKeyJSON(userID, usage string, key, signer ed25519.PrivateKey) (json.RawMessage, error) {
+	pub := key.Public().(ed25519.PublicKey)
+	data, err := json.Marshal(CrossSigningKey{
+		UserID: userID,
+		Usage:  []string{usage},
+		Keys:   map[string]string{ed25519KeyID(pub): base64.RawStdEncoding.EncodeToString(pub)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal the %s key: %w", usage, err)
+	}
+	if signer == nil {
+		return data, nil
+	}
+	return SignJSON(data, userID, ed25519KeyID(signer.Public().(ed25519.PublicKey)), signer)
+}
+
+// ed25519KeyID returns the ID cross-signing keys are referred to by: the algorithm and the public key itself.
+func ed25519KeyID(pub ed25519.PublicKey) string {
+	return KeyAlgorithmEd25519 + ":" + base64.RawStdEncoding.EncodeToString(pub)
+}
@@ -0,0 +1,227 @@
+package gomatrix
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sync"
+)
+
+type TrustState int
+
+const (
+	TrustUnset TrustState = iota
+	TrustVerified
+	TrustBlacklisted
+)
+
+type InboundGroupSession struct {
+	RoomID    string `json:"room_id"`
+	SenderKey string `json:"sender_key"`
+	SessionID string `json:"session_id"`
+	// SigningKey is the ed25519 key the sender claimed when sharing the session.
+	SigningKey string `json:"signing_key,omitempty"`
+	// Pickle is the serialized session as produced by the Olm implementation.
+	Pickle []byte `json:"pickle"`
+}
+
+type OutboundGroupSession struct {
+	RoomID  string          `json:"room_id"`
+	Session OutboundSession `json:"session"`
+	Pickle  []byte          `json:"pickle"`
+}
+
+// CryptoStore persists the end-to-end encryption state of the device. The Olm account and the sessions
+// are stored as the opaque pickles of the Olm implementation in use.
+type CryptoStore interface {
+	PutAccount(pickle []byte) error
+	// GetAccount returns nil if the account has not been created yet.
+	GetAccount() ([]byte, error)
+
+	PutDevices(userID string, devices map[string]DeviceKeys) error
+	GetDevices(userID string) (map[string]DeviceKeys, error)
+
+	PutInboundGroupSession(session InboundGroupSession) error
+	GetInboundGroupSession(roomID, senderKey, sessionID string) (InboundGroupSession, bool, error)
+
+	PutOutboundGroupSession(session OutboundGroupSession) error
+	GetOutboundGroupSession(roomID string) (OutboundGroupSession, bool, error)
+	DeleteOutboundGroupSession(roomID string) error
+
+	// SetTrust binds the trust to the keys the device has in the store, it fails for the unknown devices.
+	SetTrust(userID, deviceID string, trust TrustState) error
+	// GetTrust returns TrustUnset once the device has other keys than the ones it was trusted with.
+	GetTrust(userID, deviceID string) (TrustState, error)
+}
+
+// DeviceTrust is the trust in a device along with the fingerprint of the keys it applies to.
+type DeviceTrust struct {
+	State      TrustState `json:"state"`
+	Ed25519    string     `json:"ed25519"`
+	Curve25519 string     `json:"curve25519"`
+}
+
+func newDeviceTrust(device DeviceKeys, trust TrustState) DeviceTrust {
+	return DeviceTrust{State: trust, Ed25519: device.Ed25519(), Curve25519: device.Curve25519()}
+}
+
+// For returns the trust state if the device still has the trusted keys, TrustUnset otherwise.
+func (t DeviceTrust) For(device DeviceKeys) TrustState {
+	if t.Ed25519 == "" || t.Ed25519 != device.Ed25519() || t.Curve25519 != device.Curve25519() {
+		return TrustUnset
+	}
+	return t.State
+}
+
+// VerifiedDevicesFrom adapts the trust state of the store to DecryptionConfig.VerifiedDevices.
+func VerifiedDevicesFrom(store CryptoStore) func(ctx context.Context, userID string) ([]string, error) {
+	return func(_ context.Context, userID string) ([]string, error) {
+		devices, err := store.GetDevices(userID)
+		if err != nil {
+			return nil, err
+		}
+
+		var verified []string
+		for deviceID := range devices {
+			trust, err := store.GetTrust(userID, deviceID)
+			if err != nil {
+				return nil, err
+			}
+			if trust == TrustVerified {
+				verified = append(verified, deviceID)
+			}
+		}
+		return verified, nil
+	}
+}
+
+type InMemoryCryptoStore struct {
+	mux  sync.RWMutex
+	data cryptoData
+}
+
+// cryptoData is the whole state of a crypto store, exported fields only so it can be serialized as is.
+type cryptoData struct {
+	Account  []byte                            `json:"account,omitempty"`
+	Devices  map[string]map[string]DeviceKeys  `json:"devices"`
+	Inbound  map[string]InboundGroupSession    `json:"inbound"`
+	Outbound map[string]OutboundGroupSession   `json:"outbound"`
+	Trust    map[string]map[string]DeviceTrust `json:"trust"`
+}
+
+func newCryptoData() cryptoData {
+	return cryptoData{
+		Devices:  make(map[string]map[string]DeviceKeys),
+		Inbound:  make(map[string]InboundGroupSession),
+		Outbound: make(map[string]OutboundGroupSession),
+		Trust:    make(map[string]map[string]DeviceTrust),
+	}
+}
+
+func NewInMemoryCryptoStore() *InMemoryCryptoStore {
+	return &InMemoryCryptoStore{data: newCryptoData()}
+}
+
+func (s *InMemoryCryptoStore) PutAccount(pickle []byte) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.data.Account = pickle
+	return nil
+}
+
+func (s *InMemoryCryptoStore) GetAccount() ([]byte, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	return s.data.Account, nil
+}
+
+func (s *InMemoryCryptoStore) PutDevices(userID string, devices map[string]DeviceKeys) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.data.Devices[userID] = devices
+	return nil
+}
+
+func (s *InMemoryCryptoStore) GetDevices(userID string) (map[string]DeviceKeys, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	return maps.Clone(s.data.Devices[userID]), nil
+}
+
+func (s *InMemoryCryptoStore) PutInboundGroupSession(session InboundGroupSession) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.data.Inbound[inboundKey(session.RoomID, session.SenderKey, session.SessionID)] = session
+	return nil
+}
+
+func (s *InMemoryCryptoStore) GetInboundGroupSession(roomID, senderKey, sessionID string) (InboundGroupSession, bool, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	session, ok := s.data.Inbound[inboundKey(roomID, senderKey, sessionID)]
+	return session, ok, nil
+}
+
+func (s *InMemoryCryptoStore) PutOutboundGroupSession(session OutboundGroupSession) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.data.Outbound[session.RoomID] = session
+	return nil
+}
+
+func (s *InMemoryCryptoStore) GetOutboundGroupSession(roomID string) (OutboundGroupSession, bool, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	session, ok := s.data.Outbound[roomID]
+	return session, ok, nil
+}
+
+func (s *InMemoryCryptoStore) DeleteOutboundGroupSession(roomID string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	delete(s.data.Outbound, roomID)
+	return nil
+}
+
+func (s *InMemoryCryptoStore) SetTrust(userID, deviceID string, trust TrustState) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	_, err := s.setTrust(userID, deviceID, trust)
+	return err
+}
+
+// setTrust records the trust with the fingerprint of the device; s.mux must be held.
+func (s *InMemoryCryptoStore) setTrust(userID, deviceID string, trust TrustState) (DeviceTrust, error) {
+	device, ok := s.data.Devices[userID][deviceID]
+	if !ok {
+		return DeviceTrust{}, fmt.Errorf("unknown device %s of %s", deviceID, userID)
+	}
+
+	if s.data.Trust[userID] == nil {
+		s.data.Trust[userID] = make(map[string]DeviceTrust)
+	}
+	s.data.Trust[userID][deviceID] = newDeviceTrust(device, trust)
+	return s.data.Trust[userID][deviceID], nil
+}
+
+func (s *InMemoryCryptoStore) GetTrust(userID, deviceID string) (TrustState, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	return s.data.Trust[userID][deviceID].For(s.data.Devices[userID][deviceID]), nil
+}
+
+func inboundKey(roomID, senderKey, sessionID string) string {
+	// the parts can't contain a space: IDs and unpadded base64 keys
+	return roomID + " " + senderKey + " " + sessionID
+}
@@ -0,0 +1,169 @@
+package gomatrix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrUnknownSession is returned by a Decrypter, possibly wrapped, when it lacks the Megolm session of the event.
+var ErrUnknownSession = errors.New("unknown megolm session")
+
+// Decrypter decrypts the m.room.encrypted events, e.g. backed by libolm or vodozemac bindings.
+type Decrypter interface {
+	Decrypt(ctx context.Context, ev Event) (Event, error)
+}
+
+type EncryptedContent struct {
+	Algorithm  string `json:"algorithm"`
+	Ciphertext any    `json:"ciphertext"`
+	SenderKey  string `json:"sender_key,omitempty"`
+	DeviceID   string `json:"device_id,omitempty"`
+	SessionID  string `json:"session_id,omitempty"`
+}
+
+type WithheldCode string
+
+// https://spec.matrix.org/v1.13/client-server-api/#mroom_keywithheld
+const (
+	WithheldBlacklisted  WithheldCode = "m.blacklisted"
+	WithheldUnverified   WithheldCode = "m.unverified"
+	WithheldUnauthorised WithheldCode = "m.unauthorised"
+	WithheldUnavailable  WithheldCode = "m.unavailable"
+	WithheldNoOlm        WithheldCode = "m.no_olm"
+)
+
+type RoomKeyWithheldContent struct {
+	Algorithm string       `json:"algorithm"`
+	RoomID    string       `json:"room_id,omitempty"`
+	SessionID string       `json:"session_id,omitempty"`
+	SenderKey string       `json:"sender_key"`
+	Code      WithheldCode `json:"code"`
+	Reason    string       `json:"reason,omitempty"`
+}
+
+// DecryptionError describes an event that failed to decrypt. Code is set if the sender reported
+// withholding the key, or to WithheldUnavailable if the session is unknown for another reason.
+type DecryptionError struct {
+	Event     Event
+	SessionID string
+	Code      WithheldCode
+	Reason    string
+	Err       error
+}
+
+func (e *DecryptionError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("failed to decrypt event %s: %s: %v", e.Event.ID, e.Code, e.Err)
+	}
+	return fmt.Sprintf("failed to decrypt event %s: %v", e.Event.ID, e.Err)
+}
+
+func (e *DecryptionError) Unwrap() error {
+	return e.Err
+}
+
+type DecryptionConfig struct {
+	// Decrypter makes Listen pass the decrypted events to the handler instead of the encrypted ones.
+	Decrypter Decrypter
+	// OnError is called with the events that failed to decrypt, which are not passed to the handler.
+	OnError func(ctx context.Context, err *DecryptionError)
+	// RequestKeysAfter is the delay after which the missing keys are requested from the other devices of the user,
+	// unless the sender withheld them on purpose. Zero disables the requests.
+	RequestKeysAfter time.Duration
+	// VerifiedDevices returns the IDs of the verified devices of the user the keys are requested from
+	// and accepted from; no device is trusted if it is not set, so the keys are neither requested nor accepted.
+	VerifiedDevices func(ctx context.Context, userID string) ([]string, error)
+}
+
+type sessionRef struct {
+	roomID    string
+	sessionID string
+}
+
+// withheldKeys remembers the keys the senders refused to share with the device.
+type withheldKeys struct {
+	mux  sync.RWMutex
+	keys map[sessionRef]RoomKeyWithheldContent
+}
+
+func newWithheldKeys() *withheldKeys {
+	return &withheldKeys{keys: make(map[sessionRef]RoomKeyWithheldContent)}
+}
+
+func (w *withheldKeys) observe(events []Event) {
+	for _, ev := range events {
+		var content RoomKeyWithheldContent
+		if ev.Type != "m.room_key.withheld" || ev.ParseContent(&content) != nil || content.SessionID == "" {
+			continue
+		}
+
+		w.mux.Lock()
+		w.keys[sessionRef{roomID: content.RoomID, sessionID: content.SessionID}] = content
+		w.mux.Unlock()
+	}
+}
+
+func (w *withheldKeys) get(roomID, sessionID string) (RoomKeyWithheldContent, bool) {
+	w.mux.RLock()
+	defer w.mux.RUnlock()
+
+	content, ok := w.keys[sessionRef{roomID: roomID, sessionID: sessionID}]
+	return content, ok
+}
+
+// WithheldKey returns the notice of the sender refusing to share the key of the Megolm session.
+func (c *Client) WithheldKey(roomID, sessionID string) (RoomKeyWithheldContent, bool) {
+	return c.withheld.get(roomID, sessionID)
+}
+
+// decrypt returns the decrypted event; ok is false if the event should not be dispatched.
+func (c *Client) decrypt(ctx context.Context, ev Event) (Event, bool) {
+	if ev.Type != "m.room.encrypted" || c.decryption.Decrypter == nil {
+		return ev, true
+	}
+
+	decrypted, err := c.decryption.Decrypter.Decrypt(ctx, ev)
+	if err == nil {
+		decrypted.RoomID = ev.RoomID
+		return decrypted, true
+	}
+
+	var content EncryptedContent
+	_ = ev.ParseContent(&content)
+
+	decErr := &DecryptionError{Event: ev, SessionID: content.SessionID, Err: err}
+	if withheld, ok := c.withheld.get(ev.RoomID, content.SessionID); ok {
+		decErr.Code, decErr.Reason = withheld.Code, withheld.Reason
+	} else if errors.Is(err, ErrUnknownSession) {
+		decErr.Code = WithheldUnavailable
+	}
+
+	if c.decryption.OnError != nil {
+		c.decryption.OnError(ctx, decErr)
+	}
+	// to-device events are Olm encrypted, so there is no Megolm session to request
+	if decErr.Code == WithheldUnavailable && ev.RoomID != "" && c.decryption.RequestKeysAfter > 0 {
+		c.scheduleKeyRequest(ctx, ev.RoomID, content)
+	}
+	return Event{}, false
+}
+
+func (c *Client) scheduleKeyRequest(ctx context.Context, roomID string, content EncryptedContent) {
+	time.AfterFunc(c.decryption.RequestKeysAfter, func() {
+		if ctx.Err() != nil {
+			return
+		}
+		// the key may have been withheld in the meantime
+		if withheld, ok := c.withheld.get(roomID, content.SessionID); ok && withheld.Code != WithheldUnavailable {
+			return
+		}
+
+		err := c.RequestRoomKey(ctx, roomID, content)
+		if err != nil && c.decryption.OnError != nil {
+			c.decryption.OnError(ctx, &DecryptionError{SessionID: content.SessionID, Err: err})
+		}
+	})
+}
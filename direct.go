@@ -0,0 +1,84 @@
+package gomatrix
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+const DirectAccountDataType = "m.direct"
+
+// DirectRooms maps the users to the IDs of the direct chats with them.
+// https://spec.matrix.org/v1.13/client-server-api/#mdirect
+type DirectRooms map[string][]string
+
+func (c *Client) GetDirectRooms(ctx context.Context) (DirectRooms, error) {
+	rooms := DirectRooms{}
+	err := c.GetAccountData(ctx, DirectAccountDataType, &rooms)
+	if err != nil && !IsNotFound(err) {
+		return nil, err
+	}
+	return rooms, nil
+}
+
+// AddDirectRoom marks the room as a direct chat with the user in the m.direct account data.
+func (c *Client) AddDirectRoom(ctx context.Context, userID, roomID string) error {
+	c.directMux.Lock()
+	defer c.directMux.Unlock()
+
+	return c.addDirectRoom(ctx, userID, roomID)
+}
+
+// GetOrCreateDM returns the direct chat with the user the client is still joined to,
+// or creates a new one inviting the user and records it in the m.direct account data.
+func (c *Client) GetOrCreateDM(ctx context.Context, userID string) (string, error) {
+	// serialized so concurrent calls for the same user don't create several rooms
+	c.directMux.Lock()
+	defer c.directMux.Unlock()
+
+	direct, err := c.GetDirectRooms(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if len(direct[userID]) > 0 {
+		joined, err := c.GetJoinedRooms(ctx)
+		if err != nil {
+			return "", err
+		}
+		// the latest rooms are appended last
+		for _, roomID := range slices.Backward(direct[userID]) {
+			if slices.Contains(joined, roomID) {
+				return roomID, nil
+			}
+		}
+	}
+
+	roomID, err := c.CreateRoom(ctx, CreateRoomRequest{
+		Preset:   PresetTrustedPrivateChat,
+		Invite:   []string{userID},
+		IsDirect: true,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create a direct chat: %w", err)
+	}
+
+	err = c.addDirectRoom(ctx, userID, roomID)
+	if err != nil {
+		return "", err
+	}
+	return roomID, nil
+}
+
+func (c *Client) addDirectRoom(ctx context.Context, userID, roomID string) error {
+	direct, err := c.GetDirectRooms(ctx)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(direct[userID], roomID) {
+		return nil
+	}
+
+	direct[userID] = append(direct[userID], roomID)
+	return c.SetAccountData(ctx, DirectAccountDataType, direct)
+}
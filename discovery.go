@@ -0,0 +1,143 @@
+package gomatrix
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+type ServerSource string
+
+const (
+	SourceWellKnown  ServerSource = "well-known"
+	SourceSRV        ServerSource = "srv"
+	SourceServerName ServerSource = "server-name"
+)
+
+// ResolvedServer is the client API endpoint of a server name and how it was found.
+type ResolvedServer struct {
+	ServerName string
+	BaseURL    string
+	Source     ServerSource
+}
+
+type DiscoveryConfig struct {
+	HttpClient *http.Client
+	// Resolver looks up the _matrix-client._tcp SRV records, net.DefaultResolver by default.
+	Resolver *net.Resolver
+}
+
+// ServerDiscovery resolves the client API endpoint of the server names: with .well-known/matrix/client first,
+// then with the DNS SRV records, falling back to the server name itself. Each candidate is checked to serve
+// the client API with /_matrix/client/versions before it's used.
+// https://spec.matrix.org/v1.13/client-server-api/#server-discovery
+type ServerDiscovery struct {
+	cfg DiscoveryConfig
+}
+
+func NewServerDiscovery(cfg DiscoveryConfig) *ServerDiscovery {
+	if cfg.HttpClient == nil {
+		cfg.HttpClient = &http.Client{Timeout: requestTimeout}
+	}
+	if cfg.Resolver == nil {
+		cfg.Resolver = net.DefaultResolver
+	}
+	return &ServerDiscovery{cfg: cfg}
+}
+
+// Resolve returns the client API endpoint of the server name. The client resolves its server once, when created,
+// so a server moving elsewhere takes creating the client again.
+func (d *ServerDiscovery) Resolve(ctx context.Context, serverName string) (ResolvedServer, error) {
+	baseURL, err := d.wellKnown(ctx, serverName)
+	if err != nil {
+		return ResolvedServer{}, err
+	}
+	if baseURL != "" {
+		// the server points at the endpoint explicitly, so it failing isn't a reason to look elsewhere
+		err = d.validate(ctx, baseURL)
+		if err != nil {
+			return ResolvedServer{}, fmt.Errorf("invalid homeserver %s in .well-known/matrix/client of %s: %w", baseURL, serverName, err)
+		}
+		return ResolvedServer{ServerName: serverName, BaseURL: baseURL, Source: SourceWellKnown}, nil
+	}
+
+	// the SRV records are optional, so the failed lookups fall back to the server name
+	for _, baseURL := range d.srv(ctx, serverName) {
+		if d.validate(ctx, baseURL) == nil {
+			return ResolvedServer{ServerName: serverName, BaseURL: baseURL, Source: SourceSRV}, nil
+		}
+	}
+
+	baseURL = "https://" + serverName
+	err = d.validate(ctx, baseURL)
+	if err != nil {
+		return ResolvedServer{}, fmt.Errorf("failed to find the homeserver of %s: %w", serverName, err)
+	}
+	return ResolvedServer{ServerName: serverName, BaseURL: baseURL, Source: SourceServerName}, nil
+}
+
+// validate checks that the base URL serves the client API.
+func (d *ServerDiscovery) validate(ctx context.Context, baseURL string) error {
+	return probeServer(ctx, d.cfg.HttpClient, http.Header{}, baseURL)
+}
+
+// wellKnown returns an empty base URL if the server doesn't publish the file.
+func (d *ServerDiscovery) wellKnown(ctx context.Context, serverName string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://"+serverName+"/.well-known/matrix/client", nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create a request: %w", err)
+	}
+
+	resp, err := d.cfg.HttpClient.Do(req)
+	if err != nil {
+		return "", nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", nil
+	}
+
+	var wellKnown apiWellKnownClient
+	err = json.NewDecoder(resp.Body).Decode(&wellKnown)
+	if err != nil {
+		return "", fmt.Errorf("invalid .well-known/matrix/client of %s: %w", serverName, err)
+	}
+
+	baseURL := strings.TrimRight(wellKnown.Homeserver.BaseURL, "/")
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("invalid homeserver base URL %q in .well-known/matrix/client of %s", baseURL, serverName)
+	}
+	return baseURL, nil
+}
+
+// srv returns the base URLs of the SRV records in the order they're to be tried.
+func (d *ServerDiscovery) srv(ctx context.Context, serverName string) []string {
+	// LookupSRV sorts the records by priority and shuffles them by weight
+	_, records, err := d.cfg.Resolver.LookupSRV(ctx, "matrix-client", "tcp", serverName)
+	if err != nil {
+		return nil
+	}
+
+	var baseURLs []string
+	for _, r := range records {
+		// a single "." target means the service is decidedly not available
+		host := strings.TrimSuffix(r.Target, ".")
+		if host == "" {
+			return nil
+		}
+		baseURLs = append(baseURLs, "https://"+net.JoinHostPort(host, strconv.Itoa(int(r.Port))))
+	}
+	return baseURLs
+}
+
+// ResolvedServer tells how the client API endpoint was found when the client is configured with a ServerDiscovery.
+func (c *Client) ResolvedServer() ResolvedServer {
+	return c.resolved
+}
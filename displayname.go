@@ -0,0 +1,114 @@
+package gomatrix
+
+import (
+	"context"
+	"sync"
+)
+
+// memberCache keeps the joined and invited members of the rooms until their membership changes.
+// The generation of a room is bumped on every change, so a fetch that raced with one isn't cached.
+type memberCache struct {
+	mux   sync.RWMutex
+	rooms map[string]map[string]MemberContent
+	gens  map[string]uint64
+}
+
+func newMemberCache() *memberCache {
+	return &memberCache{rooms: make(map[string]map[string]MemberContent), gens: make(map[string]uint64)}
+}
+
+// get returns the cached members of the room, or the generation to pass to set once they're fetched.
+func (m *memberCache) get(roomID string) (map[string]MemberContent, uint64, bool) {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+
+	members, ok := m.rooms[roomID]
+	return members, m.gens[roomID], ok
+}
+
+// set caches the members unless the membership of the room changed since get returned gen.
+func (m *memberCache) set(roomID string, gen uint64, members map[string]MemberContent) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if m.gens[roomID] == gen {
+		m.rooms[roomID] = members
+	}
+}
+
+func (m *memberCache) invalidate(roomID string) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	delete(m.rooms, roomID)
+	m.gens[roomID]++
+}
+
+// observe drops the rooms with membership changes among the events.
+func (m *memberCache) observe(roomID string, events []Event) {
+	for _, ev := range events {
+		if ev.Type == "m.room.member" {
+			m.invalidate(roomID)
+			return
+		}
+	}
+}
+
+// ResolveDisplayName returns the name to show for the user in the room following the spec rules:
+// the display name if it's unique among the joined and invited members, the display name followed by
+// the user ID if it's not, and the user ID if the user has no display name.
+// The room members are cached until Listen sees a membership change in the room.
+// https://spec.matrix.org/v1.13/client-server-api/#calculating-the-display-name-for-a-user
+func (c *Client) ResolveDisplayName(ctx context.Context, roomID, userID string) (string, error) {
+	members, err := c.roomMembers(ctx, roomID)
+	if err != nil {
+		return "", err
+	}
+
+	name := members[userID].DisplayName
+	if name == "" {
+		return userID, nil
+	}
+
+	for otherID, member := range members {
+		if otherID != userID && member.DisplayName == name {
+			return name + " (" + userID + ")", nil
+		}
+	}
+	return name, nil
+}
+
+// ResolveAvatar returns the avatar of the user in the room, empty if the user has none.
+func (c *Client) ResolveAvatar(ctx context.Context, roomID, userID string) (MXCURI, error) {
+	members, err := c.roomMembers(ctx, roomID)
+	if err != nil {
+		return MXCURI{}, err
+	}
+	return members[userID].AvatarURL, nil
+}
+
+func (c *Client) roomMembers(ctx context.Context, roomID string) (map[string]MemberContent, error) {
+	members, gen, ok := c.members.get(roomID)
+	if ok {
+		return members, nil
+	}
+
+	events, err := c.GetRoomMembers(ctx, roomID, "", MembershipFilter{NotMembership: MembershipLeave})
+	if err != nil {
+		return nil, err
+	}
+
+	members = make(map[string]MemberContent, len(events))
+	for _, ev := range events {
+		var member MemberContent
+		if ev.ParseContent(&member) != nil {
+			continue
+		}
+		if member.Membership == MembershipJoin || member.Membership == MembershipInvite {
+			members[ev.GetStateKey()] = member
+		}
+	}
+
+	c.members.set(roomID, gen, members)
+	return members, nil
+}
@@ -0,0 +1,159 @@
+package gomatrix
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// https://github.com/matrix-org/matrix-spec-proposals/pull/2545
+const (
+	// RoomEmotesEventType is the state event of a pack of the room, its state key being the pack ID.
+	RoomEmotesEventType = "im.ponies.room_emotes"
+	// UserEmotesAccountDataType is the personal pack of the user.
+	UserEmotesAccountDataType = "im.ponies.user_emotes"
+	// EmoteRoomsAccountDataType lists the room packs the user enabled everywhere.
+	EmoteRoomsAccountDataType = "im.ponies.emote_rooms"
+
+	EmoteUsageEmoticon = "emoticon"
+	EmoteUsageSticker  = "sticker"
+)
+
+type EmoteImage struct {
+	URL MXCURI `json:"url"`
+	// Body is the text the image stands for, the shortcode by default.
+	Body string     `json:"body,omitempty"`
+	Info *MediaInfo `json:"info,omitempty"`
+	// Usage overrides the usage of the pack for the image.
+	Usage []string `json:"usage,omitempty"`
+}
+
+type EmotePackInfo struct {
+	DisplayName string `json:"display_name,omitempty"`
+	AvatarURL   MXCURI `json:"avatar_url,omitempty"`
+	// Usage is emoticon, sticker or both; an empty usage means both.
+	Usage       []string `json:"usage,omitempty"`
+	Attribution string   `json:"attribution,omitempty"`
+}
+
+// EmotePack is a set of custom emoticons and stickers keyed by shortcode, without the colons.
+type EmotePack struct {
+	Images map[string]EmoteImage `json:"images"`
+	Pack   EmotePackInfo         `json:"pack"`
+}
+
+// Image returns the image of the shortcode, usable as the given usage, e.g. EmoteUsageEmoticon.
+func (p EmotePack) Image(shortcode, usage string) (EmoteImage, bool) {
+	image, ok := p.Images[strings.Trim(shortcode, ":")]
+	if !ok {
+		return EmoteImage{}, false
+	}
+
+	usages := image.Usage
+	if len(usages) == 0 {
+		usages = p.Pack.Usage
+	}
+	return image, len(usages) == 0 || slices.Contains(usages, usage)
+}
+
+// GetRoomEmotePacks returns the packs of the room keyed by pack ID.
+func (c *Client) GetRoomEmotePacks(ctx context.Context, roomID string) (map[string]EmotePack, error) {
+	state, err := c.GetRoomState(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	packs := make(map[string]EmotePack)
+	for _, ev := range state.OfType(RoomEmotesEventType) {
+		var pack EmotePack
+		// the removed packs have no images
+		if ev.ParseContent(&pack) == nil && len(pack.Images) > 0 {
+			packs[ev.GetStateKey()] = pack
+		}
+	}
+	return packs, nil
+}
+
+// SetRoomEmotePack adds or replaces the pack of the room; the default pack has an empty ID.
+func (c *Client) SetRoomEmotePack(ctx context.Context, roomID, packID string, pack EmotePack) (string, error) {
+	return c.SetStateEvent(ctx, roomID, RoomEmotesEventType, packID, pack)
+}
+
+// GetUserEmotePack returns the personal pack of the user, empty if it has never been set.
+func (c *Client) GetUserEmotePack(ctx context.Context) (EmotePack, error) {
+	return NewAccountData[EmotePack](c, UserEmotesAccountDataType).Get(ctx)
+}
+
+func (c *Client) SetUserEmotePack(ctx context.Context, pack EmotePack) error {
+	return NewAccountData[EmotePack](c, UserEmotesAccountDataType).Set(ctx, pack)
+}
+
+// EmoticonHTML returns the inline image clients render as the custom emoticon.
+func EmoticonHTML(shortcode string, uri MXCURI) string {
+	alt := html.EscapeString(":" + strings.Trim(shortcode, ":") + ":")
+	return `<img data-mx-emoticon height="32" src="` + html.EscapeString(uri.String()) + `" alt="` + alt + `" title="` + alt + `" />`
+}
+
+// SendCustomEmote sends the custom emoticon of the shortcode, looked up in the personal pack of the user first,
+// then in the packs of the room.
+func (c *Client) SendCustomEmote(ctx context.Context, roomID, shortcode string) (string, error) {
+	image, err := c.findEmote(ctx, roomID, shortcode, EmoteUsageEmoticon)
+	if err != nil {
+		return "", err
+	}
+
+	body := ":" + strings.Trim(shortcode, ":") + ":"
+	return c.sendMessage(ctx, apiSendMsgReq{
+		RoomID:        roomID,
+		Type:          string(Text),
+		Format:        "org.matrix.custom.html",
+		Body:          body,
+		FormattedBody: EmoticonHTML(shortcode, image.URL),
+	})
+}
+
+// SendSticker sends the sticker of the shortcode, looked up as with SendCustomEmote.
+func (c *Client) SendSticker(ctx context.Context, roomID, shortcode string) (string, error) {
+	image, err := c.findEmote(ctx, roomID, shortcode, EmoteUsageSticker)
+	if err != nil {
+		return "", err
+	}
+
+	body := image.Body
+	if body == "" {
+		body = strings.Trim(shortcode, ":")
+	}
+	info := image.Info
+	if info == nil {
+		info = &MediaInfo{}
+	}
+	eventID, err := c.sendEvent(ctx, roomID, "m.sticker", apiStickerReq{Body: body, URL: image.URL.String(), Info: info})
+	if err != nil {
+		return "", fmt.Errorf("failed to send a sticker: %w", err)
+	}
+	return eventID, nil
+}
+
+func (c *Client) findEmote(ctx context.Context, roomID, shortcode, usage string) (EmoteImage, error) {
+	userPack, err := c.GetUserEmotePack(ctx)
+	if err != nil {
+		return EmoteImage{}, err
+	}
+	if image, ok := userPack.Image(shortcode, usage); ok {
+		return image, nil
+	}
+
+	roomPacks, err := c.GetRoomEmotePacks(ctx, roomID)
+	if err != nil {
+		return EmoteImage{}, err
+	}
+	for _, packID := range slices.Sorted(maps.Keys(roomPacks)) {
+		if image, ok := roomPacks[packID].Image(shortcode, usage); ok {
+			return image, nil
+		}
+	}
+	return EmoteImage{}, fmt.Errorf("no %s %q in the packs", usage, shortcode)
+}
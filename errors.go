@@ -0,0 +1,102 @@
+package gomatrix
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ErrNoPassword is returned when the client needs to log in, e.g. its access token was revoked,
+// but is configured with no password.
+var ErrNoPassword = errors.New("no password to log in with")
+
+// ErrGuestExpired is returned when the access token of the guest account is rejected and can't be refreshed;
+// registering a new guest would make the client another user.
+var ErrGuestExpired = errors.New("the guest session expired")
+
+// ErrNoRefreshToken is returned when the expired access token of an OAuth login has no refresh token to renew it with.
+var ErrNoRefreshToken = errors.New("no refresh token to renew the access token, log in again")
+
+// HTTPError is a failed response of the server. The standard error response is parsed
+// into ErrCode and Message, the raw body is kept in Body.
+// https://spec.matrix.org/v1.13/client-server-api/#standard-error-response
+type HTTPError struct {
+	StatusCode int
+	Body       []byte
+	// Header has the headers of the response the callers may act on, e.g. Retry-After.
+	Header http.Header
+	// ErrCode is the Matrix error code, e.g. M_FORBIDDEN, empty if the body isn't a standard error response.
+	ErrCode string
+	// Message is the human-readable error of the server.
+	Message string
+	// RetryAfter is how long the server asks to wait before retrying the rate-limited request, zero if it doesn't tell.
+	RetryAfter time.Duration
+}
+
+// retainedHeaders are the response headers kept in HTTPError.Header.
+var retainedHeaders = []string{"Retry-After", "WWW-Authenticate", "Content-Type", "Date"}
+
+// NewHTTPError builds the error of the failed response with its body already read.
+func NewHTTPError(resp *http.Response, body []byte) *HTTPError {
+	httpErr := &HTTPError{StatusCode: resp.StatusCode, Body: body, Header: make(http.Header)}
+	for _, key := range retainedHeaders {
+		if values := resp.Header.Values(key); len(values) > 0 {
+			httpErr.Header[key] = values
+		}
+	}
+
+	var respData apiErrorResp
+	if json.Unmarshal(body, &respData) == nil {
+		httpErr.ErrCode, httpErr.Message = respData.ErrCode, respData.Error
+		httpErr.RetryAfter = time.Duration(respData.RetryAfterMs) * time.Millisecond
+	}
+
+	// the header takes precedence over the deprecated retry_after_ms
+	retryAfter := resp.Header.Get("Retry-After")
+	if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds >= 0 {
+		httpErr.RetryAfter = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(retryAfter); err == nil {
+		httpErr.RetryAfter = max(time.Until(date), 0)
+	}
+	return httpErr
+}
+
+// readHTTPError reads the body of the failed response into an *HTTPError.
+func readHTTPError(resp *http.Response) *HTTPError {
+	body, _ := io.ReadAll(resp.Body)
+	return NewHTTPError(resp, body)
+}
+
+func (e *HTTPError) Error() string {
+	if e.ErrCode != "" {
+		return fmt.Sprintf("unexpected status code: %d; %s: %s", e.StatusCode, e.ErrCode, e.Message)
+	}
+	return fmt.Sprintf("unexpected status code: %d; body: %s", e.StatusCode, e.Body)
+}
+
+func IsNotFound(err error) bool {
+	var httpErr *HTTPError
+	return errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound
+}
+
+// ErrCode returns the Matrix error code of the failed request, e.g. M_FORBIDDEN, empty if there is none.
+func ErrCode(err error) string {
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		return ""
+	}
+	return httpErr.ErrCode
+}
+
+// RetryAfter returns how long the server asks to wait before retrying the failed request, zero if it doesn't tell.
+func RetryAfter(err error) time.Duration {
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		return 0
+	}
+	return httpErr.RetryAfter
+}
@@ -0,0 +1,138 @@
+package gomatrix
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// https://spec.matrix.org/v1.13/client-server-api/#forming-relationships-between-events
+const (
+	RelAnnotation = "m.annotation"
+	RelReplace    = "m.replace"
+	RelThread     = "m.thread"
+	RelReference  = "m.reference"
+)
+
+type Event struct {
+	ID             string          `json:"event_id"`
+	Type           string          `json:"type"`
+	RoomID         string          `json:"room_id,omitempty"`
+	Sender         string          `json:"sender"`
+	StateKey       *string         `json:"state_key,omitempty"`
+	OriginServerTS int64           `json:"origin_server_ts"`
+	Content        json.RawMessage `json:"content"`
+	Unsigned       json.RawMessage `json:"unsigned,omitempty"`
+}
+
+func (e Event) ParseContent(v any) error {
+	err := json.Unmarshal(e.Content, v)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal %s event content: %w", e.Type, err)
+	}
+	return nil
+}
+
+type Relation struct {
+	Type    string `json:"rel_type,omitempty"`
+	EventID string `json:"event_id,omitempty"`
+	Key     string `json:"key,omitempty"`
+
+	InReplyTo     *InReplyTo `json:"m.in_reply_to,omitempty"`
+	IsFallingBack bool       `json:"is_falling_back,omitempty"`
+}
+
+type InReplyTo struct {
+	EventID string `json:"event_id"`
+}
+
+func (e Event) Relation() (Relation, bool) {
+	var content struct {
+		RelatesTo *Relation `json:"m.relates_to"`
+	}
+	if json.Unmarshal(e.Content, &content) != nil || content.RelatesTo == nil {
+		return Relation{}, false
+	}
+	return *content.RelatesTo, true
+}
+
+type MessageContent struct {
+	MsgType       string     `json:"msgtype"`
+	Body          string     `json:"body"`
+	Format        string     `json:"format,omitempty"`
+	FormattedBody string     `json:"formatted_body,omitempty"`
+	Filename      string     `json:"filename,omitempty"`
+	URL           MXCURI     `json:"url,omitempty"`
+	Info          *MediaInfo `json:"info,omitempty"`
+}
+
+type Membership string
+
+// https://spec.matrix.org/v1.13/client-server-api/#mroommember
+const (
+	MembershipJoin   Membership = "join"
+	MembershipInvite Membership = "invite"
+	MembershipLeave  Membership = "leave"
+	MembershipBan    Membership = "ban"
+	MembershipKnock  Membership = "knock"
+)
+
+type MemberContent struct {
+	Membership  Membership `json:"membership"`
+	DisplayName string     `json:"displayname,omitempty"`
+	AvatarURL   MXCURI     `json:"avatar_url,omitempty"`
+	Reason      string     `json:"reason,omitempty"`
+	IsDirect    bool       `json:"is_direct,omitempty"`
+}
+
+func (e Event) GetStateKey() string {
+	if e.StateKey == nil {
+		return ""
+	}
+	return *e.StateKey
+}
+
+type CreateContent struct {
+	Creator     string `json:"creator,omitempty"`
+	RoomVersion string `json:"room_version,omitempty"`
+	Type        string `json:"type,omitempty"`
+	Federate    *bool  `json:"m.federate,omitempty"`
+}
+
+type EncryptionContent struct {
+	Algorithm          string `json:"algorithm"`
+	RotationPeriodMs   int64  `json:"rotation_period_ms,omitempty"`
+	RotationPeriodMsgs int    `json:"rotation_period_msgs,omitempty"`
+}
+
+// https://spec.matrix.org/v1.13/client-server-api/#mroompower_levels
+type PowerLevelsContent struct {
+	Users         map[string]int `json:"users,omitempty"`
+	UsersDefault  int            `json:"users_default"`
+	Events        map[string]int `json:"events,omitempty"`
+	EventsDefault int            `json:"events_default"`
+	StateDefault  int            `json:"state_default"`
+	Ban           int            `json:"ban"`
+	Kick          int            `json:"kick"`
+	Redact        int            `json:"redact"`
+	Invite        int            `json:"invite"`
+	Notifications map[string]int `json:"notifications,omitempty"`
+}
+
+func (p PowerLevelsContent) UserLevel(userID string) int {
+	if level, ok := p.Users[userID]; ok {
+		return level
+	}
+	return p.UsersDefault
+}
+
+// UnmarshalJSON applies the spec defaults to the levels missing in the event.
+func (p *PowerLevelsContent) UnmarshalJSON(data []byte) error {
+	type plain PowerLevelsContent
+	levels := plain{StateDefault: 50, Ban: 50, Kick: 50, Redact: 50}
+	err := json.Unmarshal(data, &levels)
+	if err != nil {
+		return err
+	}
+	*p = PowerLevelsContent(levels)
+	return nil
+}
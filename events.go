@@ -0,0 +1,91 @@
+package gomatrix
+
+import (
+	"context"
+	"errors"
+)
+
+const defaultEventsBuffer = 100
+
+// Backpressure tells what Events does when the receiver falls behind and the buffer is full.
+type Backpressure int
+
+const (
+	// BackpressureBlock holds the sync loop until the receiver catches up, so no event is dropped while the
+	// client runs; the buffered events are still lost on a restart, see Events.
+	BackpressureBlock Backpressure = iota
+	// BackpressureDropNewest drops the events that don't fit in the buffer.
+	BackpressureDropNewest
+	// BackpressureDropOldest drops the oldest buffered event to make room, keeping the most recent ones.
+	BackpressureDropOldest
+)
+
+type EventsOptions struct {
+	// Buffer is the capacity of the channel, 100 by default.
+	Buffer       int
+	Backpressure Backpressure
+	// OnDrop is called with the events dropped by the backpressure policy.
+	OnDrop func(ev Event)
+	// OnError is called with the error Listen stopped with, unless the context is done.
+	OnError func(err error)
+}
+
+// Events streams the events Listen would pass to its handler over a channel, which is closed once the context
+// is done or Listen fails. The delivery is at most once: an event counts as handled once it's in the channel,
+// so with a SyncStore the sync position is saved ahead of the events the receiver hasn't taken yet, and those
+// are not received again if the process stops. Use Listen, whose position follows the handler, to handle
+// every event at least once.
+func (c *Client) Events(ctx context.Context, opts EventsOptions) <-chan Event {
+	if opts.Buffer <= 0 {
+		opts.Buffer = defaultEventsBuffer
+	}
+	events := make(chan Event, opts.Buffer)
+
+	drop := func(ev Event) {
+		if opts.OnDrop != nil {
+			opts.OnDrop(ev)
+		}
+	}
+
+	go func() {
+		defer close(events)
+
+		err := c.Listen(ctx, func(ctx context.Context, ev Event) {
+			switch opts.Backpressure {
+			case BackpressureDropNewest:
+				select {
+				case events <- ev:
+				default:
+					drop(ev)
+				}
+			case BackpressureDropOldest:
+				for {
+					select {
+					case events <- ev:
+						return
+					default:
+					}
+					// the receiver may have taken the oldest event meanwhile
+					select {
+					case oldest := <-events:
+						drop(oldest)
+					default:
+					}
+				}
+			default:
+				select {
+				case events <- ev:
+				case <-ctx.Done():
+				}
+			}
+		})
+		if err != nil && ctx.Err() == nil && !errors.Is(err, ErrClosed) {
+			c.logger.Error("event stream stopped", "error", err)
+			if opts.OnError != nil {
+				opts.OnError(err)
+			}
+		}
+	}()
+
+	return events
+}
@@ -0,0 +1,79 @@
+// Package alerts is an example alert receiver that accepts Alertmanager webhooks
+// and posts the alerts to a Matrix room.
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+
+	gomatrix "github.com/beldeveloper/go-matrix"
+)
+
+// https://prometheus.io/docs/alerting/latest/configuration/#webhook_config
+type Webhook struct {
+	Status string  `json:"status"`
+	Alerts []Alert `json:"alerts"`
+}
+
+type Alert struct {
+	Status      string            `json:"status"`
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+type Receiver struct {
+	client gomatrix.MatrixClient
+	roomID string
+}
+
+func NewReceiver(client gomatrix.MatrixClient, roomID string) *Receiver {
+	return &Receiver{client: client, roomID: roomID}
+}
+
+func (r *Receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var webhook Webhook
+	err := json.NewDecoder(req.Body).Decode(&webhook)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = r.Notify(req.Context(), webhook)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (r *Receiver) Notify(ctx context.Context, webhook Webhook) error {
+	if len(webhook.Alerts) == 0 {
+		return nil
+	}
+
+	var text, formatted strings.Builder
+	for _, alert := range webhook.Alerts {
+		name := alert.Labels["alertname"]
+		summary := alert.Annotations["summary"]
+		status := strings.ToUpper(alert.Status)
+
+		fmt.Fprintf(&text, "[%s] %s: %s\n", status, name, summary)
+		fmt.Fprintf(&formatted, "<p><b>[%s] %s</b>: %s</p>", html.EscapeString(status), html.EscapeString(name), html.EscapeString(summary))
+	}
+
+	_, err := r.client.Send(ctx, r.roomID, gomatrix.Message{
+		Text: strings.TrimSpace(text.String()),
+		HTML: formatted.String(),
+	})
+	return err
+}
@@ -0,0 +1,74 @@
+package alerts_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	gomatrix "github.com/beldeveloper/go-matrix"
+	"github.com/beldeveloper/go-matrix/examples/alerts"
+	"github.com/beldeveloper/go-matrix/matrixtest"
+)
+
+const roomID = "!alerts:matrixtest.local"
+
+const webhook = `{
+	"status": "firing",
+	"alerts": [
+		{"status": "firing", "labels": {"alertname": "DiskFull"}, "annotations": {"summary": "disk <90%> full"}},
+		{"status": "resolved", "labels": {"alertname": "HighLoad"}, "annotations": {"summary": "load is back"}}
+	]
+}`
+
+func TestReceiver(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		fail   error
+		status int
+		sends  int
+	}{
+		{name: "alerts", method: http.MethodPost, body: webhook, status: http.StatusNoContent, sends: 1},
+		{name: "no alerts", method: http.MethodPost, body: `{"status":"firing","alerts":[]}`, status: http.StatusNoContent},
+		{name: "wrong method", method: http.MethodGet, status: http.StatusMethodNotAllowed},
+		{name: "malformed webhook", method: http.MethodPost, body: `{`, status: http.StatusBadRequest},
+		{name: "send failure", method: http.MethodPost, body: webhook, fail: errors.New("boom"), status: http.StatusBadGateway, sends: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := matrixtest.NewClient("@alerts:matrixtest.local")
+			client.FailWith("Send", tt.fail)
+
+			w := httptest.NewRecorder()
+			alerts.NewReceiver(client, roomID).ServeHTTP(w, httptest.NewRequest(tt.method, "/", strings.NewReader(tt.body)))
+			if w.Code != tt.status {
+				t.Fatalf("got status %d, want %d", w.Code, tt.status)
+			}
+			if sends := client.CallsTo("Send"); len(sends) != tt.sends {
+				t.Fatalf("got %d sends, want %d", len(sends), tt.sends)
+			}
+		})
+	}
+}
+
+func TestReceiverMessage(t *testing.T) {
+	client := matrixtest.NewClient("@alerts:matrixtest.local")
+	w := httptest.NewRecorder()
+	alerts.NewReceiver(client, roomID).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(webhook)))
+
+	sends := client.CallsTo("Send")
+	if len(sends) != 1 || sends[0].Args[0] != roomID {
+		t.Fatalf("unexpected sends: %v", sends)
+	}
+	msg := sends[0].Args[1].(gomatrix.Message)
+	if want := "[FIRING] DiskFull: disk <90%> full\n[RESOLVED] HighLoad: load is back"; msg.Text != want {
+		t.Fatalf("got text %q, want %q", msg.Text, want)
+	}
+	if !strings.Contains(msg.HTML, "disk &lt;90%&gt; full") {
+		t.Fatalf("the summary is not escaped: %q", msg.HTML)
+	}
+}
@@ -0,0 +1,83 @@
+// Package bridge is a skeleton of a bridge relaying messages between a Matrix room and a remote network.
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	gomatrix "github.com/beldeveloper/go-matrix"
+)
+
+type RemoteMessage struct {
+	Author string
+	Text   string
+}
+
+// Remote is the network side of the bridge.
+type Remote interface {
+	Send(ctx context.Context, msg RemoteMessage) error
+	Messages() <-chan RemoteMessage
+}
+
+type Bridge struct {
+	client gomatrix.MatrixClient
+	roomID string
+	remote Remote
+	userID string
+}
+
+func New(client gomatrix.MatrixClient, roomID string, remote Remote) *Bridge {
+	return &Bridge{client: client, roomID: roomID, remote: remote}
+}
+
+func (b *Bridge) Run(ctx context.Context) error {
+	userID, err := b.client.WhoAmI(ctx)
+	if err != nil {
+		return err
+	}
+	b.userID = userID
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- b.client.Listen(ctx, func(ctx context.Context, ev gomatrix.Event) {
+			_ = b.HandleEvent(ctx, ev)
+		})
+	}()
+
+	for {
+		select {
+		case err := <-errCh:
+			return err
+		case msg, ok := <-b.remote.Messages():
+			if !ok {
+				return errors.New("remote closed")
+			}
+			err := b.ToMatrix(ctx, msg)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (b *Bridge) HandleEvent(ctx context.Context, ev gomatrix.Event) error {
+	if ev.RoomID != b.roomID || ev.Type != "m.room.message" || ev.Sender == b.userID {
+		return nil
+	}
+
+	var msg gomatrix.MessageContent
+	if ev.ParseContent(&msg) != nil {
+		return nil
+	}
+
+	return b.remote.Send(ctx, RemoteMessage{Author: ev.Sender, Text: msg.Body})
+}
+
+func (b *Bridge) ToMatrix(ctx context.Context, msg RemoteMessage) error {
+	_, err := b.client.SendText(ctx, b.roomID, fmt.Sprintf("<%s> %s", msg.Author, msg.Text))
+	return err
+}
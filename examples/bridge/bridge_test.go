@@ -0,0 +1,70 @@
+package bridge_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	gomatrix "github.com/beldeveloper/go-matrix"
+	"github.com/beldeveloper/go-matrix/examples/bridge"
+	"github.com/beldeveloper/go-matrix/matrixtest"
+)
+
+const (
+	botID  = "@bridge:matrixtest.local"
+	roomID = "!bridged:matrixtest.local"
+)
+
+func message(roomID, sender, body string) gomatrix.Event {
+	content, _ := json.Marshal(gomatrix.MessageContent{MsgType: string(gomatrix.Text), Body: body})
+	return gomatrix.Event{ID: "$" + body, Type: "m.room.message", RoomID: roomID, Sender: sender, Content: content}
+}
+
+func TestBridge(t *testing.T) {
+	client := matrixtest.NewClient(botID)
+	remoteIn, toBridge := io.Pipe()
+	fromBridge, remoteOut := io.Pipe()
+	remote := bridge.NewStdioRemote("carol", remoteIn, remoteOut)
+	lines := bufio.NewScanner(fromBridge)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- bridge.New(client, roomID, remote).Run(ctx) }()
+
+	// the messages of the other rooms and of the bridge itself are not relayed
+	client.Deliver(
+		message("!other:matrixtest.local", "@alice:matrixtest.local", "elsewhere"),
+		message(roomID, botID, "echo"),
+		message(roomID, "@alice:matrixtest.local", "hi carol"),
+	)
+	if !lines.Scan() {
+		t.Fatal(lines.Err())
+	}
+	if !strings.Contains(lines.Text(), "@alice:matrixtest.local") || !strings.Contains(lines.Text(), "hi carol") {
+		t.Fatalf("unexpected remote line: %q", lines.Text())
+	}
+
+	_, err := io.WriteString(toBridge, "hi alice\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(client.CallsTo("SendText")) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the relayed message")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	sends := client.CallsTo("SendText")
+	if sends[0].Args[0] != roomID || sends[0].Args[1] != "<carol> hi alice" {
+		t.Fatalf("unexpected send: %v", sends[0])
+	}
+}
@@ -0,0 +1,38 @@
+package bridge
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+)
+
+// StdioRemote bridges the room to a line-based stream, e.g. a terminal.
+type StdioRemote struct {
+	author   string
+	w        io.Writer
+	messages chan RemoteMessage
+}
+
+func NewStdioRemote(author string, r io.Reader, w io.Writer) *StdioRemote {
+	remote := &StdioRemote{author: author, w: w, messages: make(chan RemoteMessage)}
+
+	go func() {
+		defer close(remote.messages)
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			remote.messages <- RemoteMessage{Author: author, Text: scanner.Text()}
+		}
+	}()
+
+	return remote
+}
+
+func (r *StdioRemote) Send(_ context.Context, msg RemoteMessage) error {
+	_, err := fmt.Fprintf(r.w, "<%s> %s\n", msg.Author, msg.Text)
+	return err
+}
+
+func (r *StdioRemote) Messages() <-chan RemoteMessage {
+	return r.messages
+}
@@ -0,0 +1,85 @@
+// Command examplebot runs one of the example bots against a homeserver configured via environment variables:
+// MATRIX_SERVER, MATRIX_USER and MATRIX_PASSWORD.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"regexp"
+
+	gomatrix "github.com/beldeveloper/go-matrix"
+	"github.com/beldeveloper/go-matrix/examples/alerts"
+	"github.com/beldeveloper/go-matrix/examples/bridge"
+	"github.com/beldeveloper/go-matrix/examples/echo"
+	"github.com/beldeveloper/go-matrix/examples/moderation"
+)
+
+func main() {
+	bot := flag.String("bot", "echo", "bot to run: echo, moderation, alerts or bridge")
+	roomID := flag.String("room", "", "room ID used by the alerts and bridge bots")
+	addr := flag.String("addr", ":9095", "listen address of the alerts receiver")
+	pattern := flag.String("pattern", "", "forbidden message pattern of the moderation bot, required by it")
+	flag.Parse()
+
+	var forbidden *regexp.Regexp
+	if *bot == "moderation" {
+		// an empty pattern matches every message, the bot would kick everyone
+		if *pattern == "" {
+			usageError("the moderation bot requires -pattern")
+		}
+		var err error
+		forbidden, err = regexp.Compile(*pattern)
+		if err != nil {
+			usageError(fmt.Sprintf("invalid -pattern: %v", err))
+		}
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	client, err := gomatrix.NewClient(gomatrix.Credentials{
+		Server:   os.Getenv("MATRIX_SERVER"),
+		User:     os.Getenv("MATRIX_USER"),
+		Password: os.Getenv("MATRIX_PASSWORD"),
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	switch *bot {
+	case "echo":
+		err = echo.New(client).Run(ctx)
+	case "moderation":
+		err = moderation.New(client, moderation.Config{
+			Patterns: []*regexp.Regexp{forbidden},
+			Action:   moderation.ActionKick,
+		}).Run(ctx)
+	case "alerts":
+		srv := &http.Server{Addr: *addr, Handler: alerts.NewReceiver(client, *roomID)}
+		go func() {
+			<-ctx.Done()
+			_ = srv.Close()
+		}()
+		err = srv.ListenAndServe()
+	case "bridge":
+		err = bridge.New(client, *roomID, bridge.NewStdioRemote(os.Getenv("USER"), os.Stdin, os.Stdout)).Run(ctx)
+	default:
+		log.Fatalf("unknown bot %q", *bot)
+	}
+
+	if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+}
+
+func usageError(msg string) {
+	fmt.Fprintln(flag.CommandLine.Output(), msg)
+	flag.Usage()
+	os.Exit(2)
+}
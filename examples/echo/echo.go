@@ -0,0 +1,54 @@
+// Package echo is an example bot that joins the rooms it is invited to and repeats every text message as a notice.
+package echo
+
+import (
+	"context"
+
+	gomatrix "github.com/beldeveloper/go-matrix"
+)
+
+type Bot struct {
+	client gomatrix.MatrixClient
+	userID string
+}
+
+func New(client gomatrix.MatrixClient) *Bot {
+	return &Bot{client: client}
+}
+
+func (b *Bot) Run(ctx context.Context) error {
+	userID, err := b.client.WhoAmI(ctx)
+	if err != nil {
+		return err
+	}
+	b.userID = userID
+
+	return b.client.Listen(ctx, func(ctx context.Context, ev gomatrix.Event) {
+		_ = b.HandleEvent(ctx, ev)
+	})
+}
+
+func (b *Bot) HandleEvent(ctx context.Context, ev gomatrix.Event) error {
+	if ev.Sender == b.userID {
+		return nil
+	}
+
+	switch ev.Type {
+	case "m.room.member":
+		var member gomatrix.MemberContent
+		if ev.ParseContent(&member) != nil || member.Membership != gomatrix.MembershipInvite || ev.GetStateKey() != b.userID {
+			return nil
+		}
+		_, err := b.client.JoinRoom(ctx, ev.RoomID)
+		return err
+	case "m.room.message":
+		var msg gomatrix.MessageContent
+		if ev.ParseContent(&msg) != nil || msg.MsgType != string(gomatrix.Text) {
+			return nil
+		}
+		_, err := b.client.SendNotice(ctx, ev.RoomID, msg.Body)
+		return err
+	}
+
+	return nil
+}
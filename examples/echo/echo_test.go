@@ -0,0 +1,66 @@
+package echo_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	gomatrix "github.com/beldeveloper/go-matrix"
+	"github.com/beldeveloper/go-matrix/examples/echo"
+	"github.com/beldeveloper/go-matrix/matrixtest"
+)
+
+const botID = "@bot:matrixtest.local"
+
+func TestEcho(t *testing.T) {
+	client := matrixtest.NewClient(botID)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- echo.New(client).Run(ctx) }()
+
+	botKey := botID
+	client.Deliver(
+		gomatrix.Event{
+			ID: "$1", Type: "m.room.member", RoomID: "!room:matrixtest.local", Sender: "@alice:matrixtest.local",
+			StateKey: &botKey, Content: json.RawMessage(`{"membership":"invite"}`),
+		},
+		gomatrix.Event{
+			ID: "$2", Type: "m.room.message", RoomID: "!room:matrixtest.local", Sender: "@alice:matrixtest.local",
+			Content: json.RawMessage(`{"msgtype":"m.text","body":"hello"}`),
+		},
+		gomatrix.Event{
+			ID: "$3", Type: "m.room.message", RoomID: "!room:matrixtest.local", Sender: "@alice:matrixtest.local",
+			Content: json.RawMessage(`{"msgtype":"m.notice","body":"not echoed"}`),
+		},
+		gomatrix.Event{
+			ID: "$4", Type: "m.room.message", RoomID: "!room:matrixtest.local", Sender: botID,
+			Content: json.RawMessage(`{"msgtype":"m.text","body":"own message"}`),
+		},
+	)
+	waitForCalls(t, client, "SendNotice", 1)
+	cancel()
+	<-done
+
+	joins := client.CallsTo("JoinRoom")
+	if len(joins) != 1 || joins[0].Args[0] != "!room:matrixtest.local" {
+		t.Fatalf("unexpected joins: %v", joins)
+	}
+	notices := client.CallsTo("SendNotice")
+	if len(notices) != 1 || notices[0].Args[1] != "hello" {
+		t.Fatalf("unexpected notices: %v", notices)
+	}
+}
+
+func waitForCalls(t *testing.T, client *matrixtest.Client, method string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(client.CallsTo(method)) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %d calls to %s", n, method)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,84 @@
+// Package moderation is an example bot that redacts messages matching forbidden patterns
+// and kicks or bans their senders.
+package moderation
+
+import (
+	"context"
+	"errors"
+	"regexp"
+
+	gomatrix "github.com/beldeveloper/go-matrix"
+)
+
+type Action string
+
+const (
+	ActionRedact Action = "redact"
+	ActionKick   Action = "kick"
+	ActionBan    Action = "ban"
+)
+
+type Config struct {
+	Patterns []*regexp.Regexp
+	Action   Action
+	Reason   string
+}
+
+type Bot struct {
+	client gomatrix.MatrixClient
+	cfg    Config
+	userID string
+}
+
+func New(client gomatrix.MatrixClient, cfg Config) *Bot {
+	if cfg.Action == "" {
+		cfg.Action = ActionRedact
+	}
+	if cfg.Reason == "" {
+		cfg.Reason = "Forbidden content"
+	}
+	return &Bot{client: client, cfg: cfg}
+}
+
+func (b *Bot) Run(ctx context.Context) error {
+	userID, err := b.client.WhoAmI(ctx)
+	if err != nil {
+		return err
+	}
+	b.userID = userID
+
+	return b.client.Listen(ctx, func(ctx context.Context, ev gomatrix.Event) {
+		_ = b.HandleEvent(ctx, ev)
+	})
+}
+
+func (b *Bot) HandleEvent(ctx context.Context, ev gomatrix.Event) error {
+	if ev.Type != "m.room.message" || ev.Sender == b.userID {
+		return nil
+	}
+
+	var msg gomatrix.MessageContent
+	if ev.ParseContent(&msg) != nil || !b.forbidden(msg.Body) {
+		return nil
+	}
+
+	_, err := b.client.Redact(ctx, ev.RoomID, ev.ID, b.cfg.Reason)
+
+	switch b.cfg.Action {
+	case ActionKick:
+		err = errors.Join(err, b.client.Kick(ctx, ev.RoomID, ev.Sender, b.cfg.Reason))
+	case ActionBan:
+		err = errors.Join(err, b.client.Ban(ctx, ev.RoomID, ev.Sender, b.cfg.Reason))
+	}
+
+	return err
+}
+
+func (b *Bot) forbidden(text string) bool {
+	for _, pattern := range b.cfg.Patterns {
+		if pattern.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package moderation_test
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"testing"
+
+	gomatrix "github.com/beldeveloper/go-matrix"
+	"github.com/beldeveloper/go-matrix/examples/moderation"
+	"github.com/beldeveloper/go-matrix/matrixtest"
+)
+
+const (
+	botID  = "@bot:matrixtest.local"
+	roomID = "!room:matrixtest.local"
+)
+
+func message(id, sender, body string) gomatrix.Event {
+	content, _ := json.Marshal(gomatrix.MessageContent{MsgType: string(gomatrix.Text), Body: body})
+	return gomatrix.Event{ID: id, Type: "m.room.message", RoomID: roomID, Sender: sender, Content: content}
+}
+
+func TestModeration(t *testing.T) {
+	tests := []struct {
+		name    string
+		action  moderation.Action
+		event   gomatrix.Event
+		redacts int
+		removed string
+	}{
+		{name: "allowed message", action: moderation.ActionKick, event: message("$1", "@alice:matrixtest.local", "hello")},
+		{name: "redact", event: message("$1", "@alice:matrixtest.local", "buy spam now"), redacts: 1},
+		{name: "kick", action: moderation.ActionKick, event: message("$1", "@alice:matrixtest.local", "spam"), redacts: 1, removed: "Kick"},
+		{name: "ban", action: moderation.ActionBan, event: message("$1", "@alice:matrixtest.local", "spam"), redacts: 1, removed: "Ban"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := matrixtest.NewClient(botID)
+			bot := moderation.New(client, moderation.Config{Patterns: []*regexp.Regexp{regexp.MustCompile(`spam`)}, Action: tt.action})
+			err := bot.HandleEvent(context.Background(), tt.event)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			redactions := client.CallsTo("Redact")
+			if len(redactions) != tt.redacts {
+				t.Fatalf("got %d redactions, want %d", len(redactions), tt.redacts)
+			}
+			if tt.redacts > 0 && (redactions[0].Args[1] != tt.event.ID || redactions[0].Args[2] != "Forbidden content") {
+				t.Fatalf("unexpected redaction: %v", redactions[0])
+			}
+			for _, method := range []string{"Kick", "Ban"} {
+				calls := client.CallsTo(method)
+				if method != tt.removed {
+					if len(calls) != 0 {
+						t.Fatalf("unexpected %s: %v", method, calls)
+					}
+					continue
+				}
+				if len(calls) != 1 || calls[0].Args[1] != tt.event.Sender {
+					t.Fatalf("unexpected %s calls: %v", method, calls)
+				}
+			}
+		})
+	}
+}
+
+func TestNoPatterns(t *testing.T) {
+	client := matrixtest.NewClient(botID)
+	bot := moderation.New(client, moderation.Config{Action: moderation.ActionBan})
+
+	err := bot.HandleEvent(context.Background(), message("$1", "@alice:matrixtest.local", "anything"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls := client.Calls(); len(calls) != 0 {
+		t.Fatalf("a bot without patterns moderated: %v", calls)
+	}
+}
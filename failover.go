@@ -0,0 +1,241 @@
+package gomatrix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const defaultRecheckInterval = 30 * time.Second
+
+type FailoverConfig struct {
+	// Servers are the other base URLs of the homeserver, e.g. the internal and the external ingress,
+	// used in order when Credentials.Server can't be reached.
+	Servers []string
+	// RecheckInterval is how often an unreachable server is probed to switch back to it, 30 seconds by default.
+	RecheckInterval time.Duration
+}
+
+// ServerStatus is the health of a base URL of the homeserver.
+type ServerStatus struct {
+	Server    string
+	Healthy   bool
+	CheckedAt time.Time
+	Err       error
+}
+
+// serverPool picks the first reachable base URL of the homeserver, in the order of preference.
+type serverPool struct {
+	httpClient      *http.Client
+	headers         http.Header
+	recheckInterval time.Duration
+
+	mux     sync.Mutex
+	servers []ServerStatus
+	current int
+	probing bool
+}
+
+func newServerPool(primary string, httpClient *http.Client, headers http.Header, cfg FailoverConfig) *serverPool {
+	if len(cfg.Servers) == 0 {
+		return nil
+	}
+	if cfg.RecheckInterval <= 0 {
+		cfg.RecheckInterval = defaultRecheckInterval
+	}
+
+	p := &serverPool{httpClient: httpClient, headers: headers, recheckInterval: cfg.RecheckInterval}
+	for _, server := range append([]string{primary}, cfg.Servers...) {
+		p.servers = append(p.servers, ServerStatus{Server: server, Healthy: true})
+	}
+	return p
+}
+
+// server returns the base URL to send the requests to, probing the unreachable servers when due.
+func (p *serverPool) server(fallback string) string {
+	if p == nil {
+		return fallback
+	}
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	if !p.probing && len(p.due()) > 0 {
+		p.probing = true
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+			defer cancel()
+
+			p.mux.Lock()
+			due := p.due()
+			p.mux.Unlock()
+
+			p.probe(ctx, due)
+
+			p.mux.Lock()
+			p.probing = false
+			p.mux.Unlock()
+		}()
+	}
+	return p.servers[p.current].Server
+}
+
+// due returns the unreachable servers to probe again; p.mux must be held.
+func (p *serverPool) due() []string {
+	var servers []string
+	for _, status := range p.servers {
+		if !status.Healthy && time.Since(status.CheckedAt) >= p.recheckInterval {
+			servers = append(servers, status.Server)
+		}
+	}
+	return servers
+}
+
+// fail marks the server unreachable and reports whether another server is left to try.
+func (p *serverPool) fail(server string, err error) bool {
+	if p == nil {
+		return false
+	}
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	p.set(ServerStatus{Server: server, CheckedAt: time.Now(), Err: err})
+	return p.pick()
+}
+
+// probe checks the servers with /versions and switches to the most preferred healthy server.
+func (p *serverPool) probe(ctx context.Context, servers []string) {
+	for _, server := range servers {
+		err := probeServer(ctx, p.httpClient, p.headers, server)
+
+		p.mux.Lock()
+		p.set(ServerStatus{Server: server, Healthy: err == nil, CheckedAt: time.Now(), Err: err})
+		p.mux.Unlock()
+	}
+
+	p.mux.Lock()
+	p.pick()
+	p.mux.Unlock()
+}
+
+// set updates the status of the server; p.mux must be held.
+func (p *serverPool) set(status ServerStatus) {
+	for i := range p.servers {
+		if p.servers[i].Server == status.Server {
+			p.servers[i] = status
+		}
+	}
+}
+
+// pick switches to the most preferred healthy server; p.mux must be held.
+func (p *serverPool) pick() bool {
+	for i, status := range p.servers {
+		if status.Healthy {
+			p.current = i
+			return true
+		}
+	}
+	return false
+}
+
+func (p *serverPool) statuses() []ServerStatus {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	return append([]ServerStatus(nil), p.servers...)
+}
+
+// CheckServers probes every base URL of the homeserver with /versions, updating the choice of the server
+// the requests are sent to.
+func (c *Client) CheckServers(ctx context.Context) []ServerStatus {
+	if c.servers == nil {
+		err := probeServer(ctx, c.httpClient, c.headers, c.credentials.Server)
+		return []ServerStatus{{Server: c.credentials.Server, Healthy: err == nil, CheckedAt: time.Now(), Err: err}}
+	}
+
+	var servers []string
+	for _, status := range c.servers.statuses() {
+		servers = append(servers, status.Server)
+	}
+	c.servers.probe(ctx, servers)
+	return c.servers.statuses()
+}
+
+// Server returns the base URL of the homeserver the requests are currently sent to.
+func (c *Client) Server() string {
+	return c.servers.server(c.credentials.Server)
+}
+
+func probeServer(ctx context.Context, httpClient *http.Client, headers http.Header, server string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server+"/_matrix/client/versions", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create a request: %w", err)
+	}
+	req.Header = headers.Clone()
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// sendWithFailover sends the request built for the current base URL of the homeserver, moving on to the next one
+// if the server can't be reached. The request is not retried if rewind is nil, nor if it might have reached the server
+// already without being safe to repeat, see canRetry.
+func (c *Client) sendWithFailover(newReq func(server string) (*http.Request, error), rewind func() error) (*http.Response, error) {
+	for {
+		server := c.Server()
+		req, err := newReq(server)
+		if err != nil {
+			return nil, err
+		}
+		for key, values := range c.headers {
+			if _, ok := req.Header[key]; !ok {
+				req.Header[key] = values
+			}
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err == nil {
+			return resp, decompressResponse(resp)
+		}
+		if req.Context().Err() != nil || !c.servers.fail(server, err) {
+			return nil, err
+		}
+		c.logger.Warn("the homeserver is unreachable, failing over", "server", server, "next", c.Server(), "error", err)
+		// the following requests go to the next server either way
+		if rewind == nil || !canRetry(req, err) {
+			return nil, err
+		}
+
+		err = rewind()
+		if err != nil {
+			return nil, fmt.Errorf("failed to rewind the request body: %w", err)
+		}
+	}
+}
+
+// canRetry reports whether the failed request can be sent to another server: if it never left the client because
+// the connection failed, or if repeating it is harmless, as for the PUTs whose transaction ID makes the server
+// ignore the retries.
+func canRetry(req *http.Request, err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,208 @@
+package gomatrix
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// maxCryptoJournal is the number of changes appended to the file before it's compacted.
+const maxCryptoJournal = 1000
+
+// FileCryptoStore is a CryptoStore keeping the state in memory and persisting it to a JSON file: a snapshot
+// of the state followed by a journal of the changes, each change appending a line. The file is rewritten
+// only to fold the journal into the snapshot once it grows long. The pickles are only as safe as the file,
+// so keep it private.
+type FileCryptoStore struct {
+	InMemoryCryptoStore
+	path string
+
+	fileMux sync.Mutex
+	journal int
+}
+
+// cryptoChange is a line of the journal, a single field of it being set.
+type cryptoChange struct {
+	Account        []byte                `json:"account,omitempty"`
+	Devices        *cryptoDevicesChange  `json:"devices,omitempty"`
+	Inbound        *InboundGroupSession  `json:"inbound,omitempty"`
+	Outbound       *OutboundGroupSession `json:"outbound,omitempty"`
+	DeleteOutbound string                `json:"delete_outbound,omitempty"`
+	Trust          *cryptoTrustChange    `json:"trust,omitempty"`
+}
+
+type cryptoDevicesChange struct {
+	UserID  string                `json:"user_id"`
+	Devices map[string]DeviceKeys `json:"devices"`
+}
+
+type cryptoTrustChange struct {
+	UserID   string      `json:"user_id"`
+	DeviceID string      `json:"device_id"`
+	Trust    DeviceTrust `json:"trust"`
+}
+
+func NewFileCryptoStore(path string) (*FileCryptoStore, error) {
+	s := &FileCryptoStore{InMemoryCryptoStore: InMemoryCryptoStore{data: newCryptoData()}, path: path}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the crypto store: %w", err)
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(bufio.NewReader(f))
+	err = dec.Decode(&s.data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the crypto store: %w", err)
+	}
+	for {
+		var change cryptoChange
+		err = dec.Decode(&change)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			// a change cut short by a crash was never acknowledged; the file is rewritten without it,
+			// the next changes would be appended to the partial line otherwise
+			return s, s.compact()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal the crypto store journal: %w", err)
+		}
+		s.apply(change)
+		s.journal++
+	}
+	return s, nil
+}
+
+// apply replays a change of the journal.
+func (s *FileCryptoStore) apply(change cryptoChange) {
+	switch {
+	case change.Account != nil:
+		s.data.Account = change.Account
+	case change.Devices != nil:
+		s.data.Devices[change.Devices.UserID] = change.Devices.Devices
+	case change.Inbound != nil:
+		session := *change.Inbound
+		s.data.Inbound[inboundKey(session.RoomID, session.SenderKey, session.SessionID)] = session
+	case change.Outbound != nil:
+		s.data.Outbound[change.Outbound.RoomID] = *change.Outbound
+	case change.DeleteOutbound != "":
+		delete(s.data.Outbound, change.DeleteOutbound)
+	case change.Trust != nil:
+		if s.data.Trust[change.Trust.UserID] == nil {
+			s.data.Trust[change.Trust.UserID] = make(map[string]DeviceTrust)
+		}
+		s.data.Trust[change.Trust.UserID][change.Trust.DeviceID] = change.Trust.Trust
+	}
+}
+
+func (s *FileCryptoStore) PutAccount(pickle []byte) error {
+	return s.change(func() (cryptoChange, error) {
+		_ = s.InMemoryCryptoStore.PutAccount(pickle)
+		return cryptoChange{Account: pickle}, nil
+	})
+}
+
+func (s *FileCryptoStore) PutDevices(userID string, devices map[string]DeviceKeys) error {
+	return s.change(func() (cryptoChange, error) {
+		_ = s.InMemoryCryptoStore.PutDevices(userID, devices)
+		return cryptoChange{Devices: &cryptoDevicesChange{UserID: userID, Devices: devices}}, nil
+	})
+}
+
+func (s *FileCryptoStore) PutInboundGroupSession(session InboundGroupSession) error {
+	return s.change(func() (cryptoChange, error) {
+		_ = s.InMemoryCryptoStore.PutInboundGroupSession(session)
+		return cryptoChange{Inbound: &session}, nil
+	})
+}
+
+func (s *FileCryptoStore) PutOutboundGroupSession(session OutboundGroupSession) error {
+	return s.change(func() (cryptoChange, error) {
+		_ = s.InMemoryCryptoStore.PutOutboundGroupSession(session)
+		return cryptoChange{Outbound: &session}, nil
+	})
+}
+
+func (s *FileCryptoStore) DeleteOutboundGroupSession(roomID string) error {
+	return s.change(func() (cryptoChange, error) {
+		_ = s.InMemoryCryptoStore.DeleteOutboundGroupSession(roomID)
+		return cryptoChange{DeleteOutbound: roomID}, nil
+	})
+}
+
+func (s *FileCryptoStore) SetTrust(userID, deviceID string, trust TrustState) error {
+	return s.change(func() (cryptoChange, error) {
+		s.mux.Lock()
+		defer s.mux.Unlock()
+
+		deviceTrust, err := s.setTrust(userID, deviceID, trust)
+		if err != nil {
+			return cryptoChange{}, err
+		}
+		return cryptoChange{Trust: &cryptoTrustChange{UserID: userID, DeviceID: deviceID, Trust: deviceTrust}}, nil
+	})
+}
+
+// change applies the change in memory and appends it to the journal, compacting the file once the journal
+// is long; the file lock keeps the journal in the order of the changes.
+func (s *FileCryptoStore) change(apply func() (cryptoChange, error)) error {
+	s.fileMux.Lock()
+	defer s.fileMux.Unlock()
+
+	change, err := apply()
+	if err != nil {
+		return err
+	}
+
+	if s.journal >= maxCryptoJournal {
+		return s.compact()
+	}
+	if _, err := os.Stat(s.path); errors.Is(err, os.ErrNotExist) {
+		return s.compact()
+	}
+
+	line, err := json.Marshal(change)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the crypto store change: %w", err)
+	}
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to write the crypto store: %w", err)
+	}
+	_, err = f.Write(append(line, '\n'))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write the crypto store: %w", err)
+	}
+	s.journal++
+	return nil
+}
+
+// compact rewrites the file as a snapshot of the state with an empty journal; s.fileMux must be held.
+func (s *FileCryptoStore) compact() error {
+	s.mux.RLock()
+	data, err := json.Marshal(s.data)
+	s.mux.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to marshal the crypto store: %w", err)
+	}
+
+	err = replaceFile(s.path, append(data, '\n'))
+	if err != nil {
+		return fmt.Errorf("failed to write the crypto store: %w", err)
+	}
+	s.journal = 0
+	return nil
+}
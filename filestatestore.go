@@ -0,0 +1,243 @@
+package gomatrix
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// minStateJournal is the number of events a room file can hold besides its state before it's compacted.
+const minStateJournal = 100
+
+// FileStateStore is a StateStore persisting the state of every room as a file in a directory, so a restarted
+// client resuming from a stored sync position still knows the room state. The state is kept in memory as well;
+// a room file holds a JSON line per event, the state events of a sync being appended to it, and is rewritten
+// only to drop the replaced events once it grows to twice the room state.
+type FileStateStore struct {
+	*InMemoryStateStore
+	dir string
+
+	fileMux sync.Mutex
+	lines   map[string]int
+}
+
+func NewFileStateStore(dir string) (*FileStateStore, error) {
+	err := os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the state directory: %w", err)
+	}
+
+	s := &FileStateStore{InMemoryStateStore: NewInMemoryStateStore(), dir: dir, lines: make(map[string]int)}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the state directory: %w", err)
+	}
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		roomID, err := base64.RawURLEncoding.DecodeString(name)
+		if err != nil {
+			continue
+		}
+
+		err = s.load(string(roomID))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// load replays the events of the room file.
+func (s *FileStateStore) load(roomID string) error {
+	f, err := os.Open(s.path(roomID))
+	if err != nil {
+		return fmt.Errorf("failed to read the room state: %w", err)
+	}
+	defer f.Close()
+
+	var events []Event
+	truncated := false
+	dec := json.NewDecoder(bufio.NewReader(f))
+	for {
+		var line json.RawMessage
+		err = dec.Decode(&line)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			// an append cut short by a crash, the next ones would follow the partial line
+			truncated = true
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal the state of %s: %w", roomID, err)
+		}
+
+		// the files written before the journal hold a single array of the state
+		if line[0] == '[' {
+			var state []Event
+			err = json.Unmarshal(line, &state)
+			events = append(events, state...)
+		} else {
+			var ev Event
+			err = json.Unmarshal(line, &ev)
+			events = append(events, ev)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal the state of %s: %w", roomID, err)
+		}
+	}
+
+	_ = s.InMemoryStateStore.SetState(roomID, events)
+	s.lines[roomID] = len(events)
+	if truncated {
+		return s.compact(roomID)
+	}
+	return nil
+}
+
+func (s *FileStateStore) SetState(roomID string, events []Event) error {
+	if !containsState(events) {
+		return nil
+	}
+
+	s.fileMux.Lock()
+	defer s.fileMux.Unlock()
+
+	_ = s.InMemoryStateStore.SetState(roomID, events)
+
+	var data []byte
+	var lines int
+	for _, ev := range events {
+		if ev.StateKey == nil {
+			continue
+		}
+		ev.RoomID = roomID
+		line, err := json.Marshal(ev)
+		if err != nil {
+			return fmt.Errorf("failed to marshal the room state: %w", err)
+		}
+		data = append(append(data, line...), '\n')
+		lines++
+	}
+
+	s.mux.RLock()
+	size := len(s.rooms[roomID])
+	s.mux.RUnlock()
+
+	_, err := os.Stat(s.path(roomID))
+	if errors.Is(err, os.ErrNotExist) || s.lines[roomID]+lines > 2*size+minStateJournal {
+		return s.compact(roomID)
+	}
+
+	f, err := os.OpenFile(s.path(roomID), os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to write the room state: %w", err)
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write the room state: %w", err)
+	}
+	s.lines[roomID] += lines
+	return nil
+}
+
+// compact rewrites the room file with the current state only; s.fileMux must be held.
+func (s *FileStateStore) compact(roomID string) error {
+	var data []byte
+	s.mux.RLock()
+	for _, ev := range s.rooms[roomID] {
+		line, err := json.Marshal(ev)
+		if err != nil {
+			s.mux.RUnlock()
+			return fmt.Errorf("failed to marshal the room state: %w", err)
+		}
+		data = append(append(data, line...), '\n')
+	}
+	lines := len(s.rooms[roomID])
+	s.mux.RUnlock()
+
+	err := replaceFile(s.path(roomID), data)
+	if err != nil {
+		return fmt.Errorf("failed to write the room state: %w", err)
+	}
+	s.lines[roomID] = lines
+	return nil
+}
+
+func (s *FileStateStore) ForgetRoom(roomID string) error {
+	s.fileMux.Lock()
+	defer s.fileMux.Unlock()
+
+	_ = s.InMemoryStateStore.ForgetRoom(roomID)
+	delete(s.lines, roomID)
+
+	err := os.Remove(s.path(roomID))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove the room state: %w", err)
+	}
+	return nil
+}
+
+func (s *FileStateStore) path(roomID string) string {
+	// room IDs contain characters not allowed in file names on some systems
+	return filepath.Join(s.dir, base64.RawURLEncoding.EncodeToString([]byte(roomID))+".json")
+}
+
+func containsState(events []Event) bool {
+	for _, ev := range events {
+		if ev.StateKey != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// replaceFile writes the file through a temporary one, so a crash leaves either the old content or the new one
+// and never a truncated file. Both the file and the directory are synced, the rename being lost otherwise.
+func replaceFile(path string, data []byte) error {
+	f, err := os.OpenFile(path+".tmp", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(path+".tmp", path)
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	err = dir.Sync()
+	if closeErr := dir.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
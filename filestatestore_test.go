@@ -0,0 +1,200 @@
+package gomatrix_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gomatrix "github.com/beldeveloper/go-matrix"
+)
+
+const testRoomID = "!room:example.org"
+
+func nameEvent(name string) gomatrix.Event {
+	stateKey := ""
+	return gomatrix.Event{Type: "m.room.name", StateKey: &stateKey, Content: json.RawMessage(fmt.Sprintf(`{"name":%q}`, name))}
+}
+
+func roomStatePath(dir string) string {
+	return filepath.Join(dir, base64.RawURLEncoding.EncodeToString([]byte(testRoomID))+".json")
+}
+
+func TestFileStateStoreReplay(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(t *testing.T, dir string)
+		want  string
+	}{
+		{
+			name: "journal",
+			write: func(t *testing.T, dir string) {
+				store, err := gomatrix.NewFileStateStore(dir)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, name := range []string{"first", "second", "third"} {
+					err = store.SetState(testRoomID, []gomatrix.Event{nameEvent(name)})
+					if err != nil {
+						t.Fatal(err)
+					}
+				}
+			},
+			want: "third",
+		},
+		{
+			name: "state array",
+			write: func(t *testing.T, dir string) {
+				data, _ := json.Marshal([]gomatrix.Event{nameEvent("first"), nameEvent("second")})
+				writeFile(t, roomStatePath(dir), append(data, '\n'))
+			},
+			want: "second",
+		},
+		{
+			name: "state array then journal",
+			write: func(t *testing.T, dir string) {
+				data, _ := json.Marshal([]gomatrix.Event{nameEvent("first")})
+				line, _ := json.Marshal(nameEvent("second"))
+				writeFile(t, roomStatePath(dir), append(append(append(data, '\n'), line...), '\n'))
+			},
+			want: "second",
+		},
+		{
+			name: "append cut short",
+			write: func(t *testing.T, dir string) {
+				line, _ := json.Marshal(nameEvent("first"))
+				partial, _ := json.Marshal(nameEvent("second"))
+				writeFile(t, roomStatePath(dir), append(append(line, '\n'), partial[:len(partial)/2]...))
+			},
+			want: "first",
+		},
+		{
+			name: "non-state events",
+			write: func(t *testing.T, dir string) {
+				store, err := gomatrix.NewFileStateStore(dir)
+				if err != nil {
+					t.Fatal(err)
+				}
+				err = store.SetState(testRoomID, []gomatrix.Event{nameEvent("first")})
+				if err != nil {
+					t.Fatal(err)
+				}
+				err = store.SetState(testRoomID, []gomatrix.Event{{Type: "m.room.message", Content: json.RawMessage(`{}`)}})
+				if err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: "first",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			tt.write(t, dir)
+
+			store, err := gomatrix.NewFileStateStore(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ev, ok, err := store.GetState(testRoomID, "m.room.name", "")
+			if err != nil || !ok {
+				t.Fatalf("GetState returned %t, %v", ok, err)
+			}
+			var content struct {
+				Name string `json:"name"`
+			}
+			_ = ev.ParseContent(&content)
+			if content.Name != tt.want {
+				t.Fatalf("got name %q, want %q", content.Name, tt.want)
+			}
+
+			// a partial line left by a crash is dropped on load, the next appends would follow it otherwise
+			data, err := os.ReadFile(roomStatePath(dir))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(data) > 0 && !bytes.HasSuffix(data, []byte("\n")) {
+				t.Fatal("the room file ends with a partial line")
+			}
+		})
+	}
+}
+
+func TestFileStateStoreCompact(t *testing.T) {
+	dir := t.TempDir()
+	store, err := gomatrix.NewFileStateStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const updates = 1000
+	for i := range updates {
+		err = store.SetState(testRoomID, []gomatrix.Event{nameEvent(fmt.Sprint(i))})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := os.ReadFile(roomStatePath(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := bytes.Count(data, []byte("\n")); lines >= updates/2 {
+		t.Fatalf("the room file holds %d lines for a single state event, it wasn't compacted", lines)
+	}
+	if _, err := os.Stat(roomStatePath(dir) + ".tmp"); err == nil {
+		t.Fatal("the temporary file of the compaction was left behind")
+	}
+
+	reopened, err := gomatrix.NewFileStateStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev, ok, err := reopened.GetState(testRoomID, "m.room.name", "")
+	if err != nil || !ok {
+		t.Fatalf("GetState returned %t, %v", ok, err)
+	}
+	if want := fmt.Sprintf(`{"name":"%d"}`, updates-1); string(ev.Content) != want {
+		t.Fatalf("got %s, want %s", ev.Content, want)
+	}
+}
+
+func TestFileStateStoreForgetRoom(t *testing.T) {
+	dir := t.TempDir()
+	store, err := gomatrix.NewFileStateStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.SetState(testRoomID, []gomatrix.Event{nameEvent("first")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.ForgetRoom(testRoomID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(roomStatePath(dir)); !os.IsNotExist(err) {
+		t.Fatalf("the room file wasn't removed: %v", err)
+	}
+
+	reopened, err := gomatrix.NewFileStateStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := reopened.GetState(testRoomID, "m.room.name", ""); ok {
+		t.Fatal("the forgotten room state was loaded")
+	}
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	err := os.WriteFile(path, data, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
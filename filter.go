@@ -0,0 +1,111 @@
+package gomatrix
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// https://spec.matrix.org/v1.13/client-server-api/#filtering
+type Filter struct {
+	EventFields []string     `json:"event_fields,omitempty"`
+	AccountData *EventFilter `json:"account_data,omitempty"`
+	Presence    *EventFilter `json:"presence,omitempty"`
+	Room        *RoomFilter  `json:"room,omitempty"`
+}
+
+type EventFilter struct {
+	Limit      int      `json:"limit,omitempty"`
+	Types      []string `json:"types,omitempty"`
+	NotTypes   []string `json:"not_types,omitempty"`
+	Senders    []string `json:"senders,omitempty"`
+	NotSenders []string `json:"not_senders,omitempty"`
+}
+
+type RoomFilter struct {
+	Rooms        []string         `json:"rooms,omitempty"`
+	NotRooms     []string         `json:"not_rooms,omitempty"`
+	IncludeLeave bool             `json:"include_leave,omitempty"`
+	State        *RoomEventFilter `json:"state,omitempty"`
+	Timeline     *RoomEventFilter `json:"timeline,omitempty"`
+	Ephemeral    *RoomEventFilter `json:"ephemeral,omitempty"`
+	AccountData  *RoomEventFilter `json:"account_data,omitempty"`
+}
+
+type RoomEventFilter struct {
+	EventFilter
+
+	Rooms                     []string `json:"rooms,omitempty"`
+	NotRooms                  []string `json:"not_rooms,omitempty"`
+	ContainsURL               *bool    `json:"contains_url,omitempty"`
+	LazyLoadMembers           bool     `json:"lazy_load_members,omitempty"`
+	IncludeRedundantMembers   bool     `json:"include_redundant_members,omitempty"`
+	UnreadThreadNotifications bool     `json:"unread_thread_notifications,omitempty"`
+}
+
+// LazyLoadMembersFilter returns a filter that makes the server send only the membership events
+// of the senders in the returned timeline instead of the full member list of every room.
+// The members that are not delivered can be fetched on demand with GetMember.
+func LazyLoadMembersFilter(includeRedundantMembers bool) *Filter {
+	return &Filter{
+		Room: &RoomFilter{
+			State: &RoomEventFilter{
+				LazyLoadMembers:         true,
+				IncludeRedundantMembers: includeRedundantMembers,
+			},
+			Timeline: &RoomEventFilter{
+				LazyLoadMembers:         true,
+				IncludeRedundantMembers: includeRedundantMembers,
+			},
+		},
+	}
+}
+
+// ToDeviceOnlyFilter returns a filter that drops the room events, the presence and the account data from the syncs,
+// leaving the to-device events and the device list changes, which can't be filtered. It suits the components
+// doing only the key management of the encryption, see ListenToDevice.
+func ToDeviceOnlyFilter() *Filter {
+	none := &EventFilter{NotTypes: []string{"*"}}
+	noRoomEvents := &RoomEventFilter{EventFilter: *none}
+	return &Filter{
+		AccountData: none,
+		Presence:    none,
+		Room: &RoomFilter{
+			State:       noRoomEvents,
+			Timeline:    noRoomEvents,
+			Ephemeral:   noRoomEvents,
+			AccountData: noRoomEvents,
+		},
+	}
+}
+
+// CreateFilter uploads the filter and returns its ID to be used in SyncOptions.Filter.
+func (c *Client) CreateFilter(ctx context.Context, filter Filter) (string, error) {
+	userID, err := c.WhoAmI(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var respData apiCreateFilterResp
+	err = c.doJSON(ctx, http.MethodPost, fmt.Sprintf("/_matrix/client/v3/user/%s/filter", url.PathEscape(userID)), filter, &respData)
+	if err != nil {
+		return "", fmt.Errorf("failed to create a filter: %w", err)
+	}
+
+	return respData.FilterID, nil
+}
+
+func (f *Filter) encode() (string, error) {
+	if f == nil {
+		return "", nil
+	}
+
+	data, err := json.Marshal(f)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal the filter: %w", err)
+	}
+
+	return string(data), nil
+}
@@ -2,4 +2,4 @@ module github.com/beldeveloper/go-matrix
 
 go 1.23.1
 
-require github.com/google/uuid v1.6.0 // indirect
+require github.com/google/uuid v1.6.0
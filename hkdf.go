@@ -0,0 +1,44 @@
+package gomatrix
+
+import (
+	"crypto/hmac"
+	"hash"
+	"io"
+)
+
+// hkdf returns the RFC 5869 output key material stream for the secret.
+func hkdf(h func() hash.Hash, secret, salt, info []byte) io.Reader {
+	extractor := hmac.New(h, salt)
+	extractor.Write(secret)
+	return &hkdfReader{expander: hmac.New(h, extractor.Sum(nil)), info: info}
+}
+
+type hkdfReader struct {
+	expander hash.Hash
+	info     []byte
+	counter  byte
+	prev     []byte
+	buf      []byte
+}
+
+func (r *hkdfReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(r.buf) == 0 {
+			if r.counter == 255 {
+				return n, io.EOF
+			}
+			r.counter++
+			r.expander.Reset()
+			r.expander.Write(r.prev)
+			r.expander.Write(r.info)
+			r.expander.Write([]byte{r.counter})
+			r.prev = r.expander.Sum(nil)
+			r.buf = r.prev
+		}
+		copied := copy(p[n:], r.buf)
+		r.buf = r.buf[copied:]
+		n += copied
+	}
+	return n, nil
+}
@@ -0,0 +1,41 @@
+package gomatrix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// IdentityServer is an identity server the client is registered with.
+type IdentityServer interface {
+	// Server returns the host name of the identity server, e.g. vector.im.
+	Server() string
+	// Token returns the identity server access token.
+	Token() string
+}
+
+// InviteByEmail invites the owner of the email address to the room. If the address is bound to a Matrix ID,
+// the user is invited directly, otherwise the identity server emails the invitation and the homeserver
+// sends an m.room.third_party_invite event, which is turned into a membership once the address gets bound.
+// https://spec.matrix.org/v1.13/client-server-api/#post_matrixclientv3roomsroomidinvite-1
+func (c *Client) InviteByEmail(ctx context.Context, roomID, email string) error {
+	if c.identityServer == nil {
+		return errors.New("identity server is not configured")
+	}
+	token := c.identityServer.Token()
+	if token == "" {
+		return errors.New("identity server access token is missing")
+	}
+
+	err := c.doJSON(ctx, http.MethodPost, c.roomPath(roomID, "invite"), apiInviteThirdPartyReq{
+		IDServer:      c.identityServer.Server(),
+		IDAccessToken: token,
+		Medium:        "email",
+		Address:       email,
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to invite by email: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package identity
+
+import "encoding/json"
+
+type apiRegisterResp struct {
+	Token string `json:"token"`
+}
+
+type apiTermsResp struct {
+	Policies map[string]json.RawMessage `json:"policies"`
+}
+
+type apiTermsDocument struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+type apiAcceptTermsReq struct {
+	UserAccepts []string `json:"user_accepts"`
+}
+
+type apiHashDetailsResp struct {
+	LookupPepper string   `json:"lookup_pepper"`
+	Algorithms   []string `json:"algorithms"`
+}
+
+type apiLookupReq struct {
+	Addresses []string `json:"addresses"`
+	Algorithm string   `json:"algorithm"`
+	Pepper    string   `json:"pepper"`
+}
+
+type apiLookupResp struct {
+	Mappings map[string]string `json:"mappings"`
+}
+
+type apiStoreInviteReq struct {
+	Medium          Medium `json:"medium"`
+	Address         string `json:"address"`
+	RoomID          string `json:"room_id"`
+	Sender          string `json:"sender"`
+	RoomAlias       string `json:"room_alias,omitempty"`
+	RoomName        string `json:"room_name,omitempty"`
+	RoomAvatarURL   string `json:"room_avatar_url,omitempty"`
+	RoomJoinRules   string `json:"room_join_rules,omitempty"`
+	SenderName      string `json:"sender_display_name,omitempty"`
+	SenderAvatarURL string `json:"sender_avatar_url,omitempty"`
+}
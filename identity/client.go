@@ -0,0 +1,318 @@
+// Package identity is a client of the identity service API v2 used to look up Matrix IDs
+// by email addresses and phone numbers and to invite people who don't have a Matrix ID yet.
+// https://spec.matrix.org/v1.13/identity-service-api/
+package identity
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	gomatrix "github.com/beldeveloper/go-matrix"
+)
+
+const requestTimeout = time.Minute
+
+type Medium string
+
+const (
+	Email  Medium = "email"
+	MSISDN Medium = "msisdn"
+)
+
+type Config struct {
+	// Server is the base URL of the identity server, e.g. https://vector.im.
+	Server     string
+	HttpClient *http.Client
+	// AllowPlaintextLookup lets Lookup send the addresses unhashed to the identity servers supporting
+	// only the "none" algorithm; Lookup fails against them otherwise.
+	AllowPlaintextLookup bool
+}
+
+var _ gomatrix.IdentityServer = (*Client)(nil)
+
+type Client struct {
+	server         string
+	httpClient     *http.Client
+	allowPlaintext bool
+
+	mux   sync.RWMutex
+	token string
+}
+
+func NewClient(cfg Config) *Client {
+	if cfg.HttpClient == nil {
+		cfg.HttpClient = &http.Client{Timeout: requestTimeout}
+	}
+	return &Client{
+		server:         strings.TrimRight(cfg.Server, "/"),
+		httpClient:     cfg.HttpClient,
+		allowPlaintext: cfg.AllowPlaintextLookup,
+	}
+}
+
+// Server returns the host name of the identity server as used in the id_server request fields.
+func (c *Client) Server() string {
+	host := strings.TrimPrefix(strings.TrimPrefix(c.server, "https://"), "http://")
+	return strings.TrimRight(host, "/")
+}
+
+func (c *Client) Token() string {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+	return c.token
+}
+
+// Register exchanges the homeserver OpenID token for an identity server access token.
+func (c *Client) Register(ctx context.Context, openID gomatrix.OpenIDToken) error {
+	var respData apiRegisterResp
+	err := c.do(ctx, http.MethodPost, "/_matrix/identity/v2/account/register", openID, &respData)
+	if err != nil {
+		return fmt.Errorf("failed to register at the identity server: %w", err)
+	}
+
+	c.mux.Lock()
+	c.token = respData.Token
+	c.mux.Unlock()
+
+	return nil
+}
+
+// RegisterWith requests an OpenID token from the homeserver and registers with it.
+func (c *Client) RegisterWith(ctx context.Context, client *gomatrix.Client) error {
+	openID, err := client.RequestOpenIDToken(ctx)
+	if err != nil {
+		return err
+	}
+	return c.Register(ctx, openID)
+}
+
+type Policy struct {
+	Name    string
+	Version string
+	URL     string
+}
+
+// GetTerms returns the policies of the identity server in the preferred language,
+// falling back to English and then to any available translation.
+func (c *Client) GetTerms(ctx context.Context, lang string) ([]Policy, error) {
+	var respData apiTermsResp
+	err := c.do(ctx, http.MethodGet, "/_matrix/identity/v2/terms", nil, &respData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the terms: %w", err)
+	}
+
+	policies := make([]Policy, 0, len(respData.Policies))
+	for id, raw := range respData.Policies {
+		var version struct {
+			Version string `json:"version"`
+		}
+		var translations map[string]json.RawMessage
+		if json.Unmarshal(raw, &version) != nil || json.Unmarshal(raw, &translations) != nil {
+			continue
+		}
+		delete(translations, "version")
+
+		var doc apiTermsDocument
+		for _, candidate := range append([]string{lang, "en"}, sortedKeys(translations)...) {
+			if data, ok := translations[candidate]; ok && json.Unmarshal(data, &doc) == nil {
+				break
+			}
+		}
+		if doc.URL == "" {
+			continue
+		}
+
+		name := doc.Name
+		if name == "" {
+			name = id
+		}
+		policies = append(policies, Policy{Name: name, Version: version.Version, URL: doc.URL})
+	}
+
+	return policies, nil
+}
+
+// AcceptTerms accepts the policies by their URLs.
+func (c *Client) AcceptTerms(ctx context.Context, urls []string) error {
+	err := c.do(ctx, http.MethodPost, "/_matrix/identity/v2/terms", apiAcceptTermsReq{UserAccepts: urls}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to accept the terms: %w", err)
+	}
+	return nil
+}
+
+type ThreePID struct {
+	Medium  Medium
+	Address string
+}
+
+// Lookup returns the Matrix IDs bound to the third-party identifiers; the identifiers without a binding are absent.
+// Only the hashes of the addresses are sent to the identity server unless Config.AllowPlaintextLookup is set.
+func (c *Client) Lookup(ctx context.Context, pids []ThreePID) (map[ThreePID]string, error) {
+	var details apiHashDetailsResp
+	err := c.do(ctx, http.MethodGet, "/_matrix/identity/v2/hash_details", nil, &details)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the hash details: %w", err)
+	}
+
+	algorithm := "sha256"
+	if !slices.Contains(details.Algorithms, algorithm) {
+		if !slices.Contains(details.Algorithms, "none") {
+			return nil, fmt.Errorf("unsupported lookup algorithms: %v", details.Algorithms)
+		}
+		if !c.allowPlaintext {
+			return nil, errors.New("the identity server supports only the plaintext lookups, which are not allowed")
+		}
+		algorithm = "none"
+	}
+
+	byHash := make(map[string]ThreePID, len(pids))
+	addresses := make([]string, 0, len(pids))
+	for _, pid := range pids {
+		hash := hashThreePID(algorithm, details.LookupPepper, pid)
+		byHash[hash] = pid
+		addresses = append(addresses, hash)
+	}
+
+	var respData apiLookupResp
+	err = c.do(ctx, http.MethodPost, "/_matrix/identity/v2/lookup", apiLookupReq{
+		Addresses: addresses,
+		Algorithm: algorithm,
+		Pepper:    details.LookupPepper,
+	}, &respData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up the addresses: %w", err)
+	}
+
+	result := make(map[ThreePID]string, len(respData.Mappings))
+	for hash, userID := range respData.Mappings {
+		if pid, ok := byHash[hash]; ok {
+			result[pid] = userID
+		}
+	}
+	return result, nil
+}
+
+type InviteRequest struct {
+	Medium  Medium
+	Address string
+	RoomID  string
+	Sender  string
+	// The optional fields are used by the identity server to render the invitation.
+	RoomAlias       string
+	RoomName        string
+	RoomAvatarURL   string
+	RoomJoinRules   string
+	SenderName      string
+	SenderAvatarURL string
+}
+
+type PublicKey struct {
+	PublicKey      string `json:"public_key"`
+	KeyValidityURL string `json:"key_validity_url"`
+}
+
+type Invite struct {
+	Token       string      `json:"token"`
+	DisplayName string      `json:"display_name"`
+	PublicKeys  []PublicKey `json:"public_keys"`
+}
+
+// StoreInvite makes the identity server send the invitation to the address
+// and remember it until the address is bound to a Matrix ID.
+func (c *Client) StoreInvite(ctx context.Context, req InviteRequest) (Invite, error) {
+	var invite Invite
+	err := c.do(ctx, http.MethodPost, "/_matrix/identity/v2/store-invite", apiStoreInviteReq{
+		Medium:          req.Medium,
+		Address:         req.Address,
+		RoomID:          req.RoomID,
+		Sender:          req.Sender,
+		RoomAlias:       req.RoomAlias,
+		RoomName:        req.RoomName,
+		RoomAvatarURL:   req.RoomAvatarURL,
+		RoomJoinRules:   req.RoomJoinRules,
+		SenderName:      req.SenderName,
+		SenderAvatarURL: req.SenderAvatarURL,
+	}, &invite)
+	if err != nil {
+		return Invite{}, fmt.Errorf("failed to store the invite: %w", err)
+	}
+	return invite, nil
+}
+
+func hashThreePID(algorithm, pepper string, pid ThreePID) string {
+	address := pid.Address
+	if pid.Medium == Email {
+		address = strings.ToLower(address)
+	}
+	value := address + " " + string(pid.Medium)
+	if algorithm == "none" {
+		return value
+	}
+
+	sum := sha256.Sum256([]byte(value + " " + pepper))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func (c *Client) do(ctx context.Context, method, path string, reqData, respData any) error {
+	var body io.Reader
+	if reqData != nil {
+		payload, err := json.Marshal(reqData)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request payload: %w", err)
+		}
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.server+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create a request: %w", err)
+	}
+	if reqData != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token := c.Token(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to do a request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return gomatrix.NewHTTPError(resp, respBody)
+	}
+
+	if respData == nil {
+		return nil
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(respData)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
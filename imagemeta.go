@@ -0,0 +1,246 @@
+package gomatrix
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+)
+
+var errMalformedImage = errors.New("malformed image")
+
+// StripImageMetadata removes the EXIF, XMP, IPTC and textual metadata (GPS position, camera serial numbers,
+// comments, etc.) from JPEG, PNG and WebP images without re-encoding them. The EXIF orientation is kept,
+// alone, so the photos taken with a rotated camera aren't displayed rotated.
+// The data of other formats is returned as is.
+func StripImageMetadata(data []byte) ([]byte, error) {
+	var stripped []byte
+	var err error
+
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8}):
+		stripped, err = stripJPEGMetadata(data)
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		stripped, err = stripPNGMetadata(data)
+	case len(data) >= 12 && bytes.Equal(data[0:4], []byte("RIFF")) && bytes.Equal(data[8:12], []byte("WEBP")):
+		stripped, err = stripWebPMetadata(data)
+	default:
+		return data, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to strip image metadata: %w", err)
+	}
+	return stripped, nil
+}
+
+func isStrippableImage(mimeType string) bool {
+	switch mimeType {
+	case "image/jpeg", "image/png", "image/webp":
+		return true
+	}
+	return false
+}
+
+func stripJPEGMetadata(data []byte) ([]byte, error) {
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:2])
+
+	pos := 2
+	for pos < len(data) {
+		if data[pos] != 0xFF || pos+1 >= len(data) {
+			return nil, errMalformedImage
+		}
+
+		marker := data[pos+1]
+		switch {
+		case marker == 0xFF:
+			// fill byte
+			pos++
+			continue
+		case marker == 0xD9 || marker >= 0xD0 && marker <= 0xD7 || marker == 0x01:
+			// markers without a payload
+			out.Write(data[pos : pos+2])
+			pos += 2
+			continue
+		}
+
+		if pos+4 > len(data) {
+			return nil, errMalformedImage
+		}
+		end := pos + 2 + int(binary.BigEndian.Uint16(data[pos+2:pos+4]))
+		if end > len(data) {
+			return nil, errMalformedImage
+		}
+
+		if marker == 0xDA {
+			// start of scan: the rest is the entropy-coded image data
+			out.Write(data[pos:])
+			return out.Bytes(), nil
+		}
+
+		// APP1 holds EXIF and XMP, APP13 holds IPTC, COM holds comments
+		switch {
+		case marker == 0xE1 && bytes.HasPrefix(data[pos+4:end], jpegEXIFHeader):
+			if exif := orientationEXIF(data[pos+4+len(jpegEXIFHeader) : end]); exif != nil {
+				payload := append(bytes.Clone(jpegEXIFHeader), exif...)
+				out.Write([]byte{0xFF, 0xE1})
+				_ = binary.Write(out, binary.BigEndian, uint16(2+len(payload)))
+				out.Write(payload)
+			}
+		case marker != 0xE1 && marker != 0xED && marker != 0xFE:
+			out.Write(data[pos:end])
+		}
+		pos = end
+	}
+
+	return out.Bytes(), nil
+}
+
+func stripPNGMetadata(data []byte) ([]byte, error) {
+	const signatureSize = 8
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:signatureSize])
+
+	pos := signatureSize
+	for pos < len(data) {
+		if pos+8 > len(data) {
+			return nil, errMalformedImage
+		}
+		// length, type, data and CRC
+		end := pos + 12 + int(binary.BigEndian.Uint32(data[pos:pos+4]))
+		if end > len(data) || end < pos {
+			return nil, errMalformedImage
+		}
+
+		switch string(data[pos+4 : pos+8]) {
+		case "eXIf":
+			if exif := orientationEXIF(data[pos+8 : end-4]); exif != nil {
+				_ = binary.Write(out, binary.BigEndian, uint32(len(exif)))
+				chunk := append([]byte("eXIf"), exif...)
+				out.Write(chunk)
+				_ = binary.Write(out, binary.BigEndian, crc32.ChecksumIEEE(chunk))
+			}
+		case "tEXt", "zTXt", "iTXt", "tIME":
+		default:
+			out.Write(data[pos:end])
+		}
+		pos = end
+	}
+
+	return out.Bytes(), nil
+}
+
+func stripWebPMetadata(data []byte) ([]byte, error) {
+	const headerSize = 12
+	const (
+		vp8xFlagXMP  = 0x04
+		vp8xFlagEXIF = 0x08
+	)
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:headerSize])
+
+	vp8x, keptEXIF := -1, false
+	pos := headerSize
+	for pos < len(data) {
+		if pos+8 > len(data) {
+			return nil, errMalformedImage
+		}
+		size := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		// chunks are padded to an even size
+		end := pos + 8 + size + size%2
+		if end > len(data) || end < pos {
+			return nil, errMalformedImage
+		}
+
+		switch string(data[pos : pos+4]) {
+		case "EXIF":
+			// some encoders keep the JPEG header in front of the TIFF data
+			if exif := orientationEXIF(bytes.TrimPrefix(data[pos+8:pos+8+size], jpegEXIFHeader)); exif != nil {
+				out.WriteString("EXIF")
+				_ = binary.Write(out, binary.LittleEndian, uint32(len(exif)))
+				out.Write(exif)
+				if len(exif)%2 == 1 {
+					out.WriteByte(0)
+				}
+				keptEXIF = true
+			}
+		case "XMP ":
+		case "VP8X":
+			if size < 1 {
+				return nil, errMalformedImage
+			}
+			vp8x = out.Len()
+			chunk := bytes.Clone(data[pos:end])
+			chunk[8] &^= vp8xFlagXMP | vp8xFlagEXIF
+			out.Write(chunk)
+		default:
+			out.Write(data[pos:end])
+		}
+		pos = end
+	}
+
+	stripped := out.Bytes()
+	binary.LittleEndian.PutUint32(stripped[4:8], uint32(len(stripped)-8))
+	if keptEXIF && vp8x >= 0 {
+		stripped[vp8x+8] |= vp8xFlagEXIF
+	}
+	return stripped, nil
+}
+
+// jpegEXIFHeader precedes the TIFF structured EXIF data in the APP1 segment of the JPEG images.
+var jpegEXIFHeader = []byte("Exif\x00\x00")
+
+const exifOrientationTag = 0x0112
+
+// orientationEXIF returns the TIFF structured EXIF data holding only the orientation of the given EXIF data,
+// nil if the image is upright or the data has no valid orientation.
+func orientationEXIF(tiff []byte) []byte {
+	orientation := exifOrientation(tiff)
+	if orientation < 2 || orientation > 8 {
+		return nil
+	}
+
+	// big-endian header, the first IFD right after it with a single SHORT entry, and no next IFD
+	exif := []byte{'M', 'M', 0, 42, 0, 0, 0, 8, 0, 1}
+	exif = binary.BigEndian.AppendUint16(exif, exifOrientationTag)
+	exif = binary.BigEndian.AppendUint16(exif, 3)
+	exif = binary.BigEndian.AppendUint32(exif, 1)
+	exif = binary.BigEndian.AppendUint16(exif, orientation)
+	return append(exif, 0, 0, 0, 0, 0, 0)
+}
+
+// exifOrientation looks the orientation up in the first IFD of the TIFF structured EXIF data, 0 if it's not there.
+func exifOrientation(tiff []byte) uint16 {
+	if len(tiff) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 0
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for i := range entries {
+		entry := ifd + 2 + 12*i
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:]) == exifOrientationTag && order.Uint16(tiff[entry+2:]) == 3 {
+			return order.Uint16(tiff[entry+8:])
+		}
+	}
+	return 0
+}
@@ -0,0 +1,127 @@
+package gomatrix
+
+import (
+	"context"
+	"slices"
+	"strings"
+)
+
+// Invite is the invitation of the user to a room, as told by the stripped state of the invited room.
+type Invite struct {
+	RoomID string
+	// Inviter is the user who sent the invitation.
+	Inviter  string
+	Reason   string
+	IsDirect bool
+
+	Name           string
+	Topic          string
+	CanonicalAlias string
+	AvatarURL      MXCURI
+	JoinRule       JoinRule
+	// RoomType is m.space for the spaces, empty for the rooms.
+	RoomType  string
+	Encrypted bool
+}
+
+// Invite parses the stripped state of the room the user is invited to.
+// https://spec.matrix.org/v1.13/client-server-api/#stripped-state
+func (r InvitedRoom) Invite(roomID, userID string) Invite {
+	invite := Invite{RoomID: roomID}
+	for _, ev := range r.InviteState.Events {
+		switch ev.Type {
+		case "m.room.member":
+			var member MemberContent
+			if ev.GetStateKey() == userID && ev.ParseContent(&member) == nil && member.Membership == MembershipInvite {
+				invite.Inviter, invite.Reason, invite.IsDirect = ev.Sender, member.Reason, member.IsDirect
+			}
+		case "m.room.name":
+			var content struct {
+				Name string `json:"name"`
+			}
+			_ = ev.ParseContent(&content)
+			invite.Name = content.Name
+		case "m.room.topic":
+			var content struct {
+				Topic string `json:"topic"`
+			}
+			_ = ev.ParseContent(&content)
+			invite.Topic = content.Topic
+		case "m.room.canonical_alias":
+			var content struct {
+				Alias string `json:"alias"`
+			}
+			_ = ev.ParseContent(&content)
+			invite.CanonicalAlias = content.Alias
+		case "m.room.avatar":
+			var content struct {
+				URL MXCURI `json:"url"`
+			}
+			_ = ev.ParseContent(&content)
+			invite.AvatarURL = content.URL
+		case "m.room.join_rules":
+			var content JoinRulesContent
+			_ = ev.ParseContent(&content)
+			invite.JoinRule = content.JoinRule
+		case "m.room.create":
+			var content CreateContent
+			_ = ev.ParseContent(&content)
+			invite.RoomType = content.Type
+		case "m.room.encryption":
+			invite.Encrypted = true
+		}
+	}
+	return invite
+}
+
+// AutoJoinPolicy tells which invitations to accept. With no inviters, servers or check set, every invitation is.
+type AutoJoinPolicy struct {
+	// Inviters are the users whose invitations are accepted.
+	Inviters []string
+	// Servers are the homeservers, e.g. example.org, whose users' invitations are accepted.
+	Servers []string
+	// Check decides on the invitations of the other users; they are declined if it's nil and
+	// either of the lists is set.
+	Check func(ctx context.Context, invite Invite) bool
+}
+
+// Allows reports whether the invitation should be accepted.
+func (p AutoJoinPolicy) Allows(ctx context.Context, invite Invite) bool {
+	if len(p.Inviters) == 0 && len(p.Servers) == 0 && p.Check == nil {
+		return true
+	}
+
+	_, server, _ := strings.Cut(invite.Inviter, ":")
+	if slices.Contains(p.Inviters, invite.Inviter) || (server != "" && slices.Contains(p.Servers, server)) {
+		return true
+	}
+	return p.Check != nil && p.Check(ctx, invite)
+}
+
+// autoJoin accepts the invitations of the sync allowed by the auto-join policy, if configured.
+func (c *Client) autoJoin(ctx context.Context, rooms map[string]InvitedRoom) {
+	if c.autoJoinPolicy == nil || len(rooms) == 0 {
+		return
+	}
+
+	userID, err := c.WhoAmI(ctx)
+	if err != nil {
+		c.logger.Warn("failed to accept the invitations", "error", err)
+		return
+	}
+
+	for roomID, room := range rooms {
+		invite := room.Invite(roomID, userID)
+		if !c.autoJoinPolicy.Allows(ctx, invite) {
+			continue
+		}
+
+		_, err := c.JoinRoom(ctx, roomID)
+		if err == nil && invite.IsDirect {
+			err = c.AddDirectRoom(ctx, invite.Inviter, roomID)
+		}
+		if err != nil {
+			c.logger.Warn("failed to accept the invitation", "room_id", roomID, "inviter", invite.Inviter, "error", err)
+		}
+	}
+}
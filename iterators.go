@@ -0,0 +1,91 @@
+package gomatrix
+
+import (
+	"context"
+	"iter"
+)
+
+// paginate iterates over the items of the pages fetched with next, starting with the from token and following
+// the tokens next returns until the last page. The iteration stops after yielding the first error.
+func paginate[T any](from string, next func(from string) (items []T, nextToken string, err error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for {
+			items, nextToken, err := next(from)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if nextToken == "" || len(items) == 0 {
+				return
+			}
+			from = nextToken
+		}
+	}
+}
+
+// Messages iterates over the room timeline in the direction of the options, fetching the pages as needed.
+// The iteration ends at the To token of the options or at the end of the timeline.
+func (c *Client) Messages(ctx context.Context, roomID string, opts PaginationOptions) iter.Seq2[Event, error] {
+	return paginate(opts.From, func(from string) ([]Event, string, error) {
+		opts.From = from
+		page, err := c.GetMessages(ctx, roomID, opts)
+		return page.Events, page.End, err
+	})
+}
+
+// MessagesBackward iterates over the room timeline from the most recent event back to the creation of the room.
+//
+//	for ev, err := range client.MessagesBackward(ctx, roomID) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+func (c *Client) MessagesBackward(ctx context.Context, roomID string) iter.Seq2[Event, error] {
+	return c.Messages(ctx, roomID, PaginationOptions{Dir: Backward})
+}
+
+// MessagesForward iterates over the room timeline from the token, e.g. the prev_batch of a sync, to the most recent event.
+func (c *Client) MessagesForward(ctx context.Context, roomID, from string) iter.Seq2[Event, error] {
+	return c.Messages(ctx, roomID, PaginationOptions{From: from, Dir: Forward})
+}
+
+// Relations iterates over the events relating to the parent event, see GetEventRelations.
+func (c *Client) Relations(ctx context.Context, roomID, eventID, relType, eventType string, opts RelationsOptions) iter.Seq2[Event, error] {
+	return paginate(opts.From, func(from string) ([]Event, string, error) {
+		opts.From = from
+		page, err := c.GetEventRelations(ctx, roomID, eventID, relType, eventType, opts)
+		return page.Events, page.NextBatch, err
+	})
+}
+
+// Threads iterates over the thread roots of the room, most recent first, see GetThreads.
+func (c *Client) Threads(ctx context.Context, roomID, include string) iter.Seq2[Event, error] {
+	return paginate("", func(from string) ([]Event, string, error) {
+		page, err := c.GetThreads(ctx, roomID, include, PaginationOptions{From: from})
+		return page.Events, page.NextBatch, err
+	})
+}
+
+// SpaceRooms iterates over the space hierarchy, see GetSpaceHierarchy.
+func (c *Client) SpaceRooms(ctx context.Context, spaceID string, opts SpaceHierarchyOptions) iter.Seq2[SpaceHierarchyRoom, error] {
+	return paginate("", func(from string) ([]SpaceHierarchyRoom, string, error) {
+		page, err := c.getSpaceHierarchyPage(ctx, spaceID, opts, from)
+		return page.Rooms, page.NextBatch, err
+	})
+}
+
+// PublicRooms iterates over the room directory, see GetPublicRooms.
+func (c *Client) PublicRooms(ctx context.Context, opts PublicRoomsOptions) iter.Seq2[PublicRoomSummary, error] {
+	return paginate(opts.Since, func(from string) ([]PublicRoomSummary, string, error) {
+		opts.Since = from
+		page, err := c.GetPublicRooms(ctx, opts)
+		return page.Rooms, page.NextBatch, err
+	})
+}
@@ -0,0 +1,105 @@
+package gomatrix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+type JoinRule string
+
+// https://spec.matrix.org/v1.13/client-server-api/#mroomjoin_rules
+const (
+	JoinPublic          JoinRule = "public"
+	JoinInvite          JoinRule = "invite"
+	JoinKnock           JoinRule = "knock"
+	JoinRestricted      JoinRule = "restricted"
+	JoinKnockRestricted JoinRule = "knock_restricted"
+	JoinPrivate         JoinRule = "private"
+)
+
+const AllowRoomMembership = "m.room_membership"
+
+// AllowCondition lets the members of another room, usually a space, join a restricted room.
+type AllowCondition struct {
+	Type   string `json:"type"`
+	RoomID string `json:"room_id,omitempty"`
+}
+
+type JoinRulesContent struct {
+	JoinRule JoinRule         `json:"join_rule"`
+	Allow    []AllowCondition `json:"allow,omitempty"`
+}
+
+// RestrictedToSpaces returns the join rules letting the members of any of the spaces join the room.
+// With knock set, everyone else can still knock.
+func RestrictedToSpaces(knock bool, spaceIDs ...string) JoinRulesContent {
+	content := JoinRulesContent{JoinRule: JoinRestricted}
+	if knock {
+		content.JoinRule = JoinKnockRestricted
+	}
+	for _, spaceID := range spaceIDs {
+		content.Allow = append(content.Allow, AllowCondition{Type: AllowRoomMembership, RoomID: spaceID})
+	}
+	return content
+}
+
+// Validate checks the join rules are consistent and supported by the room version.
+// Unstable room versions, whose support can't be inferred from the version string, are not checked.
+func (c JoinRulesContent) Validate(roomVersion string) error {
+	var minVersion int
+	switch c.JoinRule {
+	case JoinPublic, JoinInvite, JoinPrivate, JoinKnock:
+		if len(c.Allow) > 0 {
+			return fmt.Errorf("allow conditions require a restricted join rule, got %s", c.JoinRule)
+		}
+		if c.JoinRule == JoinKnock {
+			minVersion = 7
+		}
+	case JoinRestricted:
+		minVersion = 8
+	case JoinKnockRestricted:
+		minVersion = 10
+	default:
+		return fmt.Errorf("unknown join rule %q", c.JoinRule)
+	}
+
+	if c.JoinRule == JoinRestricted || c.JoinRule == JoinKnockRestricted {
+		if len(c.Allow) == 0 {
+			// nobody could join without an invite, which is what the invite rule is for
+			return errors.New("restricted join rules require at least one allow condition")
+		}
+		for _, cond := range c.Allow {
+			if cond.Type == AllowRoomMembership && cond.RoomID == "" {
+				return errors.New("room membership allow condition requires a room ID")
+			}
+		}
+	}
+
+	version, err := strconv.Atoi(roomVersion)
+	if err == nil && version < minVersion {
+		return fmt.Errorf("join rule %s requires room version %d or later, the room is version %s", c.JoinRule, minVersion, roomVersion)
+	}
+	return nil
+}
+
+// SetJoinRules validates the join rules against the room version and sends them to the room.
+func (c *Client) SetJoinRules(ctx context.Context, roomID string, content JoinRulesContent) (string, error) {
+	version, err := c.GetRoomVersion(ctx, roomID)
+	if err != nil {
+		return "", err
+	}
+
+	err = content.Validate(version)
+	if err != nil {
+		return "", err
+	}
+
+	return c.SetStateEvent(ctx, roomID, "m.room.join_rules", "", content)
+}
+
+// SetJoinRule sets the join rule of the room, with the allow conditions of the restricted rules, see SetJoinRules.
+func (c *Client) SetJoinRule(ctx context.Context, roomID string, rule JoinRule, allow []AllowCondition) (string, error) {
+	return c.SetJoinRules(ctx, roomID, JoinRulesContent{JoinRule: rule, Allow: allow})
+}
@@ -0,0 +1,49 @@
+package gomatrix_test
+
+import (
+	"testing"
+
+	gomatrix "github.com/beldeveloper/go-matrix"
+)
+
+func TestJoinRulesContentValidate(t *testing.T) {
+	space := []gomatrix.AllowCondition{{Type: gomatrix.AllowRoomMembership, RoomID: "!space:example.org"}}
+
+	tests := []struct {
+		name        string
+		content     gomatrix.JoinRulesContent
+		roomVersion string
+		wantErr     bool
+	}{
+		{name: "public", content: gomatrix.JoinRulesContent{JoinRule: gomatrix.JoinPublic}, roomVersion: "1"},
+		{name: "public with allow", content: gomatrix.JoinRulesContent{JoinRule: gomatrix.JoinPublic, Allow: space}, roomVersion: "10", wantErr: true},
+		{name: "invite with allow", content: gomatrix.JoinRulesContent{JoinRule: gomatrix.JoinInvite, Allow: space}, roomVersion: "10", wantErr: true},
+		{name: "knock", content: gomatrix.JoinRulesContent{JoinRule: gomatrix.JoinKnock}, roomVersion: "7"},
+		{name: "knock with allow", content: gomatrix.JoinRulesContent{JoinRule: gomatrix.JoinKnock, Allow: space}, roomVersion: "10", wantErr: true},
+		{name: "knock in old room", content: gomatrix.JoinRulesContent{JoinRule: gomatrix.JoinKnock}, roomVersion: "6", wantErr: true},
+		{name: "restricted", content: gomatrix.RestrictedToSpaces(false, "!space:example.org"), roomVersion: "8"},
+		{name: "restricted in old room", content: gomatrix.RestrictedToSpaces(false, "!space:example.org"), roomVersion: "7", wantErr: true},
+		{name: "restricted without allow", content: gomatrix.JoinRulesContent{JoinRule: gomatrix.JoinRestricted}, roomVersion: "8", wantErr: true},
+		{
+			name: "restricted without room ID",
+			content: gomatrix.JoinRulesContent{
+				JoinRule: gomatrix.JoinRestricted, Allow: []gomatrix.AllowCondition{{Type: gomatrix.AllowRoomMembership}},
+			},
+			roomVersion: "8",
+			wantErr:     true,
+		},
+		{name: "knock restricted", content: gomatrix.RestrictedToSpaces(true, "!space:example.org"), roomVersion: "10"},
+		{name: "knock restricted in old room", content: gomatrix.RestrictedToSpaces(true, "!space:example.org"), roomVersion: "9", wantErr: true},
+		{name: "unstable room version", content: gomatrix.RestrictedToSpaces(true, "!space:example.org"), roomVersion: "org.example.1"},
+		{name: "unknown join rule", content: gomatrix.JoinRulesContent{JoinRule: "everyone"}, roomVersion: "10", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.content.Validate(tt.roomVersion)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate(%q) = %v, want error %t", tt.roomVersion, err, tt.wantErr)
+			}
+		})
+	}
+}
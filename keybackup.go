@@ -0,0 +1,319 @@
+package gomatrix
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// https://spec.matrix.org/v1.13/client-server-api/#server-side-key-backups
+const (
+	KeyBackupAlgorithm = "m.megolm_backup.v1.curve25519-aes-sha2"
+	// SecretMegolmBackup is the secret storage name of the backup private key.
+	SecretMegolmBackup = "m.megolm_backup.v1"
+
+	defaultKeyBackupDebounce = 10 * time.Second
+)
+
+type KeyBackupAuthData struct {
+	PublicKey  string                       `json:"public_key"`
+	Signatures map[string]map[string]string `json:"signatures,omitempty"`
+}
+
+type KeyBackupVersion struct {
+	Algorithm string            `json:"algorithm"`
+	AuthData  KeyBackupAuthData `json:"auth_data"`
+	Count     int               `json:"count"`
+	ETag      string            `json:"etag"`
+	Version   string            `json:"version"`
+}
+
+// BackupSession is an inbound Megolm session exported at its first known message index.
+type BackupSession struct {
+	ExportedSession
+	FirstMessageIndex int
+	ForwardedCount    int
+	IsVerified        bool
+}
+
+// RoomKeyExporter is implemented by the Decrypters able to export the inbound Megolm sessions for the key backup.
+type RoomKeyExporter interface {
+	ExportRoomKey(ctx context.Context, roomID, sessionID string) (BackupSession, error)
+}
+
+type KeyBackupConfig struct {
+	// Version is the backup the received sessions are uploaded to; the upload is disabled if it is empty.
+	// The Decrypter must implement RoomKeyExporter.
+	Version string
+	// PublicKey is the curve25519 public key of the backup from its auth data, unpadded base64.
+	PublicKey string
+	// Debounce is the delay gathering the received sessions into one upload, 10 seconds by default.
+	Debounce time.Duration
+	// OnError is called when the sessions fail to be uploaded; they are retried with the next upload.
+	OnError func(err error)
+}
+
+// GenerateKeyBackupKey creates the curve25519 key pair of a new backup.
+// The private key is usually kept in the secret storage as SecretMegolmBackup.
+func GenerateKeyBackupKey() (*ecdh.PrivateKey, error) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate the backup key: %w", err)
+	}
+	return key, nil
+}
+
+// CreateKeyBackupVersion creates a new backup encrypted to the public key and returns its version.
+func (c *Client) CreateKeyBackupVersion(ctx context.Context, publicKey *ecdh.PublicKey) (string, error) {
+	var respData apiKeyBackupVersionResp
+	err := c.doJSON(ctx, http.MethodPost, "/_matrix/client/v3/room_keys/version", apiKeyBackupVersionReq{
+		Algorithm: KeyBackupAlgorithm,
+		AuthData:  KeyBackupAuthData{PublicKey: base64.RawStdEncoding.EncodeToString(publicKey.Bytes())},
+	}, &respData)
+	if err != nil {
+		return "", fmt.Errorf("failed to create the key backup: %w", err)
+	}
+	return respData.Version, nil
+}
+
+// GetKeyBackupVersion returns the current backup; the error matches IsNotFound if there is none.
+func (c *Client) GetKeyBackupVersion(ctx context.Context) (KeyBackupVersion, error) {
+	var version KeyBackupVersion
+	err := c.doJSON(ctx, http.MethodGet, "/_matrix/client/v3/room_keys/version", nil, &version)
+	if err != nil {
+		return KeyBackupVersion{}, fmt.Errorf("failed to get the key backup: %w", err)
+	}
+	return version, nil
+}
+
+// UploadRoomKeys encrypts the sessions to the public key and stores them in the backup.
+func (c *Client) UploadRoomKeys(ctx context.Context, version, publicKey string, sessions []BackupSession) error {
+	key, err := decodeBase64(publicKey)
+	if err != nil {
+		return fmt.Errorf("failed to decode the backup key: %w", err)
+	}
+	pub, err := ecdh.X25519().NewPublicKey(key)
+	if err != nil {
+		return fmt.Errorf("failed to parse the backup key: %w", err)
+	}
+
+	req := apiRoomKeysReq{Rooms: make(map[string]apiRoomKeyBackup)}
+	for _, session := range sessions {
+		data, err := encryptBackupSession(pub, session.ExportedSession)
+		if err != nil {
+			return err
+		}
+
+		room, ok := req.Rooms[session.RoomID]
+		if !ok {
+			room = apiRoomKeyBackup{Sessions: make(map[string]apiKeyBackupData)}
+			req.Rooms[session.RoomID] = room
+		}
+		room.Sessions[session.SessionID] = apiKeyBackupData{
+			FirstMessageIndex: session.FirstMessageIndex,
+			ForwardedCount:    session.ForwardedCount,
+			IsVerified:        session.IsVerified,
+			SessionData:       data,
+		}
+	}
+
+	path := "/_matrix/client/v3/room_keys/keys?" + url.Values{"version": {version}}.Encode()
+	err = c.doJSON(ctx, http.MethodPut, path, req, nil)
+	if err != nil {
+		return fmt.Errorf("failed to upload the room keys: %w", err)
+	}
+	return nil
+}
+
+func encryptBackupSession(publicKey *ecdh.PublicKey, session ExportedSession) (apiEncryptedSessionData, error) {
+	if session.ForwardingCurve25519KeyChain == nil {
+		session.ForwardingCurve25519KeyChain = []string{}
+	}
+	plaintext, err := json.Marshal(apiBackupSessionData{
+		Algorithm:                    session.Algorithm,
+		ForwardingCurve25519KeyChain: session.ForwardingCurve25519KeyChain,
+		SenderClaimedKeys:            session.SenderClaimedKeys,
+		SenderKey:                    session.SenderKey,
+		SessionKey:                   session.SessionKey,
+	})
+	if err != nil {
+		return apiEncryptedSessionData{}, fmt.Errorf("failed to marshal the session: %w", err)
+	}
+
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return apiEncryptedSessionData{}, fmt.Errorf("failed to generate the ephemeral key: %w", err)
+	}
+	shared, err := ephemeral.ECDH(publicKey)
+	if err != nil {
+		return apiEncryptedSessionData{}, fmt.Errorf("failed to agree on the shared secret: %w", err)
+	}
+
+	keys := make([]byte, 80)
+	_, err = io.ReadFull(hkdf(sha256.New, shared, make([]byte, 32), nil), keys)
+	if err != nil {
+		return apiEncryptedSessionData{}, fmt.Errorf("failed to derive the keys: %w", err)
+	}
+	aesKey, macKey, iv := keys[:32], keys[32:64], keys[64:]
+
+	block, err := aes.NewCipher(aesKey)
+	if err != nil {
+		return apiEncryptedSessionData{}, fmt.Errorf("failed to create the cipher: %w", err)
+	}
+	padding := aes.BlockSize - len(plaintext)%aes.BlockSize
+	plaintext = append(plaintext, bytes.Repeat([]byte{byte(padding)}, padding)...)
+	ciphertext := make([]byte, len(plaintext))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, plaintext)
+
+	// the MAC covers an empty message, as libolm implemented it and the spec kept it
+	mac := hmac.New(sha256.New, macKey)
+
+	return apiEncryptedSessionData{
+		Ephemeral:  base64.RawStdEncoding.EncodeToString(ephemeral.PublicKey().Bytes()),
+		Ciphertext: base64.RawStdEncoding.EncodeToString(ciphertext),
+		MAC:        base64.RawStdEncoding.EncodeToString(mac.Sum(nil)[:8]),
+	}, nil
+}
+
+// keyBackup uploads the sessions received during the syncs, gathering the ones received close together.
+type keyBackup struct {
+	client *Client
+	cfg    KeyBackupConfig
+
+	mux     sync.Mutex
+	pending map[sessionRef]struct{}
+	notify  chan struct{}
+}
+
+func newKeyBackup(client *Client, cfg KeyBackupConfig) *keyBackup {
+	if cfg.Version == "" {
+		return nil
+	}
+	if cfg.Debounce == 0 {
+		cfg.Debounce = defaultKeyBackupDebounce
+	}
+	return &keyBackup{
+		client:  client,
+		cfg:     cfg,
+		pending: make(map[sessionRef]struct{}),
+		notify:  make(chan struct{}, 1),
+	}
+}
+
+func (b *keyBackup) queue(roomID, sessionID string) {
+	if b == nil || roomID == "" || sessionID == "" {
+		return
+	}
+
+	b.mux.Lock()
+	b.pending[sessionRef{roomID: roomID, sessionID: sessionID}] = struct{}{}
+	b.mux.Unlock()
+
+	select {
+	case b.notify <- struct{}{}:
+	default:
+	}
+}
+
+// observe queues the sessions of the decrypted m.room_key to-device events.
+func (b *keyBackup) observe(ev Event) {
+	if b == nil || ev.Type != "m.room_key" {
+		return
+	}
+
+	var key apiRoomKeyContent
+	if ev.ParseContent(&key) == nil {
+		b.queue(key.RoomID, key.SessionID)
+	}
+}
+
+func (b *keyBackup) run(ctx context.Context) {
+	if b == nil {
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-b.notify:
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(b.cfg.Debounce):
+		}
+
+		err := b.upload(ctx)
+		if err != nil && ctx.Err() == nil && b.cfg.OnError != nil {
+			b.cfg.OnError(err)
+		}
+	}
+}
+
+// flush uploads the queued sessions without waiting for the debounce.
+func (b *keyBackup) flush(ctx context.Context) error {
+	if b == nil {
+		return nil
+	}
+
+	b.mux.Lock()
+	empty := len(b.pending) == 0
+	b.mux.Unlock()
+	if empty {
+		return nil
+	}
+	return b.upload(ctx)
+}
+
+func (b *keyBackup) upload(ctx context.Context) error {
+	exporter, ok := b.client.decryption.Decrypter.(RoomKeyExporter)
+	if !ok {
+		return errors.New("the decrypter can't export the room keys")
+	}
+
+	b.mux.Lock()
+	refs := b.pending
+	b.pending = make(map[sessionRef]struct{})
+	b.mux.Unlock()
+
+	sessions := make([]BackupSession, 0, len(refs))
+	var err error
+	for ref := range refs {
+		var session BackupSession
+		session, err = exporter.ExportRoomKey(ctx, ref.roomID, ref.sessionID)
+		if err != nil {
+			err = fmt.Errorf("failed to export the room key: %w", err)
+			break
+		}
+		sessions = append(sessions, session)
+	}
+	if err == nil {
+		err = b.client.UploadRoomKeys(ctx, b.cfg.Version, b.cfg.PublicKey, sessions)
+	}
+	if err == nil {
+		return nil
+	}
+
+	b.mux.Lock()
+	for ref := range refs {
+		b.pending[ref] = struct{}{}
+	}
+	b.mux.Unlock()
+	return err
+}
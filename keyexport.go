@@ -0,0 +1,185 @@
+package gomatrix
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"slices"
+	"strings"
+)
+
+const (
+	keyExportHeader  = "-----BEGIN MEGOLM SESSION DATA-----"
+	keyExportFooter  = "-----END MEGOLM SESSION DATA-----"
+	keyExportVersion = 1
+	keyExportRounds  = 500000
+	// maxImportRounds bounds the PBKDF2 work crafted parameters, of a key export or a secret storage passphrase,
+	// can make the client do: ten times what the clients use.
+	maxImportRounds = 10 * keyExportRounds
+)
+
+// ExportedSession is an inbound Megolm session in the key export format.
+// https://spec.matrix.org/v1.13/client-server-api/#key-export-format
+type ExportedSession struct {
+	Algorithm                    string            `json:"algorithm"`
+	ForwardingCurve25519KeyChain []string          `json:"forwarding_curve25519_key_chain"`
+	RoomID                       string            `json:"room_id"`
+	SenderKey                    string            `json:"sender_key"`
+	SenderClaimedKeys            map[string]string `json:"sender_claimed_keys"`
+	SessionID                    string            `json:"session_id"`
+	SessionKey                   string            `json:"session_key"`
+}
+
+// ExportRoomKeys encrypts the sessions with the passphrase into the file format Element and other clients import.
+func ExportRoomKeys(sessions []ExportedSession, passphrase string) ([]byte, error) {
+	sessions = slices.Clone(sessions)
+	for i := range sessions {
+		if sessions[i].ForwardingCurve25519KeyChain == nil {
+			sessions[i].ForwardingCurve25519KeyChain = []string{}
+		}
+	}
+	plaintext, err := json.Marshal(sessions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal the sessions: %w", err)
+	}
+
+	var salt, iv [16]byte
+	_, err = rand.Read(salt[:])
+	if err == nil {
+		_, err = rand.Read(iv[:])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate random data: %w", err)
+	}
+	// clearing bit 63 leaves room for the counter, as other implementations expect
+	iv[8] &= 0x7f
+
+	aesKey, hmacKey := deriveExportKeys(passphrase, salt[:], keyExportRounds)
+
+	block, err := aes.NewCipher(aesKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the cipher: %w", err)
+	}
+	ciphertext := make([]byte, len(plaintext))
+	cipher.NewCTR(block, iv[:]).XORKeyStream(ciphertext, plaintext)
+
+	var payload bytes.Buffer
+	payload.WriteByte(keyExportVersion)
+	payload.Write(salt[:])
+	payload.Write(iv[:])
+	_ = binary.Write(&payload, binary.BigEndian, uint32(keyExportRounds))
+	payload.Write(ciphertext)
+
+	mac := hmac.New(sha256.New, hmacKey)
+	mac.Write(payload.Bytes())
+	payload.Write(mac.Sum(nil))
+
+	encoded := base64.StdEncoding.EncodeToString(payload.Bytes())
+
+	var out strings.Builder
+	out.WriteString(keyExportHeader + "\n")
+	for len(encoded) > 0 {
+		n := min(len(encoded), 96)
+		out.WriteString(encoded[:n] + "\n")
+		encoded = encoded[n:]
+	}
+	out.WriteString(keyExportFooter + "\n")
+
+	return []byte(out.String()), nil
+}
+
+// ImportRoomKeys decrypts the sessions exported by ExportRoomKeys or another client with the passphrase.
+func ImportRoomKeys(data []byte, passphrase string) ([]ExportedSession, error) {
+	text := strings.TrimSpace(string(data))
+	text, ok := strings.CutPrefix(text, keyExportHeader)
+	if !ok {
+		return nil, errors.New("missing key export header")
+	}
+	text, ok = strings.CutSuffix(text, keyExportFooter)
+	if !ok {
+		return nil, errors.New("missing key export footer")
+	}
+
+	payload, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(text), ""))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the key export: %w", err)
+	}
+
+	const headerSize = 1 + 16 + 16 + 4
+	if len(payload) < headerSize+sha256.Size {
+		return nil, errors.New("key export is too short")
+	}
+	if payload[0] != keyExportVersion {
+		return nil, fmt.Errorf("unsupported key export version %d", payload[0])
+	}
+
+	salt, iv := payload[1:17], payload[17:33]
+	rounds := binary.BigEndian.Uint32(payload[33:37])
+	if rounds == 0 || rounds > maxImportRounds {
+		return nil, fmt.Errorf("invalid key export rounds %d", rounds)
+	}
+	signed, sum := payload[:len(payload)-sha256.Size], payload[len(payload)-sha256.Size:]
+
+	aesKey, hmacKey := deriveExportKeys(passphrase, salt, int(rounds))
+
+	mac := hmac.New(sha256.New, hmacKey)
+	mac.Write(signed)
+	if !hmac.Equal(mac.Sum(nil), sum) {
+		return nil, errors.New("wrong passphrase or corrupted key export")
+	}
+
+	block, err := aes.NewCipher(aesKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the cipher: %w", err)
+	}
+	plaintext := make([]byte, len(signed)-headerSize)
+	cipher.NewCTR(block, iv).XORKeyStream(plaintext, signed[headerSize:])
+
+	var sessions []ExportedSession
+	err = json.Unmarshal(plaintext, &sessions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+func deriveExportKeys(passphrase string, salt []byte, rounds int) (aesKey, hmacKey []byte) {
+	key := pbkdf2(sha512.New, []byte(passphrase), salt, rounds, 64)
+	return key[:32], key[32:]
+}
+
+// pbkdf2 derives a key as defined in RFC 8018.
+func pbkdf2(h func() hash.Hash, password, salt []byte, rounds, keyLen int) []byte {
+	prf := hmac.New(h, password)
+	size := prf.Size()
+
+	var key []byte
+	for block := uint32(1); len(key) < keyLen; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		_ = binary.Write(prf, binary.BigEndian, block)
+		u := prf.Sum(nil)
+
+		t := bytes.Clone(u)
+		for range rounds - 1 {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for i := range size {
+				t[i] ^= u[i]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLen]
+}
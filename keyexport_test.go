@@ -0,0 +1,99 @@
+package gomatrix_test
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"testing"
+
+	gomatrix "github.com/beldeveloper/go-matrix"
+)
+
+func TestRoomKeysRoundTrip(t *testing.T) {
+	sessions := []gomatrix.ExportedSession{
+		{
+			Algorithm:                    "m.megolm.v1.aes-sha2",
+			ForwardingCurve25519KeyChain: []string{"forwarder"},
+			RoomID:                       "!room:example.org",
+			SenderKey:                    "sender",
+			SenderClaimedKeys:            map[string]string{"ed25519": "claimed"},
+			SessionID:                    "session",
+			SessionKey:                   "key",
+		},
+		{Algorithm: "m.megolm.v1.aes-sha2", RoomID: "!other:example.org", SessionID: "other", SessionKey: "other key"},
+	}
+	want := append([]gomatrix.ExportedSession(nil), sessions...)
+	want[1].ForwardingCurve25519KeyChain = []string{}
+
+	exported, err := gomatrix.ExportRoomKeys(sessions, "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sessions[1].ForwardingCurve25519KeyChain != nil {
+		t.Fatal("ExportRoomKeys modified the exported sessions")
+	}
+
+	// payload rewrites the base64 encoded data between the header and the footer
+	payload := func(modify func(data []byte) []byte) []byte {
+		lines := strings.Split(strings.TrimSpace(string(exported)), "\n")
+		data, err := base64.StdEncoding.DecodeString(strings.Join(lines[1:len(lines)-1], ""))
+		if err != nil {
+			t.Fatal(err)
+		}
+		encoded := base64.StdEncoding.EncodeToString(modify(data))
+		return []byte(lines[0] + "\n" + encoded + "\n" + lines[len(lines)-1] + "\n")
+	}
+
+	tests := []struct {
+		name       string
+		data       []byte
+		passphrase string
+		wantErr    bool
+	}{
+		{name: "round trip", data: exported, passphrase: "passphrase"},
+		{name: "rewrapped", data: []byte(strings.ReplaceAll(string(exported), "\n", "\r\n")), passphrase: "passphrase"},
+		{name: "wrong passphrase", data: exported, passphrase: "wrong", wantErr: true},
+		{
+			name:       "tampered",
+			data:       payload(func(data []byte) []byte { data[40] ^= 1; return data }),
+			passphrase: "passphrase",
+			wantErr:    true,
+		},
+		{name: "missing header", data: []byte(strings.SplitN(string(exported), "\n", 2)[1]), passphrase: "passphrase", wantErr: true},
+		{name: "missing footer", data: []byte(strings.TrimSuffix(string(exported), "-----END MEGOLM SESSION DATA-----\n")), passphrase: "passphrase", wantErr: true},
+		{
+			name:       "not base64",
+			data:       []byte("-----BEGIN MEGOLM SESSION DATA-----\n!!!!\n-----END MEGOLM SESSION DATA-----\n"),
+			passphrase: "passphrase",
+			wantErr:    true,
+		},
+		{name: "too short", data: payload(func(data []byte) []byte { return data[:60] }), passphrase: "passphrase", wantErr: true},
+		{name: "unsupported version", data: payload(func(data []byte) []byte { data[0] = 2; return data }), passphrase: "passphrase", wantErr: true},
+		{
+			name:       "zero rounds",
+			data:       payload(func(data []byte) []byte { binary.BigEndian.PutUint32(data[33:37], 0); return data }),
+			passphrase: "passphrase",
+			wantErr:    true,
+		},
+		{
+			// must fail before the key derivation, which would take minutes
+			name:       "too many rounds",
+			data:       payload(func(data []byte) []byte { binary.BigEndian.PutUint32(data[33:37], 1<<31); return data }),
+			passphrase: "passphrase",
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := gomatrix.ImportRoomKeys(tt.data, tt.passphrase)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %t", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, want) {
+				t.Fatalf("got %+v, want %+v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package gomatrix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// ForwardedRoomKeyContent is a Megolm session shared by another device, e.g. in response to a key request.
+// https://spec.matrix.org/v1.13/client-server-api/#mforwarded_room_key
+type ForwardedRoomKeyContent struct {
+	Algorithm                    string   `json:"algorithm"`
+	RoomID                       string   `json:"room_id"`
+	SenderKey                    string   `json:"sender_key"`
+	SessionID                    string   `json:"session_id"`
+	SessionKey                   string   `json:"session_key"`
+	SenderClaimedEd25519Key      string   `json:"sender_claimed_ed25519_key"`
+	ForwardingCurve25519KeyChain []string `json:"forwarding_curve25519_key_chain"`
+}
+
+// RoomKeyImporter is implemented by the Decrypters able to import the forwarded Megolm sessions.
+type RoomKeyImporter interface {
+	ImportForwardedRoomKey(ctx context.Context, key ForwardedRoomKeyContent) error
+}
+
+type keyRequest struct {
+	id      string
+	devices []string
+}
+
+// keyRequests tracks the room key requests waiting for a forwarded key.
+type keyRequests struct {
+	mux     sync.Mutex
+	pending map[sessionRef]keyRequest
+}
+
+func newKeyRequests() *keyRequests {
+	return &keyRequests{pending: make(map[sessionRef]keyRequest)}
+}
+
+// RequestRoomKey asks the verified devices of the user to share the Megolm session of the encrypted event.
+// A session already requested and not received yet is not requested again.
+// https://spec.matrix.org/v1.13/client-server-api/#mroom_key_request
+func (c *Client) RequestRoomKey(ctx context.Context, roomID string, content EncryptedContent) error {
+	userID, err := c.WhoAmI(ctx)
+	if err != nil {
+		return err
+	}
+	deviceID, err := c.DeviceID(ctx)
+	if err != nil {
+		return err
+	}
+
+	devices, err := c.verifiedDevices(ctx, userID)
+	if err != nil {
+		return err
+	}
+	devices = slices.DeleteFunc(devices, func(id string) bool { return id == deviceID })
+	if len(devices) == 0 {
+		return errors.New("no verified devices to request the key from")
+	}
+
+	ref := sessionRef{roomID: roomID, sessionID: content.SessionID}
+	req := keyRequest{id: uuid.NewString(), devices: devices}
+
+	c.keyRequests.mux.Lock()
+	_, exists := c.keyRequests.pending[ref]
+	if !exists {
+		c.keyRequests.pending[ref] = req
+	}
+	c.keyRequests.mux.Unlock()
+	if exists {
+		return nil
+	}
+
+	err = c.sendKeyRequest(ctx, userID, req, apiRoomKeyRequest{
+		Action: "request",
+		Body: &apiRoomKeyRequestBody{
+			Algorithm: content.Algorithm,
+			RoomID:    roomID,
+			SessionID: content.SessionID,
+			SenderKey: content.SenderKey,
+		},
+		RequestID:          req.id,
+		RequestingDeviceID: deviceID,
+	})
+	if err != nil {
+		c.keyRequests.mux.Lock()
+		delete(c.keyRequests.pending, ref)
+		c.keyRequests.mux.Unlock()
+	}
+	return err
+}
+
+// verifiedDevices returns the devices of the user trusted with the room keys, none without VerifiedDevices.
+func (c *Client) verifiedDevices(ctx context.Context, userID string) ([]string, error) {
+	if c.decryption.VerifiedDevices == nil {
+		return nil, nil
+	}
+	devices, err := c.decryption.VerifiedDevices(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the verified devices: %w", err)
+	}
+	return slices.Clone(devices), nil
+}
+
+func (c *Client) sendKeyRequest(ctx context.Context, userID string, req keyRequest, content apiRoomKeyRequest) error {
+	messages := make(map[string]any, len(req.devices))
+	for _, deviceID := range req.devices {
+		messages[deviceID] = content
+	}
+	return c.SendToDevice(ctx, "m.room_key_request", map[string]map[string]any{userID: messages})
+}
+
+// handleToDevice processes the to-device events of the sync relevant to the decryption
+// and returns the events decrypted.
+func (c *Client) handleToDevice(ctx context.Context, events []Event) []Event {
+	if c.decryption.Decrypter == nil {
+		return events
+	}
+	importer, canImport := c.decryption.Decrypter.(RoomKeyImporter)
+
+	decrypted := make([]Event, 0, len(events))
+	for _, ev := range events {
+		// the Olm identity key of the sending device, the forwarded keys are accepted from the verified devices only
+		var encrypted EncryptedContent
+		if ev.Type == "m.room.encrypted" {
+			_ = ev.ParseContent(&encrypted)
+		}
+
+		ev, ok := c.decrypt(ctx, ev)
+		if !ok {
+			continue
+		}
+		decrypted = append(decrypted, ev)
+
+		c.keyBackup.observe(ev)
+		if ev.Type != "m.forwarded_room_key" || !canImport {
+			continue
+		}
+
+		err := c.acceptForwardedKey(ctx, importer, ev, encrypted.SenderKey)
+		if err != nil && c.decryption.OnError != nil {
+			c.decryption.OnError(ctx, &DecryptionError{Event: ev, Err: err})
+		}
+	}
+	return decrypted
+}
+
+// acceptForwardedKey imports the key requested by the client and cancels the request on the other devices.
+// The key must come Olm encrypted from a verified device the request was sent to, identified by senderKey.
+func (c *Client) acceptForwardedKey(ctx context.Context, importer RoomKeyImporter, ev Event, senderKey string) error {
+	var key ForwardedRoomKeyContent
+	err := ev.ParseContent(&key)
+	if err != nil {
+		return fmt.Errorf("failed to parse the forwarded key: %w", err)
+	}
+
+	userID, err := c.WhoAmI(ctx)
+	if err != nil {
+		return err
+	}
+	if ev.Sender != userID {
+		// only the own devices are asked for the keys
+		return nil
+	}
+
+	ref := sessionRef{roomID: key.RoomID, sessionID: key.SessionID}
+	c.keyRequests.mux.Lock()
+	req, ok := c.keyRequests.pending[ref]
+	c.keyRequests.mux.Unlock()
+	if !ok {
+		return nil
+	}
+
+	trusted, err := c.isTrustedForwarder(ctx, userID, req.devices, senderKey)
+	if err != nil {
+		return err
+	}
+	if !trusted {
+		// the request stays pending for the answers of the verified devices
+		return errors.New("the forwarded key was not sent by a verified device the key was requested from")
+	}
+
+	c.keyRequests.mux.Lock()
+	req, ok = c.keyRequests.pending[ref]
+	delete(c.keyRequests.pending, ref)
+	c.keyRequests.mux.Unlock()
+	if !ok {
+		return nil
+	}
+
+	err = importer.ImportForwardedRoomKey(ctx, key)
+	if err != nil {
+		return fmt.Errorf("failed to import the forwarded key: %w", err)
+	}
+	c.keyBackup.queue(key.RoomID, key.SessionID)
+
+	deviceID, err := c.DeviceID(ctx)
+	if err != nil {
+		return err
+	}
+	return c.sendKeyRequest(ctx, userID, req, apiRoomKeyRequest{
+		Action:             "request_cancellation",
+		RequestID:          req.id,
+		RequestingDeviceID: deviceID,
+	})
+}
+
+// isTrustedForwarder reports whether the Olm identity key belongs to one of the requested devices
+// and the device is still verified.
+func (c *Client) isTrustedForwarder(ctx context.Context, userID string, requested []string, senderKey string) (bool, error) {
+	if senderKey == "" {
+		return false, nil
+	}
+
+	resp, err := c.QueryKeys(ctx, map[string][]string{userID: requested}, 0)
+	if err != nil {
+		return false, err
+	}
+	var sender string
+	for deviceID, keys := range resp.DeviceKeys[userID] {
+		if slices.Contains(requested, deviceID) && keys.Curve25519() == senderKey {
+			sender = deviceID
+			break
+		}
+	}
+	if sender == "" {
+		return false, nil
+	}
+
+	verified, err := c.verifiedDevices(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(verified, sender), nil
+}
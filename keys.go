@@ -0,0 +1,235 @@
+package gomatrix
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// https://spec.matrix.org/v1.13/client-server-api/#end-to-end-encryption
+const (
+	AlgorithmOlm    = "m.olm.v1.curve25519-aes-sha2"
+	AlgorithmMegolm = "m.megolm.v1.aes-sha2"
+
+	KeyAlgorithmEd25519          = "ed25519"
+	KeyAlgorithmCurve25519       = "curve25519"
+	KeyAlgorithmSignedCurve25519 = "signed_curve25519"
+)
+
+type DeviceKeys struct {
+	UserID     string                       `json:"user_id"`
+	DeviceID   string                       `json:"device_id"`
+	Algorithms []string                     `json:"algorithms"`
+	Keys       map[string]string            `json:"keys"`
+	Signatures map[string]map[string]string `json:"signatures,omitempty"`
+	Unsigned   *DeviceKeysUnsigned          `json:"unsigned,omitempty"`
+
+	// raw is the object as received, the signature is verified over it
+	raw json.RawMessage
+}
+
+type DeviceKeysUnsigned struct {
+	DeviceDisplayName string `json:"device_display_name,omitempty"`
+}
+
+func (k *DeviceKeys) UnmarshalJSON(data []byte) error {
+	type plain DeviceKeys
+	err := json.Unmarshal(data, (*plain)(k))
+	if err != nil {
+		return err
+	}
+	k.raw = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+func (k DeviceKeys) MarshalJSON() ([]byte, error) {
+	if k.raw != nil {
+		return k.raw, nil
+	}
+	type plain DeviceKeys
+	return json.Marshal(plain(k))
+}
+
+// Ed25519 returns the fingerprint key of the device.
+func (k DeviceKeys) Ed25519() string {
+	return k.Keys[KeyAlgorithmEd25519+":"+k.DeviceID]
+}
+
+// Curve25519 returns the identity key of the device.
+func (k DeviceKeys) Curve25519() string {
+	return k.Keys[KeyAlgorithmCurve25519+":"+k.DeviceID]
+}
+
+// Verify checks the keys are signed by the device itself.
+func (k DeviceKeys) Verify() error {
+	key, err := ParseEd25519Key(k.Ed25519())
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(k)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the device keys: %w", err)
+	}
+	return VerifySignature(data, k.UserID, KeyAlgorithmEd25519+":"+k.DeviceID, key)
+}
+
+// OneTimeKey is a signed curve25519 key used to establish an Olm session with a device.
+type OneTimeKey struct {
+	Key        string                       `json:"key"`
+	Fallback   bool                         `json:"fallback,omitempty"`
+	Signatures map[string]map[string]string `json:"signatures,omitempty"`
+
+	raw json.RawMessage
+}
+
+func (k *OneTimeKey) UnmarshalJSON(data []byte) error {
+	// unsigned keys are plain strings
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, &k.Key)
+	}
+
+	type plain OneTimeKey
+	err := json.Unmarshal(data, (*plain)(k))
+	if err != nil {
+		return err
+	}
+	k.raw = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+func (k OneTimeKey) MarshalJSON() ([]byte, error) {
+	if k.raw != nil {
+		return k.raw, nil
+	}
+	type plain OneTimeKey
+	return json.Marshal(plain(k))
+}
+
+// Verify checks the key is signed by the device owning it.
+func (k OneTimeKey) Verify(device DeviceKeys) error {
+	key, err := ParseEd25519Key(device.Ed25519())
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(k)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the one-time key: %w", err)
+	}
+	return VerifySignature(data, device.UserID, KeyAlgorithmEd25519+":"+device.DeviceID, key)
+}
+
+type CrossSigningKey struct {
+	UserID     string                       `json:"user_id"`
+	Usage      []string                     `json:"usage"`
+	Keys       map[string]string            `json:"keys"`
+	Signatures map[string]map[string]string `json:"signatures,omitempty"`
+}
+
+type KeysUploadRequest struct {
+	DeviceKeys *DeviceKeys `json:"device_keys,omitempty"`
+	// OneTimeKeys and FallbackKeys are keyed by <algorithm>:<key ID>.
+	OneTimeKeys  map[string]OneTimeKey `json:"one_time_keys,omitempty"`
+	FallbackKeys map[string]OneTimeKey `json:"fallback_keys,omitempty"`
+}
+
+// UploadKeys publishes the keys of the device and returns the number of unclaimed one-time keys per algorithm.
+func (c *Client) UploadKeys(ctx context.Context, req KeysUploadRequest) (map[string]int, error) {
+	var respData apiKeysUploadResp
+	err := c.doJSON(ctx, http.MethodPost, "/_matrix/client/v3/keys/upload", req, &respData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload the keys: %w", err)
+	}
+	return respData.OneTimeKeyCounts, nil
+}
+
+type KeysQueryResponse struct {
+	// DeviceKeys holds the devices with valid self-signatures only.
+	DeviceKeys map[string]map[string]DeviceKeys `json:"device_keys"`
+	// InvalidDevices lists the devices whose keys were dropped because of a failed verification.
+	InvalidDevices  map[string][]string        `json:"-"`
+	Failures        map[string]json.RawMessage `json:"failures,omitempty"`
+	MasterKeys      map[string]CrossSigningKey `json:"master_keys,omitempty"`
+	SelfSigningKeys map[string]CrossSigningKey `json:"self_signing_keys,omitempty"`
+	UserSigningKeys map[string]CrossSigningKey `json:"user_signing_keys,omitempty"`
+}
+
+// QueryKeys downloads the device and cross-signing keys of the users. An empty device list
+// queries all devices of the user. The device keys not signed by the device itself
+// or published under a different user or device ID are dropped.
+func (c *Client) QueryKeys(ctx context.Context, devices map[string][]string, timeout time.Duration) (KeysQueryResponse, error) {
+	req := apiKeysQueryReq{DeviceKeys: make(map[string][]string, len(devices))}
+	for userID, deviceIDs := range devices {
+		if deviceIDs == nil {
+			deviceIDs = []string{}
+		}
+		req.DeviceKeys[userID] = deviceIDs
+	}
+	if timeout > 0 {
+		req.Timeout = timeout.Milliseconds()
+	}
+
+	var resp KeysQueryResponse
+	err := c.doJSON(ctx, http.MethodPost, "/_matrix/client/v3/keys/query", req, &resp)
+	if err != nil {
+		return KeysQueryResponse{}, fmt.Errorf("failed to query the keys: %w", err)
+	}
+
+	for userID, userDevices := range resp.DeviceKeys {
+		for deviceID, keys := range userDevices {
+			if keys.UserID == userID && keys.DeviceID == deviceID && keys.Verify() == nil {
+				continue
+			}
+			delete(userDevices, deviceID)
+			if resp.InvalidDevices == nil {
+				resp.InvalidDevices = make(map[string][]string)
+			}
+			resp.InvalidDevices[userID] = append(resp.InvalidDevices[userID], deviceID)
+		}
+	}
+
+	return resp, nil
+}
+
+type KeysClaimResponse struct {
+	// OneTimeKeys maps the users to their devices to the claimed keys by <algorithm>:<key ID>.
+	OneTimeKeys map[string]map[string]map[string]OneTimeKey `json:"one_time_keys"`
+	Failures    map[string]json.RawMessage                  `json:"failures,omitempty"`
+}
+
+// ClaimKeys claims a one-time key of the given algorithm for each of the devices. The claimed keys
+// should be verified with OneTimeKey.Verify against the queried device keys.
+func (c *Client) ClaimKeys(
+	ctx context.Context, devices map[string]map[string]string, timeout time.Duration,
+) (KeysClaimResponse, error) {
+	req := apiKeysClaimReq{OneTimeKeys: devices}
+	if timeout > 0 {
+		req.Timeout = timeout.Milliseconds()
+	}
+
+	var resp KeysClaimResponse
+	err := c.doJSON(ctx, http.MethodPost, "/_matrix/client/v3/keys/claim", req, &resp)
+	if err != nil {
+		return KeysClaimResponse{}, fmt.Errorf("failed to claim the keys: %w", err)
+	}
+	return resp, nil
+}
+
+// KeyChanges returns the users whose device keys changed between the two sync tokens
+// and the users no longer sharing an encrypted room with the client.
+func (c *Client) KeyChanges(ctx context.Context, from, to string) (changed, left []string, err error) {
+	query := url.Values{}
+	query.Set("from", from)
+	query.Set("to", to)
+
+	var respData apiKeyChangesResp
+	err = c.doJSON(ctx, http.MethodGet, withQuery("/_matrix/client/v3/keys/changes", query), nil, &respData)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get the key changes: %w", err)
+	}
+	return respData.Changed, respData.Left, nil
+}
@@ -0,0 +1,46 @@
+package gomatrix
+
+import (
+	"context"
+	"io"
+)
+
+// MatrixClient is the surface of Client the services commonly depend on, so they can be tested
+// with a double such as matrixtest.Client instead of a homeserver.
+type MatrixClient interface {
+	WhoAmI(ctx context.Context) (string, error)
+
+	Send(ctx context.Context, roomID string, msg Message) (string, error)
+	SendText(ctx context.Context, roomID, text string) (string, error)
+	SendNotice(ctx context.Context, roomID, text string) (string, error)
+	SendEmote(ctx context.Context, roomID, text string) (string, error)
+	SendHTML(ctx context.Context, roomID, html string) (string, error)
+	SendMedia(ctx context.Context, roomID string, media Media) (string, error)
+	SendReaction(ctx context.Context, roomID, eventID, key string) (string, error)
+	SendReceipt(ctx context.Context, roomID, eventID, receiptType, threadID string) error
+	SendToDevice(ctx context.Context, eventType string, messages map[string]map[string]any) error
+	Redact(ctx context.Context, roomID, eventID, reason string) (string, error)
+
+	UploadFile(ctx context.Context, contentType string, data []byte) (MXCURI, error)
+	Download(ctx context.Context, uri MXCURI) (io.ReadCloser, string, error)
+
+	CreateRoom(ctx context.Context, req CreateRoomRequest) (string, error)
+	GetOrCreateDM(ctx context.Context, userID string) (string, error)
+	JoinRoom(ctx context.Context, roomIDOrAlias string) (string, error)
+	LeaveRoom(ctx context.Context, roomID string) error
+	Invite(ctx context.Context, roomID, userID, reason string) error
+	Kick(ctx context.Context, roomID, userID, reason string) error
+	Ban(ctx context.Context, roomID, userID, reason string) error
+	GetJoinedRooms(ctx context.Context) ([]string, error)
+	GetJoinedMembers(ctx context.Context, roomID string) (map[string]JoinedMember, error)
+	GetMessages(ctx context.Context, roomID string, opts PaginationOptions) (MessagesPage, error)
+
+	GetStateEvent(ctx context.Context, roomID, eventType, stateKey string, v any) error
+	SetStateEvent(ctx context.Context, roomID, eventType, stateKey string, content any) (string, error)
+	GetAccountData(ctx context.Context, dataType string, v any) error
+	SetAccountData(ctx context.Context, dataType string, v any) error
+
+	Listen(ctx context.Context, handler EventHandler) error
+}
+
+var _ MatrixClient = (*Client)(nil)
@@ -0,0 +1,460 @@
+// Package matrixtest provides an in-memory double of gomatrix.MatrixClient for the unit tests
+// of the services built on the client:
+//
+//	client := matrixtest.NewClient("@bot:example.org")
+//	client.FailWith("SendText", errors.New("boom"))
+//	svc := NewService(client)
+//	...
+//	calls := client.CallsTo("SendNotice")
+package matrixtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	gomatrix "github.com/beldeveloper/go-matrix"
+)
+
+const serverName = "matrixtest.local"
+
+// Call is a recorded call of a method of the client.
+type Call struct {
+	Method string
+	Args   []any
+}
+
+type media struct {
+	contentType string
+	data        []byte
+}
+
+// Client records the calls and keeps the rooms, the state, the account data and the media in memory.
+// Sending returns generated event IDs and always succeeds, unless an error is set with FailWith.
+type Client struct {
+	userID string
+
+	mux         sync.Mutex
+	calls       []Call
+	errs        map[string]error
+	events      []gomatrix.Event
+	delivered   chan struct{}
+	seq         int
+	joined      []string
+	members     map[string]map[string]gomatrix.JoinedMember
+	state       map[string]json.RawMessage
+	accountData map[string]json.RawMessage
+	media       map[string]media
+	dms         map[string]string
+}
+
+var _ gomatrix.MatrixClient = (*Client)(nil)
+
+func NewClient(userID string) *Client {
+	return &Client{
+		userID:      userID,
+		errs:        make(map[string]error),
+		members:     make(map[string]map[string]gomatrix.JoinedMember),
+		state:       make(map[string]json.RawMessage),
+		accountData: make(map[string]json.RawMessage),
+		media:       make(map[string]media),
+		dms:         make(map[string]string),
+		delivered:   make(chan struct{}, 1),
+	}
+}
+
+// FailWith makes the method return the error from now on; a nil error makes it succeed again.
+func (c *Client) FailWith(method string, err error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if err == nil {
+		delete(c.errs, method)
+		return
+	}
+	c.errs[method] = err
+}
+
+// Deliver queues the events for the handler of Listen.
+func (c *Client) Deliver(events ...gomatrix.Event) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.events = append(c.events, events...)
+
+	select {
+	case c.delivered <- struct{}{}:
+	default:
+	}
+}
+
+// Calls returns the recorded calls in order.
+func (c *Client) Calls() []Call {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return slices.Clone(c.calls)
+}
+
+// CallsTo returns the recorded calls of the method in order.
+func (c *Client) CallsTo(method string) []Call {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	var calls []Call
+	for _, call := range c.calls {
+		if call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// call records the call and returns the error set for the method; c.mux must be held.
+func (c *Client) call(method string, args ...any) error {
+	c.calls = append(c.calls, Call{Method: method, Args: args})
+	return c.errs[method]
+}
+
+// nextID returns a new ID with the sigil; c.mux must be held.
+func (c *Client) nextID(sigil string) string {
+	c.seq++
+	return fmt.Sprintf("%s%d:%s", sigil, c.seq, serverName)
+}
+
+// join adds the room and the user to its members; c.mux must be held.
+func (c *Client) join(roomID string) {
+	if !slices.Contains(c.joined, roomID) {
+		c.joined = append(c.joined, roomID)
+	}
+	if c.members[roomID] == nil {
+		c.members[roomID] = make(map[string]gomatrix.JoinedMember)
+	}
+	c.members[roomID][c.userID] = gomatrix.JoinedMember{}
+}
+
+func notFound(what string) error {
+	return &gomatrix.HTTPError{
+		StatusCode: http.StatusNotFound,
+		Body:       []byte(`{"errcode":"M_NOT_FOUND"}`),
+		ErrCode:    "M_NOT_FOUND",
+		Message:    what + " not found",
+	}
+}
+
+func (c *Client) WhoAmI(context.Context) (string, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.userID, c.call("WhoAmI")
+}
+
+func (c *Client) send(method string, args ...any) (string, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	err := c.call(method, args...)
+	if err != nil {
+		return "", err
+	}
+	return c.nextID("$"), nil
+}
+
+func (c *Client) Send(_ context.Context, roomID string, msg gomatrix.Message) (string, error) {
+	return c.send("Send", roomID, msg)
+}
+
+func (c *Client) SendText(_ context.Context, roomID, text string) (string, error) {
+	return c.send("SendText", roomID, text)
+}
+
+func (c *Client) SendNotice(_ context.Context, roomID, text string) (string, error) {
+	return c.send("SendNotice", roomID, text)
+}
+
+func (c *Client) SendEmote(_ context.Context, roomID, text string) (string, error) {
+	return c.send("SendEmote", roomID, text)
+}
+
+func (c *Client) SendHTML(_ context.Context, roomID, html string) (string, error) {
+	return c.send("SendHTML", roomID, html)
+}
+
+func (c *Client) SendMedia(_ context.Context, roomID string, media gomatrix.Media) (string, error) {
+	return c.send("SendMedia", roomID, media)
+}
+
+func (c *Client) SendReaction(_ context.Context, roomID, eventID, key string) (string, error) {
+	return c.send("SendReaction", roomID, eventID, key)
+}
+
+func (c *Client) Redact(_ context.Context, roomID, eventID, reason string) (string, error) {
+	return c.send("Redact", roomID, eventID, reason)
+}
+
+func (c *Client) SendReceipt(_ context.Context, roomID, eventID, receiptType, threadID string) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.call("SendReceipt", roomID, eventID, receiptType, threadID)
+}
+
+func (c *Client) SendToDevice(_ context.Context, eventType string, messages map[string]map[string]any) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.call("SendToDevice", eventType, messages)
+}
+
+func (c *Client) UploadFile(_ context.Context, contentType string, data []byte) (gomatrix.MXCURI, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	err := c.call("UploadFile", contentType, data)
+	if err != nil {
+		return gomatrix.MXCURI{}, err
+	}
+
+	c.seq++
+	uri, err := gomatrix.ParseMXC(fmt.Sprintf("mxc://%s/media%d", serverName, c.seq))
+	if err != nil {
+		return gomatrix.MXCURI{}, err
+	}
+	c.media[uri.String()] = media{contentType: contentType, data: bytes.Clone(data)}
+	return uri, nil
+}
+
+// Download returns the media uploaded with UploadFile.
+func (c *Client) Download(_ context.Context, uri gomatrix.MXCURI) (io.ReadCloser, string, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	err := c.call("Download", uri)
+	if err != nil {
+		return nil, "", err
+	}
+
+	m, ok := c.media[uri.String()]
+	if !ok {
+		return nil, "", notFound("media")
+	}
+	return io.NopCloser(bytes.NewReader(m.data)), m.contentType, nil
+}
+
+func (c *Client) CreateRoom(_ context.Context, req gomatrix.CreateRoomRequest) (string, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	err := c.call("CreateRoom", req)
+	if err != nil {
+		return "", err
+	}
+
+	roomID := c.nextID("!")
+	c.join(roomID)
+	return roomID, nil
+}
+
+func (c *Client) GetOrCreateDM(_ context.Context, userID string) (string, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	err := c.call("GetOrCreateDM", userID)
+	if err != nil {
+		return "", err
+	}
+
+	roomID, ok := c.dms[userID]
+	if !ok {
+		roomID = c.nextID("!")
+		c.dms[userID] = roomID
+		c.join(roomID)
+		c.members[roomID][userID] = gomatrix.JoinedMember{}
+	}
+	return roomID, nil
+}
+
+// JoinRoom joins the room ID as is and makes up a room ID for an alias.
+func (c *Client) JoinRoom(_ context.Context, roomIDOrAlias string) (string, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	err := c.call("JoinRoom", roomIDOrAlias)
+	if err != nil {
+		return "", err
+	}
+
+	roomID := roomIDOrAlias
+	if !strings.HasPrefix(roomID, "!") {
+		roomID = c.nextID("!")
+	}
+	c.join(roomID)
+	return roomID, nil
+}
+
+func (c *Client) LeaveRoom(_ context.Context, roomID string) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	err := c.call("LeaveRoom", roomID)
+	if err != nil {
+		return err
+	}
+
+	c.joined = slices.DeleteFunc(c.joined, func(id string) bool { return id == roomID })
+	delete(c.members[roomID], c.userID)
+	return nil
+}
+
+func (c *Client) Invite(_ context.Context, roomID, userID, reason string) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.call("Invite", roomID, userID, reason)
+}
+
+func (c *Client) Kick(_ context.Context, roomID, userID, reason string) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	err := c.call("Kick", roomID, userID, reason)
+	if err == nil {
+		delete(c.members[roomID], userID)
+	}
+	return err
+}
+
+func (c *Client) Ban(_ context.Context, roomID, userID, reason string) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	err := c.call("Ban", roomID, userID, reason)
+	if err == nil {
+		delete(c.members[roomID], userID)
+	}
+	return err
+}
+
+func (c *Client) GetJoinedRooms(context.Context) ([]string, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return slices.Clone(c.joined), c.call("GetJoinedRooms")
+}
+
+func (c *Client) GetJoinedMembers(_ context.Context, roomID string) (map[string]gomatrix.JoinedMember, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	err := c.call("GetJoinedMembers", roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make(map[string]gomatrix.JoinedMember, len(c.members[roomID]))
+	for userID, member := range c.members[roomID] {
+		members[userID] = member
+	}
+	return members, nil
+}
+
+// GetMessages returns an empty page; the room history is not kept.
+func (c *Client) GetMessages(_ context.Context, roomID string, opts gomatrix.PaginationOptions) (gomatrix.MessagesPage, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return gomatrix.MessagesPage{Start: opts.From}, c.call("GetMessages", roomID, opts)
+}
+
+// GetStateEvent returns the content set with SetStateEvent, an M_NOT_FOUND error otherwise.
+func (c *Client) GetStateEvent(_ context.Context, roomID, eventType, stateKey string, v any) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	err := c.call("GetStateEvent", roomID, eventType, stateKey)
+	if err != nil {
+		return err
+	}
+
+	content, ok := c.state[roomID+"\x00"+eventType+"\x00"+stateKey]
+	if !ok {
+		return notFound("state event")
+	}
+	return json.Unmarshal(content, v)
+}
+
+func (c *Client) SetStateEvent(_ context.Context, roomID, eventType, stateKey string, content any) (string, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	err := c.call("SetStateEvent", roomID, eventType, stateKey, content)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(content)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal the state event: %w", err)
+	}
+	c.state[roomID+"\x00"+eventType+"\x00"+stateKey] = data
+	return c.nextID("$"), nil
+}
+
+// GetAccountData returns the data set with SetAccountData, an M_NOT_FOUND error otherwise.
+func (c *Client) GetAccountData(_ context.Context, dataType string, v any) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	err := c.call("GetAccountData", dataType)
+	if err != nil {
+		return err
+	}
+
+	data, ok := c.accountData[dataType]
+	if !ok {
+		return notFound("account data")
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (c *Client) SetAccountData(_ context.Context, dataType string, v any) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	err := c.call("SetAccountData", dataType, v)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the account data: %w", err)
+	}
+	c.accountData[dataType] = data
+	return nil
+}
+
+// Listen passes the delivered events to the handler until the context is done.
+func (c *Client) Listen(ctx context.Context, handler gomatrix.EventHandler) error {
+	c.mux.Lock()
+	err := c.call("Listen")
+	c.mux.Unlock()
+	if err != nil {
+		return err
+	}
+
+	for {
+		c.mux.Lock()
+		events := c.events
+		c.events = nil
+		c.mux.Unlock()
+
+		for _, ev := range events {
+			handler(ctx, ev)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.delivered:
+		}
+	}
+}
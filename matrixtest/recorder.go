@@ -0,0 +1,286 @@
+package matrixtest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sync"
+	"unicode/utf8"
+)
+
+const redacted = "REDACTED"
+
+// RecorderMode tells whether a Recorder talks to the homeserver or to its fixture.
+type RecorderMode int
+
+const (
+	// Replay answers the requests from the fixture without a network.
+	Replay RecorderMode = iota
+	// Record sends the requests to the homeserver and keeps the interactions to be saved to the fixture.
+	Record
+)
+
+var (
+	// the transaction IDs of the sends are random, so they don't take part in matching the requests
+	uuidRegexp = regexp.MustCompile(`[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+	// secretFields are the JSON fields whose values are redacted from the fixture
+	secretFields = map[string]bool{"access_token": true, "refresh_token": true, "password": true, "token": true}
+)
+
+type interaction struct {
+	Method      string      `json:"method"`
+	Path        string      `json:"path"`
+	Query       string      `json:"query,omitempty"`
+	RequestBody string      `json:"request_body,omitempty"`
+	StatusCode  int         `json:"status_code"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        string      `json:"body,omitempty"`
+	// Base64 tells the body isn't text and is base64-encoded
+	Base64 bool `json:"base64,omitempty"`
+
+	used bool
+}
+
+// Recorder is a VCR-style http.RoundTripper for the integration tests: it records the interactions with
+// a real homeserver to a fixture once, then replays them offline. The access tokens, refresh tokens and
+// passwords are redacted from the fixture, and the Authorization header is not kept.
+//
+//	rec, err := matrixtest.NewRecorder("testdata/send.json", matrixtest.Replay, nil)
+//	client, err := gomatrix.New(server, gomatrix.WithHTTPClient(&http.Client{Transport: rec}), ...)
+//	...
+//	err = rec.Save() // in the Record mode
+//
+// The requests are matched by the method, the path and the query, in the recorded order.
+type Recorder struct {
+	fixture   string
+	mode      RecorderMode
+	transport http.RoundTripper
+
+	mux          sync.Mutex
+	interactions []*interaction
+}
+
+// NewRecorder makes a recorder of the fixture file, loading it in the Replay mode. The transport
+// sends the recorded requests, http.DefaultTransport if nil.
+func NewRecorder(fixture string, mode RecorderMode, transport http.RoundTripper) (*Recorder, error) {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	r := &Recorder{fixture: fixture, mode: mode, transport: transport}
+	if mode == Record {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(fixture)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the fixture: %w", err)
+	}
+	err = json.Unmarshal(data, &r.interactions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the fixture: %w", err)
+	}
+	return r, nil
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(req.Body, req.Header.Get("Content-Encoding"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the request body: %w", err)
+	}
+
+	if r.mode == Replay {
+		return r.replay(req)
+	}
+
+	req = req.Clone(req.Context())
+	req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		// the body was decompressed to be recorded
+		req.Header.Del("Content-Encoding")
+		req.ContentLength = int64(len(reqBody))
+	}
+
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := readBody(resp.Body, resp.Header.Get("Content-Encoding"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the response body: %w", err)
+	}
+
+	header := resp.Header.Clone()
+	header.Del("Content-Encoding")
+	header.Del("Content-Length")
+
+	rec := &interaction{
+		Method:      req.Method,
+		Path:        req.URL.Path,
+		Query:       redactQuery(req.URL.Query()),
+		RequestBody: string(redactJSON(reqBody)),
+		StatusCode:  resp.StatusCode,
+		Header:      header,
+		used:        true,
+	}
+	if body := redactJSON(respBody); utf8.Valid(body) {
+		rec.Body = string(body)
+	} else {
+		rec.Body, rec.Base64 = base64.StdEncoding.EncodeToString(body), true
+	}
+
+	r.mux.Lock()
+	r.interactions = append(r.interactions, rec)
+	r.mux.Unlock()
+
+	// the caller gets the response as sent, not redacted
+	return newResponse(req, resp.StatusCode, header, respBody), nil
+}
+
+func (r *Recorder) replay(req *http.Request) (*http.Response, error) {
+	path := uuidRegexp.ReplaceAllString(req.URL.Path, "")
+	query := redactQuery(req.URL.Query())
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	for _, rec := range r.interactions {
+		if rec.used || rec.Method != req.Method || uuidRegexp.ReplaceAllString(rec.Path, "") != path || rec.Query != query {
+			continue
+		}
+		rec.used = true
+
+		body := []byte(rec.Body)
+		if rec.Base64 {
+			var err error
+			body, err = base64.StdEncoding.DecodeString(rec.Body)
+			if err != nil {
+				return nil, fmt.Errorf("invalid body of the recorded %s %s: %w", rec.Method, rec.Path, err)
+			}
+		}
+		return newResponse(req, rec.StatusCode, rec.Header.Clone(), body), nil
+	}
+	return nil, fmt.Errorf("no recorded interaction for %s %s", req.Method, req.URL.Path)
+}
+
+// Save writes the recorded interactions to the fixture; it does nothing in the Replay mode.
+func (r *Recorder) Save() error {
+	if r.mode == Replay {
+		return nil
+	}
+
+	r.mux.Lock()
+	data, err := json.MarshalIndent(r.interactions, "", "  ")
+	r.mux.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to marshal the interactions: %w", err)
+	}
+
+	err = os.WriteFile(r.fixture, append(data, '\n'), 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to write the fixture: %w", err)
+	}
+	return nil
+}
+
+// Unused returns the recorded requests the replay didn't get, e.g. to check a test made all of them.
+func (r *Recorder) Unused() []string {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	var unused []string
+	for _, rec := range r.interactions {
+		if !rec.used {
+			unused = append(unused, rec.Method+" "+rec.Path)
+		}
+	}
+	return unused
+}
+
+func newResponse(req *http.Request, statusCode int, header http.Header, body []byte) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		StatusCode:    statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+func readBody(body io.ReadCloser, contentEncoding string) ([]byte, error) {
+	if body == nil {
+		return nil, nil
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil || contentEncoding != "gzip" {
+		return data, err
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
+func redactQuery(query url.Values) string {
+	if query.Has("access_token") {
+		query.Set("access_token", redacted)
+	}
+	return query.Encode()
+}
+
+// redactJSON replaces the values of the secret fields at any depth; the other bodies are kept as is.
+func redactJSON(data []byte) []byte {
+	var v any
+	if len(data) == 0 || json.Unmarshal(data, &v) != nil {
+		return data
+	}
+
+	if !redactValue(v) {
+		return data
+	}
+	redactedData, err := json.Marshal(v)
+	if err != nil {
+		return data
+	}
+	return redactedData
+}
+
+func redactValue(v any) bool {
+	changed := false
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if _, ok := value.(string); ok && secretFields[key] {
+				v[key] = redacted
+				changed = true
+				continue
+			}
+			changed = redactValue(value) || changed
+		}
+	case []any:
+		for _, value := range v {
+			changed = redactValue(value) || changed
+		}
+	}
+	return changed
+}
+
+var _ http.RoundTripper = (*Recorder)(nil)
@@ -0,0 +1,327 @@
+package gomatrix
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+type UploadOptions struct {
+	// Filename is the name the uploaded file is stored with.
+	Filename string
+	// Progress is called whenever a chunk of the data is sent with the total number of bytes sent so far.
+	Progress func(sent, total int64)
+
+	// stripped is set once the image metadata was removed, so the data isn't read and stripped again.
+	stripped bool
+}
+
+// UploadStream uploads size bytes read from r and returns the content URI.
+// If r implements io.Seeker, the upload can be repeated after an expired token is refreshed.
+func (c *Client) UploadStream(
+	ctx context.Context, contentType string, r io.Reader, size int64, opts UploadOptions,
+) (MXCURI, error) {
+	var respData apiUploadResp
+	err := c.upload(ctx, http.MethodPost, "/_matrix/media/v3/upload", contentType, r, size, opts, &respData)
+	if err != nil {
+		return MXCURI{}, err
+	}
+	return respData.URI, nil
+}
+
+// CreateMedia reserves a content URI to be uploaded later with UploadStreamTo,
+// so the URI can be referenced before the upload completes.
+// The reservation expires if nothing is uploaded until the returned time.
+func (c *Client) CreateMedia(ctx context.Context) (MXCURI, time.Time, error) {
+	var respData apiCreateMediaResp
+	err := c.doJSON(ctx, http.MethodPost, "/_matrix/media/v1/create", struct{}{}, &respData)
+	if err != nil {
+		return MXCURI{}, time.Time{}, fmt.Errorf("failed to create media: %w", err)
+	}
+
+	var expiresAt time.Time
+	if respData.UnusedExpiresAt > 0 {
+		expiresAt = time.UnixMilli(respData.UnusedExpiresAt)
+	}
+
+	return respData.URI, expiresAt, nil
+}
+
+// UploadStreamTo uploads the content of the URI previously reserved by CreateMedia.
+func (c *Client) UploadStreamTo(
+	ctx context.Context, uri MXCURI, contentType string, r io.Reader, size int64, opts UploadOptions,
+) error {
+	if uri.IsEmpty() {
+		return errors.New("empty content URI")
+	}
+	if err := uri.Validate(); err != nil {
+		return err
+	}
+	return c.upload(ctx, http.MethodPut, "/_matrix/media/v3/upload/"+uri.path(), contentType, r, size, opts, nil)
+}
+
+// UploadAsync reserves a content URI and uploads the data in the background.
+// The URI is returned immediately, so a message referencing it can be sent while the upload is in progress.
+// The channel receives the result of the upload and is closed afterward.
+func (c *Client) UploadAsync(
+	ctx context.Context, contentType string, r io.Reader, size int64, opts UploadOptions,
+) (MXCURI, <-chan error, error) {
+	uri, _, err := c.CreateMedia(ctx)
+	if err != nil {
+		return MXCURI{}, nil, err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		defer close(done)
+		done <- c.UploadStreamTo(ctx, uri, contentType, r, size, opts)
+	}()
+
+	return uri, done, nil
+}
+
+func (c *Client) upload(
+	ctx context.Context, method, path, contentType string, r io.Reader, size int64, opts UploadOptions, respData any,
+) error {
+	if c.stripImageMeta && !opts.stripped && isStrippableImage(contentType) {
+		stripped, err := stripImageReader(r, size)
+		if err != nil {
+			return err
+		}
+		r, size = stripped, stripped.Size()
+	}
+
+	body := r
+	if opts.Progress != nil {
+		body = newProgressReader(r, size, opts.Progress)
+	}
+
+	if opts.Filename != "" {
+		path += "?" + url.Values{"filename": {opts.Filename}}.Encode()
+	}
+
+	resp, err := c.doBodyRequest(ctx, method, path, body, func(r *http.Request) {
+		r.Header.Set("Content-Type", contentType)
+		r.Header.Set("Content-Length", strconv.FormatInt(size, 10))
+		r.ContentLength = size
+	}, true)
+	if err != nil {
+		return fmt.Errorf("failed to upload a file: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if respData == nil {
+		return nil
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(respData)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal upload file response: %w", err)
+	}
+
+	return nil
+}
+
+type progressReader struct {
+	r     io.Reader
+	sent  int64
+	total int64
+	fn    func(sent, total int64)
+}
+
+func newProgressReader(r io.Reader, total int64, fn func(sent, total int64)) io.Reader {
+	pr := &progressReader{r: r, total: total, fn: fn}
+	if _, ok := r.(io.Seeker); ok {
+		return &seekableProgressReader{progressReader: pr}
+	}
+	return pr
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.sent += int64(n)
+		r.fn(r.sent, r.total)
+	}
+	return n, err
+}
+
+type seekableProgressReader struct {
+	*progressReader
+}
+
+func (r *seekableProgressReader) Seek(offset int64, whence int) (int64, error) {
+	pos, err := r.r.(io.Seeker).Seek(offset, whence)
+	if err != nil {
+		return pos, err
+	}
+	r.sent = pos
+	return pos, nil
+}
+
+const (
+	defaultUploadMaxRetries = 5
+	defaultUploadRetryDelay = 2 * time.Second
+)
+
+type ResumableUploadOptions struct {
+	UploadOptions
+	// MaxRetries is the number of attempts made after the first failed one, 5 by default.
+	MaxRetries int
+	// RetryDelay is the initial delay between the attempts which doubles after every failure, 2 seconds by default.
+	RetryDelay time.Duration
+}
+
+// UploadResumable uploads the data for unreliable links: the content URI is reserved upfront,
+// so it stays the same across the attempts, and the failed transfers are retried by re-streaming the data
+// from the checkpoint the source was positioned at when the upload started.
+// The Matrix media API does not accept partial content, so every attempt sends the data from the checkpoint.
+func (c *Client) UploadResumable(
+	ctx context.Context, contentType string, rs io.ReadSeeker, size int64, opts ResumableUploadOptions,
+) (MXCURI, error) {
+	if opts.MaxRetries < 0 {
+		opts.MaxRetries = 0
+	} else if opts.MaxRetries == 0 {
+		opts.MaxRetries = defaultUploadMaxRetries
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = defaultUploadRetryDelay
+	}
+
+	checkpoint, err := rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return MXCURI{}, fmt.Errorf("failed to get the upload checkpoint: %w", err)
+	}
+
+	uri, _, err := c.CreateMedia(ctx)
+	if err != nil {
+		return MXCURI{}, err
+	}
+
+	delay := opts.RetryDelay
+	for attempt := 0; ; attempt++ {
+		_, err = rs.Seek(checkpoint, io.SeekStart)
+		if err != nil {
+			return MXCURI{}, fmt.Errorf("failed to rewind to the upload checkpoint: %w", err)
+		}
+
+		err = c.UploadStreamTo(ctx, uri, contentType, rs, size, opts.UploadOptions)
+		if err == nil {
+			return uri, nil
+		}
+
+		var httpErr *HTTPError
+		if attempt > 0 && errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusConflict {
+			// the previous attempt has completed on the server although the response was lost
+			return uri, nil
+		}
+
+		if attempt >= opts.MaxRetries || !isTransient(err) || ctx.Err() != nil {
+			return MXCURI{}, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return MXCURI{}, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// Download fetches the content by its URI; the caller must close the returned body.
+func (c *Client) Download(ctx context.Context, uri MXCURI) (io.ReadCloser, string, error) {
+	if uri.IsEmpty() {
+		return nil, "", errors.New("empty content URI")
+	}
+	if err := uri.Validate(); err != nil {
+		return nil, "", err
+	}
+
+	resp, err := c.doRequest(ctx, http.MethodGet, "/_matrix/client/v1/media/download/"+uri.path(), nil, nil, true)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to download the content: %w", err)
+	}
+
+	return resp.Body, resp.Header.Get("Content-Type"), nil
+}
+
+// MediaInfo describes the media so the recipients can render a preview before downloading it.
+// Only the fields relevant to the media type are expected to be set, e.g. Duration for audio and video.
+// https://spec.matrix.org/v1.13/client-server-api/#mimage
+type MediaInfo struct {
+	MimeType      string
+	Size          int64
+	Width         int
+	Height        int
+	Duration      time.Duration
+	ThumbnailURI  MXCURI
+	ThumbnailInfo *ThumbnailInfo
+	BlurHash      string
+}
+
+type ThumbnailInfo struct {
+	MimeType string `json:"mimetype,omitempty"`
+	Size     int64  `json:"size,omitempty"`
+	Width    int    `json:"w,omitempty"`
+	Height   int    `json:"h,omitempty"`
+}
+
+func (i MediaInfo) MarshalJSON() ([]byte, error) {
+	return json.Marshal(apiMediaInfo{
+		MimeType:      i.MimeType,
+		Size:          i.Size,
+		Width:         i.Width,
+		Height:        i.Height,
+		Duration:      i.Duration.Milliseconds(),
+		ThumbnailURL:  i.ThumbnailURI.String(),
+		ThumbnailInfo: i.ThumbnailInfo,
+		BlurHash:      i.BlurHash,
+	})
+}
+
+func (i *MediaInfo) UnmarshalJSON(data []byte) error {
+	var info apiMediaInfo
+	err := json.Unmarshal(data, &info)
+	if err != nil {
+		return err
+	}
+
+	var thumbnailURI MXCURI
+	if info.ThumbnailURL != "" {
+		// a malformed thumbnail must not prevent the media itself from being handled
+		thumbnailURI, _ = ParseMXC(info.ThumbnailURL)
+	}
+
+	*i = MediaInfo{
+		MimeType:      info.MimeType,
+		Size:          info.Size,
+		Width:         info.Width,
+		Height:        info.Height,
+		Duration:      time.Duration(info.Duration) * time.Millisecond,
+		ThumbnailURI:  thumbnailURI,
+		ThumbnailInfo: info.ThumbnailInfo,
+		BlurHash:      info.BlurHash,
+	}
+	return nil
+}
+
+// stripImageReader reads the image and returns it without its metadata.
+func stripImageReader(r io.Reader, size int64) (*bytes.Reader, error) {
+	data, err := io.ReadAll(io.LimitReader(r, size))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the image: %w", err)
+	}
+	data, err = StripImageMetadata(data)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
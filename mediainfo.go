@@ -0,0 +1,303 @@
+package gomatrix
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// NewMediaFromFile uploads the file and returns the media ready to be sent, see NewMediaFromReader.
+func (c *Client) NewMediaFromFile(ctx context.Context, path string) (Media, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Media{}, fmt.Errorf("failed to open the media file: %w", err)
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return Media{}, fmt.Errorf("failed to stat the media file: %w", err)
+	}
+
+	return c.NewMediaFromReader(ctx, filepath.Base(path), f, stat.Size())
+}
+
+// NewMediaFromReader detects the mimetype, the image dimensions and the audio or video duration of the data,
+// uploads it and returns the media ready to be sent. The filename is used as the caption.
+// If the client has a ThumbnailEncoder configured, a thumbnail is generated and attached to images and videos.
+// Durations are detected for MP4/QuickTime and WAV containers only.
+func (c *Client) NewMediaFromReader(ctx context.Context, filename string, r io.ReadSeeker, size int64) (Media, error) {
+	info, err := DetectMediaInfo(filename, r)
+	if err != nil {
+		return Media{}, err
+	}
+
+	opts := UploadOptions{Filename: filename}
+	if c.stripImageMeta && isStrippableImage(info.MimeType) {
+		// strip upfront so the reported size matches the uploaded data
+		stripped, err := stripImageReader(r, size)
+		if err != nil {
+			return Media{}, err
+		}
+		r, size = stripped, stripped.Size()
+		opts.stripped = true
+	}
+	info.Size = size
+
+	start, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return Media{}, fmt.Errorf("failed to get the media position: %w", err)
+	}
+
+	uri, err := c.UploadStream(ctx, info.MimeType, r, size, opts)
+	if err != nil {
+		return Media{}, err
+	}
+
+	media := Media{
+		Type:     mediaTypeOf(info.MimeType),
+		Caption:  filename,
+		Filename: filename,
+		URI:      uri,
+		Info:     &info,
+	}
+
+	if c.thumbnailer != nil && (media.Type == Image || media.Type == Video) {
+		_, err = r.Seek(start, io.SeekStart)
+		if err != nil {
+			return Media{}, fmt.Errorf("failed to rewind the media: %w", err)
+		}
+
+		// the media is uploaded already, it's sent without a thumbnail rather than lost, e.g. for the videos
+		// ImageThumbnailer can't decode
+		err = c.AttachThumbnail(ctx, &media, io.LimitReader(r, size), c.thumbnailer, ThumbnailOptions{})
+		if err != nil {
+			c.logger.Warn("skipped the thumbnail of the media", "uri", uri.String(), "mime_type", info.MimeType, "error", err)
+		}
+	}
+
+	return media, nil
+}
+
+// DetectMediaInfo inspects the data and rewinds the reader back to where it was positioned.
+func DetectMediaInfo(filename string, r io.ReadSeeker) (MediaInfo, error) {
+	start, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return MediaInfo{}, fmt.Errorf("failed to get the media position: %w", err)
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return MediaInfo{}, fmt.Errorf("failed to read the media: %w", err)
+	}
+
+	info := MediaInfo{MimeType: detectMimeType(filename, head[:n])}
+
+	_, err = r.Seek(start, io.SeekStart)
+	if err != nil {
+		return MediaInfo{}, fmt.Errorf("failed to rewind the media: %w", err)
+	}
+
+	switch mediaTypeOf(info.MimeType) {
+	case Image:
+		cfg, _, err := image.DecodeConfig(r)
+		if err == nil {
+			info.Width, info.Height = cfg.Width, cfg.Height
+		}
+	case Audio, Video:
+		info.Duration, _ = detectDuration(r)
+	}
+
+	_, err = r.Seek(start, io.SeekStart)
+	if err != nil {
+		return MediaInfo{}, fmt.Errorf("failed to rewind the media: %w", err)
+	}
+
+	return info, nil
+}
+
+func detectMimeType(filename string, head []byte) string {
+	mimeType := http.DetectContentType(head)
+	if mimeType == "application/octet-stream" || strings.HasPrefix(mimeType, "text/plain") {
+		if byExt := mime.TypeByExtension(filepath.Ext(filename)); byExt != "" {
+			mimeType = byExt
+		}
+	}
+
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return mimeType
+	}
+	if strings.HasPrefix(mediaType, "text/") {
+		// keep the charset for text files
+		return mimeType
+	}
+	return mediaType
+}
+
+func mediaTypeOf(mimeType string) MediaType {
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		return Image
+	case strings.HasPrefix(mimeType, "audio/"):
+		return Audio
+	case strings.HasPrefix(mimeType, "video/"):
+		return Video
+	}
+	return File
+}
+
+func detectDuration(r io.Reader) (time.Duration, error) {
+	var header [12]byte
+	_, err := io.ReadFull(r, header[:])
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case bytes.Equal(header[0:4], []byte("RIFF")) && bytes.Equal(header[8:12], []byte("WAVE")):
+		return wavDuration(r)
+	case bytes.Equal(header[4:8], []byte("ftyp")):
+		// the ftyp box has already been partially read
+		size := int64(binary.BigEndian.Uint32(header[0:4]))
+		_, err = io.CopyN(io.Discard, r, size-int64(len(header)))
+		if err != nil {
+			return 0, err
+		}
+		return mp4Duration(r)
+	}
+
+	return 0, errors.New("unsupported container")
+}
+
+func wavDuration(r io.Reader) (time.Duration, error) {
+	var byteRate uint32
+	for {
+		var chunk [8]byte
+		_, err := io.ReadFull(r, chunk[:])
+		if err != nil {
+			return 0, err
+		}
+
+		id := string(chunk[0:4])
+		size := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+
+		switch id {
+		case "fmt ":
+			if size > 1<<10 {
+				return 0, errors.New("invalid wav format chunk")
+			}
+			fmtChunk := make([]byte, size)
+			_, err = io.ReadFull(r, fmtChunk)
+			if err != nil {
+				return 0, err
+			}
+			if len(fmtChunk) < 12 {
+				return 0, errors.New("invalid wav format chunk")
+			}
+			byteRate = binary.LittleEndian.Uint32(fmtChunk[8:12])
+		case "data":
+			if byteRate == 0 {
+				return 0, errors.New("wav data chunk precedes format chunk")
+			}
+			return time.Duration(size) * time.Second / time.Duration(byteRate), nil
+		default:
+			_, err = io.CopyN(io.Discard, r, size+size%2)
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+}
+
+func mp4Duration(r io.Reader) (time.Duration, error) {
+	for {
+		var box [8]byte
+		_, err := io.ReadFull(r, box[:])
+		if err != nil {
+			return 0, err
+		}
+
+		size := int64(binary.BigEndian.Uint32(box[0:4]))
+		boxType := string(box[4:8])
+		headerSize := int64(len(box))
+
+		if size == 1 {
+			var large [8]byte
+			_, err = io.ReadFull(r, large[:])
+			if err != nil {
+				return 0, err
+			}
+			size = int64(binary.BigEndian.Uint64(large[:]))
+			headerSize += int64(len(large))
+		}
+		if size != 0 && size < headerSize {
+			return 0, errors.New("invalid mp4 box size")
+		}
+
+		switch boxType {
+		case "moov":
+			// descend into the movie box
+			continue
+		case "mvhd":
+			return mvhdDuration(r)
+		}
+
+		if size == 0 {
+			return 0, errors.New("mp4 movie header not found")
+		}
+		_, err = io.CopyN(io.Discard, r, size-headerSize)
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+func mvhdDuration(r io.Reader) (time.Duration, error) {
+	var version [4]byte
+	_, err := io.ReadFull(r, version[:])
+	if err != nil {
+		return 0, err
+	}
+
+	var timescale uint32
+	var duration uint64
+	if version[0] == 1 {
+		var fields [28]byte
+		_, err = io.ReadFull(r, fields[:])
+		if err != nil {
+			return 0, err
+		}
+		timescale = binary.BigEndian.Uint32(fields[16:20])
+		duration = binary.BigEndian.Uint64(fields[20:28])
+	} else {
+		var fields [16]byte
+		_, err = io.ReadFull(r, fields[:])
+		if err != nil {
+			return 0, err
+		}
+		timescale = binary.BigEndian.Uint32(fields[8:12])
+		duration = uint64(binary.BigEndian.Uint32(fields[12:16]))
+	}
+
+	if timescale == 0 {
+		return 0, errors.New("invalid mp4 timescale")
+	}
+
+	ts := uint64(timescale)
+	return time.Duration(duration/ts)*time.Second + time.Duration(duration%ts)*time.Second/time.Duration(ts), nil
+}
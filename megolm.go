@@ -0,0 +1,90 @@
+package gomatrix
+
+import (
+	"slices"
+	"time"
+)
+
+// https://spec.matrix.org/v1.13/client-server-api/#mroomencryption
+const (
+	defaultRotationPeriod   = 7 * 24 * time.Hour
+	defaultRotationMessages = 100
+)
+
+type RotationReason string
+
+const (
+	RotationNone     RotationReason = ""
+	RotationAge      RotationReason = "age"
+	RotationMessages RotationReason = "messages"
+	RotationLeave    RotationReason = "leave"
+	RotationJoin     RotationReason = "join"
+)
+
+// RotationPolicy decides when an outbound Megolm session of a room must be replaced, so the keys
+// of the later messages aren't available to the devices that were not supposed to read them.
+// The library doesn't encrypt events itself; the policy is meant for the Megolm implementation plugged in.
+type RotationPolicy struct {
+	// MaxAge and MaxMessages make the sessions rotate sooner than the room settings require.
+	MaxAge      time.Duration
+	MaxMessages int
+	// KeepOnLeave keeps the session when a recipient user or device is gone, trading forward secrecy
+	// for fewer key shares; other clients rotate in this case.
+	KeepOnLeave bool
+	// RotateOnJoin rotates the session when new devices appear, hiding the earlier messages of the session from them.
+	RotateOnJoin bool
+}
+
+// OutboundSession describes an outbound Megolm session as tracked by the Megolm implementation.
+type OutboundSession struct {
+	CreatedAt    time.Time
+	MessageCount int
+	// SharedWith lists the devices the session key was shared with by user ID.
+	SharedWith map[string][]string
+}
+
+// ShouldRotate reports whether the session must be replaced before encrypting the next message to the recipients,
+// given the encryption settings of the room.
+func (p RotationPolicy) ShouldRotate(
+	session OutboundSession, room EncryptionContent, recipients map[string][]string, now time.Time,
+) (bool, RotationReason) {
+	maxAge := defaultRotationPeriod
+	if room.RotationPeriodMs > 0 {
+		maxAge = time.Duration(room.RotationPeriodMs) * time.Millisecond
+	}
+	if p.MaxAge > 0 {
+		maxAge = min(maxAge, p.MaxAge)
+	}
+
+	maxMessages := defaultRotationMessages
+	if room.RotationPeriodMsgs > 0 {
+		maxMessages = room.RotationPeriodMsgs
+	}
+	if p.MaxMessages > 0 {
+		maxMessages = min(maxMessages, p.MaxMessages)
+	}
+
+	switch {
+	case now.Sub(session.CreatedAt) >= maxAge:
+		return true, RotationAge
+	case session.MessageCount >= maxMessages:
+		return true, RotationMessages
+	case !p.KeepOnLeave && !containsDevices(recipients, session.SharedWith):
+		return true, RotationLeave
+	case p.RotateOnJoin && !containsDevices(session.SharedWith, recipients):
+		return true, RotationJoin
+	}
+	return false, RotationNone
+}
+
+// containsDevices reports whether all devices of b are in a.
+func containsDevices(a, b map[string][]string) bool {
+	for userID, devices := range b {
+		for _, deviceID := range devices {
+			if !slices.Contains(a[userID], deviceID) {
+				return false
+			}
+		}
+	}
+	return true
+}
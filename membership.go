@@ -0,0 +1,123 @@
+package gomatrix
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// MembershipChangeType tells how the membership of a user changed, as the clients render it in the timeline.
+type MembershipChangeType string
+
+const (
+	MemberJoined   MembershipChangeType = "joined"
+	MemberLeft     MembershipChangeType = "left"
+	MemberKicked   MembershipChangeType = "kicked"
+	MemberBanned   MembershipChangeType = "banned"
+	MemberUnbanned MembershipChangeType = "unbanned"
+	MemberInvited  MembershipChangeType = "invited"
+	// MemberInviteRejected is the invited user declining the invitation.
+	MemberInviteRejected MembershipChangeType = "invite_rejected"
+	// MemberInviteRevoked is another user withdrawing the invitation.
+	MemberInviteRevoked MembershipChangeType = "invite_revoked"
+	MemberKnocked       MembershipChangeType = "knocked"
+	// MemberProfileChanged is a joined user changing the display name or the avatar.
+	MemberProfileChanged MembershipChangeType = "profile_changed"
+)
+
+// MembershipChange is an m.room.member event told apart by the previous membership of the user.
+type MembershipChange struct {
+	Type   MembershipChangeType
+	RoomID string
+	// UserID is the user whose membership changed.
+	UserID string
+	// Sender is the user who changed it, e.g. the moderator who kicked or banned the user.
+	Sender         string
+	Reason         string
+	Membership     MemberContent
+	PrevMembership Membership
+	Event          Event
+}
+
+// MembershipChangeHandler receives the membership changes of the joined rooms.
+type MembershipChangeHandler func(ctx context.Context, change MembershipChange)
+
+// MembershipChange returns the membership change of an m.room.member event; ok is false for the other
+// events and for the member events changing nothing.
+func (e Event) MembershipChange() (change MembershipChange, ok bool) {
+	var member MemberContent
+	if e.Type != "m.room.member" || e.StateKey == nil || e.ParseContent(&member) != nil {
+		return MembershipChange{}, false
+	}
+
+	var unsigned struct {
+		PrevContent *MemberContent `json:"prev_content"`
+	}
+	_ = json.Unmarshal(e.Unsigned, &unsigned)
+	prev := MemberContent{Membership: MembershipLeave}
+	if unsigned.PrevContent != nil {
+		prev = *unsigned.PrevContent
+	}
+
+	change = MembershipChange{
+		RoomID:         e.RoomID,
+		UserID:         *e.StateKey,
+		Sender:         e.Sender,
+		Reason:         member.Reason,
+		Membership:     member,
+		PrevMembership: prev.Membership,
+		Event:          e,
+	}
+	selfChange := e.Sender == change.UserID
+
+	switch member.Membership {
+	case MembershipJoin:
+		change.Type = MemberJoined
+		if prev.Membership == MembershipJoin {
+			if prev.DisplayName == member.DisplayName && prev.AvatarURL == member.AvatarURL {
+				return MembershipChange{}, false
+			}
+			change.Type = MemberProfileChanged
+		}
+	case MembershipLeave:
+		switch {
+		case prev.Membership == MembershipBan:
+			change.Type = MemberUnbanned
+		case prev.Membership == MembershipInvite && selfChange:
+			change.Type = MemberInviteRejected
+		case prev.Membership == MembershipInvite:
+			change.Type = MemberInviteRevoked
+		case prev.Membership == MembershipLeave:
+			return MembershipChange{}, false
+		case selfChange:
+			change.Type = MemberLeft
+		default:
+			change.Type = MemberKicked
+		}
+	case MembershipBan:
+		change.Type = MemberBanned
+	case MembershipInvite:
+		change.Type = MemberInvited
+	case MembershipKnock:
+		change.Type = MemberKnocked
+	default:
+		return MembershipChange{}, false
+	}
+	return change, true
+}
+
+// dispatchMembershipChanges passes the membership changes of the timeline events to Config.OnMembershipChange.
+func (c *Client) dispatchMembershipChanges(ctx context.Context, roomID string, events []Event) {
+	if c.onMembership == nil {
+		return
+	}
+
+	for _, ev := range events {
+		if ev.Type != "m.room.member" {
+			continue
+		}
+		ev.RoomID = roomID
+		if change, ok := ev.MembershipChange(); ok {
+			c.onMembership(ctx, change)
+		}
+	}
+}
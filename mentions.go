@@ -0,0 +1,31 @@
+package gomatrix
+
+import (
+	"html"
+	"net/url"
+)
+
+// Mentions is the m.mentions content which tells the clients who should be notified about the event.
+// https://spec.matrix.org/v1.13/client-server-api/#user-and-room-mentions
+type Mentions struct {
+	UserIDs []string `json:"user_ids,omitempty"`
+	Room    bool     `json:"room,omitempty"`
+}
+
+func MatrixToURL(id string) string {
+	return "https://matrix.to/#/" + url.PathEscape(id)
+}
+
+// UserPill returns the HTML link that clients render as a mention pill of the user.
+// The user ID is displayed if the display name is empty.
+func UserPill(userID, displayName string) string {
+	if displayName == "" {
+		displayName = userID
+	}
+	return `<a href="` + html.EscapeString(MatrixToURL(userID)) + `">` + html.EscapeString(displayName) + `</a>`
+}
+
+// RoomPill returns the HTML link that clients render as a pill of the room alias or ID.
+func RoomPill(roomIDOrAlias string) string {
+	return `<a href="` + html.EscapeString(MatrixToURL(roomIDOrAlias)) + `">` + html.EscapeString(roomIDOrAlias) + `</a>`
+}
@@ -0,0 +1,90 @@
+package gomatrix
+
+import (
+	"context"
+	"errors"
+)
+
+type Message struct {
+	// Type is the type of a text message, m.text by default. It is ignored for media.
+	Type  MessageType
+	Text  string
+	HTML  string
+	Media *Media
+	// Mentions lists who should be notified; a non-nil empty value explicitly notifies nobody.
+	Mentions *Mentions
+	// ReplyTo is the ID of the event the message replies to.
+	ReplyTo string
+	// ThreadRoot is the ID of the thread root event the message is sent to.
+	ThreadRoot string
+}
+
+func (c *Client) Send(ctx context.Context, roomID string, msg Message) (string, error) {
+	req, err := msg.toAPI(roomID)
+	if err != nil {
+		return "", err
+	}
+	return c.sendMessage(ctx, req)
+}
+
+func (m Message) toAPI(roomID string) (apiSendMsgReq, error) {
+	req, err := m.contentToAPI(roomID)
+	if err != nil {
+		return apiSendMsgReq{}, err
+	}
+
+	req.Mentions = m.Mentions
+
+	switch {
+	case m.ThreadRoot != "":
+		req.RelatesTo = &Relation{Type: RelThread, EventID: m.ThreadRoot, IsFallingBack: m.ReplyTo == ""}
+		replyTo := m.ReplyTo
+		if replyTo == "" {
+			// the thread fallback lets clients without thread support render the message as a reply
+			replyTo = m.ThreadRoot
+		}
+		req.RelatesTo.InReplyTo = &InReplyTo{EventID: replyTo}
+	case m.ReplyTo != "":
+		req.RelatesTo = &Relation{InReplyTo: &InReplyTo{EventID: m.ReplyTo}}
+	}
+
+	return req, nil
+}
+
+func (m Message) contentToAPI(roomID string) (apiSendMsgReq, error) {
+	msgType := m.Type
+	if msgType == "" {
+		msgType = Text
+	}
+
+	switch {
+	case m.Media != nil:
+		return apiSendMsgReq{
+			RoomID:   roomID,
+			Type:     string(m.Media.Type),
+			Body:     m.Media.Caption,
+			Filename: m.Media.Filename,
+			URL:      m.Media.URI.String(),
+			Info:     m.Media.Info,
+		}, nil
+	case m.HTML != "":
+		body := m.Text
+		if body == "" {
+			body = m.HTML
+		}
+		return apiSendMsgReq{
+			RoomID:        roomID,
+			Type:          string(msgType),
+			Format:        "org.matrix.custom.html",
+			Body:          body,
+			FormattedBody: m.HTML,
+		}, nil
+	case m.Text != "":
+		return apiSendMsgReq{
+			RoomID: roomID,
+			Type:   string(msgType),
+			Body:   m.Text,
+		}, nil
+	}
+	return apiSendMsgReq{}, errors.New("empty message")
+}
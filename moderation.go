@@ -0,0 +1,108 @@
+package gomatrix
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// defaultRateLimitWait is how long the bulk operations wait when rate limited by a server not telling for how long.
+	defaultRateLimitWait = 5 * time.Second
+	// maxRateLimitRetries bounds the retries of every single request of the bulk operations.
+	maxRateLimitRetries = 10
+)
+
+// BanUsers bans the users from the room, e.g. the accounts of a spam attack. The rate limited bans are retried
+// when the server allows; the users failing to be banned are reported in the error while the others are banned.
+func (c *Client) BanUsers(ctx context.Context, roomID string, userIDs []string, reason string) error {
+	var errs []error
+	for _, userID := range userIDs {
+		err := retryRateLimited(ctx, func(ctx context.Context) error {
+			return c.Ban(ctx, roomID, userID, reason)
+		})
+		if ctx.Err() != nil {
+			return errors.Join(append(errs, ctx.Err())...)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", userID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RedactUserMessages redacts the events the user sent to the room since the given time, walking the history
+// back from the most recent event, and returns how many were redacted. The state events, e.g. the membership
+// of the user, are kept. The rate limited redactions are retried when the server allows, so a cleanup after
+// an attack may take a while; configure RateLimitConfig.RoomSend to pace it below the server limits.
+func (c *Client) RedactUserMessages(ctx context.Context, roomID, userID string, since time.Time) (int, error) {
+	var redacted int
+	opts := PaginationOptions{Dir: Backward, Limit: 100}
+	for {
+		var page MessagesPage
+		err := retryRateLimited(ctx, func(ctx context.Context) error {
+			var err error
+			page, err = c.GetMessages(ctx, roomID, opts)
+			return err
+		})
+		if err != nil {
+			return redacted, err
+		}
+
+		for _, ev := range page.Events {
+			if ev.OriginServerTS < since.UnixMilli() {
+				return redacted, nil
+			}
+			if ev.Sender != userID || ev.StateKey != nil || ev.Type == "m.room.redaction" || isRedacted(ev) {
+				continue
+			}
+
+			err := retryRateLimited(ctx, func(ctx context.Context) error {
+				_, err := c.Redact(ctx, roomID, ev.ID, "")
+				return err
+			})
+			if err != nil {
+				return redacted, err
+			}
+			redacted++
+		}
+
+		if page.End == "" || len(page.Events) == 0 {
+			return redacted, nil
+		}
+		opts.From = page.End
+	}
+}
+
+// isRedacted reports whether the event has already been redacted.
+func isRedacted(ev Event) bool {
+	var unsigned struct {
+		RedactedBecause json.RawMessage `json:"redacted_because"`
+	}
+	return json.Unmarshal(ev.Unsigned, &unsigned) == nil && unsigned.RedactedBecause != nil
+}
+
+// retryRateLimited retries the request rejected with 429 Too Many Requests after the time the server asks for.
+func retryRateLimited(ctx context.Context, request func(ctx context.Context) error) error {
+	for retry := 0; ; retry++ {
+		err := request(ctx)
+
+		var httpErr *HTTPError
+		if retry == maxRateLimitRetries || !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusTooManyRequests {
+			return err
+		}
+
+		wait := httpErr.RetryAfter
+		if wait <= 0 {
+			wait = defaultRateLimitWait
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
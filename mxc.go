@@ -0,0 +1,110 @@
+package gomatrix
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var mediaIDRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// MXCURI is a content URI of the form mxc://<server-name>/<media-id>.
+// The URIs of the received content are unmarshaled leniently, a malformed URI from another user failing
+// neither the event nor the request; it's kept as is and reported by Validate.
+// https://spec.matrix.org/v1.13/client-server-api/#matrix-content-mxc-uris
+type MXCURI struct {
+	serverName string
+	mediaID    string
+	// invalid is the malformed URI as received
+	invalid string
+}
+
+func ParseMXC(uri string) (MXCURI, error) {
+	rest, ok := strings.CutPrefix(uri, "mxc://")
+	if !ok {
+		return MXCURI{}, fmt.Errorf("invalid content URI %q: mxc scheme expected", uri)
+	}
+
+	serverName, mediaID, ok := strings.Cut(rest, "/")
+	if !ok || !validServerName(serverName) || !mediaIDRegexp.MatchString(mediaID) {
+		return MXCURI{}, fmt.Errorf("invalid content URI %q", uri)
+	}
+
+	return MXCURI{serverName: serverName, mediaID: mediaID}, nil
+}
+
+func (u MXCURI) ServerName() string {
+	return u.serverName
+}
+
+func (u MXCURI) MediaID() string {
+	return u.mediaID
+}
+
+func (u MXCURI) IsEmpty() bool {
+	return u.serverName == "" && u.mediaID == "" && u.invalid == ""
+}
+
+// Validate returns the error ParseMXC failed the URI with, nil for the valid and the empty URIs.
+func (u MXCURI) Validate() error {
+	if u.invalid == "" {
+		return nil
+	}
+	_, err := ParseMXC(u.invalid)
+	return err
+}
+
+func (u MXCURI) String() string {
+	if u.invalid != "" {
+		return u.invalid
+	}
+	if u.IsEmpty() {
+		return ""
+	}
+	return "mxc://" + u.serverName + "/" + u.mediaID
+}
+
+// DownloadURL returns the authenticated media download URL on the homeserver.
+// The request to the URL must carry the access token, see Client.Download.
+func (u MXCURI) DownloadURL(homeserver string) string {
+	return strings.TrimRight(homeserver, "/") + "/_matrix/client/v1/media/download/" + u.path()
+}
+
+func (u MXCURI) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+func (u *MXCURI) UnmarshalText(data []byte) error {
+	if len(data) == 0 {
+		*u = MXCURI{}
+		return nil
+	}
+
+	uri, err := ParseMXC(string(data))
+	if err != nil {
+		*u = MXCURI{invalid: string(data)}
+		return nil
+	}
+	*u = uri
+	return nil
+}
+
+func (u MXCURI) path() string {
+	return url.PathEscape(u.serverName) + "/" + url.PathEscape(u.mediaID)
+}
+
+func validServerName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '.' || r == '-' || r == ':' || r == '[' || r == ']':
+		default:
+			return false
+		}
+	}
+	return true
+}
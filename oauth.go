@@ -0,0 +1,443 @@
+package gomatrix
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// https://github.com/matrix-org/matrix-spec-proposals/pull/3861
+const (
+	oauthScopeAPI    = "urn:matrix:org.matrix.msc2967.client:api:*"
+	oauthScopeDevice = "urn:matrix:org.matrix.msc2967.client:device:"
+
+	grantDeviceCode = "urn:ietf:params:oauth:grant-type:device_code"
+
+	authMetadataTTL = time.Hour
+)
+
+// AuthMetadata describes the OAuth 2.0 authorization server of the homeserver.
+// https://github.com/matrix-org/matrix-spec-proposals/pull/2965
+type AuthMetadata struct {
+	Issuer                        string   `json:"issuer"`
+	AuthorizationEndpoint         string   `json:"authorization_endpoint"`
+	TokenEndpoint                 string   `json:"token_endpoint"`
+	RegistrationEndpoint          string   `json:"registration_endpoint,omitempty"`
+	DeviceAuthorizationEndpoint   string   `json:"device_authorization_endpoint,omitempty"`
+	RevocationEndpoint            string   `json:"revocation_endpoint,omitempty"`
+	ResponseTypesSupported        []string `json:"response_types_supported,omitempty"`
+	GrantTypesSupported           []string `json:"grant_types_supported,omitempty"`
+	CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported,omitempty"`
+	// AccountManagementURI is the web page the users manage their account at, e.g. to change the password.
+	AccountManagementURI string `json:"account_management_uri,omitempty"`
+	// AccountManagementActionsSupported are the actions AccountManagementURI accepts in the action parameter,
+	// e.g. org.matrix.sessions_list.
+	AccountManagementActionsSupported []string `json:"account_management_actions_supported,omitempty"`
+}
+
+type authMetadataCache struct {
+	mux       sync.Mutex
+	metadata  AuthMetadata
+	expiresAt time.Time
+}
+
+// OAuthClientMetadata is the client registered dynamically with the authorization server.
+// https://github.com/matrix-org/matrix-spec-proposals/pull/2966
+type OAuthClientMetadata struct {
+	ClientName   string   `json:"client_name,omitempty"`
+	ClientURI    string   `json:"client_uri"`
+	LogoURI      string   `json:"logo_uri,omitempty"`
+	PolicyURI    string   `json:"policy_uri,omitempty"`
+	TOSURI       string   `json:"tos_uri,omitempty"`
+	RedirectURIs []string `json:"redirect_uris,omitempty"`
+	// ApplicationType is native or web, native by default.
+	ApplicationType string   `json:"application_type,omitempty"`
+	GrantTypes      []string `json:"grant_types"`
+	ResponseTypes   []string `json:"response_types,omitempty"`
+	// TokenEndpointAuthMethod is none by default, i.e. a public client.
+	TokenEndpointAuthMethod string `json:"token_endpoint_auth_method"`
+}
+
+type OAuthToken struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn    int64  `json:"expires_in,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+	// DeviceID is the device the token is bound to, as requested in the scope.
+	DeviceID string `json:"-"`
+}
+
+// OAuthError is the error response of the authorization server, e.g. invalid_grant or access_denied.
+type OAuthError struct {
+	StatusCode  int
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func (e *OAuthError) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("oauth error %s: %s", e.Code, e.Description)
+	}
+	return "oauth error " + e.Code
+}
+
+// DeviceAuthorization is the pending device code login the user approves at VerificationURI with UserCode,
+// or directly at VerificationURIComplete.
+type DeviceAuthorization struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+	ExpiresIn               int64  `json:"expires_in"`
+	Interval                int64  `json:"interval,omitempty"`
+	// DeviceID is the Matrix device the login creates.
+	DeviceID string `json:"-"`
+}
+
+// AuthorizationRequest is the pending authorization code login; keep it until the user is redirected back.
+type AuthorizationRequest struct {
+	URL          string
+	RedirectURI  string
+	State        string
+	CodeVerifier string
+	DeviceID     string
+}
+
+type OAuthConfig struct {
+	// Server is the base URL of the homeserver.
+	Server     string
+	HttpClient *http.Client
+	// ClientID is the ID of the client registered before; RegisterClient registers a new one.
+	ClientID string
+}
+
+// OAuthClient logs in with the OAuth 2.0 authorization server of a homeserver using next-gen auth,
+// in place of the m.login.password login. Pass it in Credentials.OAuth to refresh the tokens.
+type OAuthClient struct {
+	httpClient *http.Client
+	clientID   string
+	metadata   AuthMetadata
+}
+
+// NewOAuthClient discovers the authorization server advertised by the homeserver.
+func NewOAuthClient(ctx context.Context, cfg OAuthConfig) (*OAuthClient, error) {
+	if cfg.HttpClient == nil {
+		cfg.HttpClient = &http.Client{Timeout: requestTimeout}
+	}
+	o := &OAuthClient{httpClient: cfg.HttpClient, clientID: cfg.ClientID}
+
+	metadata, err := o.discover(ctx, strings.TrimRight(cfg.Server, "/"))
+	if err != nil {
+		return nil, err
+	}
+	o.metadata = metadata
+	return o, nil
+}
+
+func (o *OAuthClient) discover(ctx context.Context, server string) (AuthMetadata, error) {
+	var metadata AuthMetadata
+	err := o.getJSON(ctx, server+"/_matrix/client/v1/auth_metadata", &metadata)
+	if IsNotFound(err) || ErrCode(err) == "M_UNRECOGNIZED" {
+		err = o.getJSON(ctx, server+"/_matrix/client/unstable/org.matrix.msc2965/auth_metadata", &metadata)
+	}
+	if err != nil {
+		return AuthMetadata{}, fmt.Errorf("failed to get the auth metadata: %w", err)
+	}
+	return metadata, nil
+}
+
+// GetAuthMetadata returns the OAuth 2.0 authorization server metadata advertised by the homeserver,
+// cached for an hour. The homeservers without next-gen auth fail with IsNotFound or M_UNRECOGNIZED.
+func (c *Client) GetAuthMetadata(ctx context.Context) (AuthMetadata, error) {
+	c.authMetadata.mux.Lock()
+	defer c.authMetadata.mux.Unlock()
+
+	if time.Now().Before(c.authMetadata.expiresAt) {
+		return c.authMetadata.metadata, nil
+	}
+
+	metadata, err := (&OAuthClient{httpClient: c.httpClient}).discover(ctx, c.Server())
+	if err != nil {
+		return AuthMetadata{}, err
+	}
+	c.authMetadata.metadata, c.authMetadata.expiresAt = metadata, time.Now().Add(authMetadataTTL)
+	return metadata, nil
+}
+
+func (o *OAuthClient) Metadata() AuthMetadata {
+	return o.metadata
+}
+
+func (o *OAuthClient) ClientID() string {
+	return o.clientID
+}
+
+// RegisterClient registers the client with the authorization server; keep the returned client ID
+// in OAuthConfig to reuse the registration.
+func (o *OAuthClient) RegisterClient(ctx context.Context, metadata OAuthClientMetadata) (string, error) {
+	if o.metadata.RegistrationEndpoint == "" {
+		return "", errors.New("the authorization server doesn't support client registration")
+	}
+	if metadata.ApplicationType == "" {
+		metadata.ApplicationType = "native"
+	}
+	if metadata.TokenEndpointAuthMethod == "" {
+		metadata.TokenEndpointAuthMethod = "none"
+	}
+	if metadata.GrantTypes == nil {
+		metadata.GrantTypes = []string{"authorization_code", "refresh_token", grantDeviceCode}
+	}
+	if metadata.ResponseTypes == nil && len(metadata.RedirectURIs) > 0 {
+		metadata.ResponseTypes = []string{"code"}
+	}
+
+	payload, err := json.Marshal(metadata)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal the client metadata: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.metadata.RegistrationEndpoint, bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("failed to create a request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var respData apiOAuthRegistrationResp
+	err = o.do(req, &respData)
+	if err != nil {
+		return "", fmt.Errorf("failed to register the client: %w", err)
+	}
+
+	o.clientID = respData.ClientID
+	return o.clientID, nil
+}
+
+// StartDeviceLogin starts the device authorization grant, fit for the clients without a browser.
+// A new device ID is generated if it's empty.
+// https://datatracker.ietf.org/doc/html/rfc8628
+func (o *OAuthClient) StartDeviceLogin(ctx context.Context, deviceID string) (DeviceAuthorization, error) {
+	if o.metadata.DeviceAuthorizationEndpoint == "" {
+		return DeviceAuthorization{}, errors.New("the authorization server doesn't support the device authorization grant")
+	}
+
+	deviceID, scope, err := oauthScope(deviceID)
+	if err != nil {
+		return DeviceAuthorization{}, err
+	}
+
+	var auth DeviceAuthorization
+	err = o.postForm(ctx, o.metadata.DeviceAuthorizationEndpoint, url.Values{
+		"client_id": {o.clientID},
+		"scope":     {scope},
+	}, &auth)
+	if err != nil {
+		return DeviceAuthorization{}, fmt.Errorf("failed to start the device authorization: %w", err)
+	}
+
+	auth.DeviceID = deviceID
+	return auth, nil
+}
+
+// WaitDeviceLogin polls the authorization server until the user approves or denies the device login,
+// or the device code expires if the server told when.
+func (o *OAuthClient) WaitDeviceLogin(ctx context.Context, auth DeviceAuthorization) (OAuthToken, error) {
+	interval := time.Duration(auth.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	// the server polled past the expiry answers expired_token anyway, the timeout only saves the useless polls
+	if auth.ExpiresIn > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(auth.ExpiresIn)*time.Second)
+		defer cancel()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return OAuthToken{}, ctx.Err()
+		case <-time.After(interval):
+		}
+
+		token, err := o.requestToken(ctx, url.Values{
+			"grant_type":  {grantDeviceCode},
+			"device_code": {auth.DeviceCode},
+			"client_id":   {o.clientID},
+		})
+
+		var oauthErr *OAuthError
+		switch {
+		case errors.As(err, &oauthErr) && oauthErr.Code == "authorization_pending":
+			continue
+		case errors.As(err, &oauthErr) && oauthErr.Code == "slow_down":
+			interval += 5 * time.Second
+			continue
+		case err != nil:
+			return OAuthToken{}, fmt.Errorf("failed to complete the device login: %w", err)
+		}
+
+		token.DeviceID = auth.DeviceID
+		return token, nil
+	}
+}
+
+// StartLogin prepares the authorization code grant with PKCE; send the user to the returned URL
+// and pass the code the redirect URI receives to FinishLogin. A new device ID is generated if it's empty.
+func (o *OAuthClient) StartLogin(redirectURI, deviceID string) (AuthorizationRequest, error) {
+	deviceID, scope, err := oauthScope(deviceID)
+	if err != nil {
+		return AuthorizationRequest{}, err
+	}
+	state, err := randomString(16)
+	if err != nil {
+		return AuthorizationRequest{}, err
+	}
+	verifier, err := randomString(48)
+	if err != nil {
+		return AuthorizationRequest{}, err
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"response_mode":         {"query"},
+		"client_id":             {o.clientID},
+		"redirect_uri":          {redirectURI},
+		"scope":                 {scope},
+		"state":                 {state},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	return AuthorizationRequest{
+		URL:          o.metadata.AuthorizationEndpoint + "?" + query.Encode(),
+		RedirectURI:  redirectURI,
+		State:        state,
+		CodeVerifier: verifier,
+		DeviceID:     deviceID,
+	}, nil
+}
+
+// FinishLogin exchanges the code for the tokens once the state returned with it is checked.
+func (o *OAuthClient) FinishLogin(ctx context.Context, req AuthorizationRequest, state, code string) (OAuthToken, error) {
+	if state != req.State {
+		return OAuthToken{}, errors.New("the authorization state doesn't match")
+	}
+
+	token, err := o.requestToken(ctx, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {req.RedirectURI},
+		"client_id":     {o.clientID},
+		"code_verifier": {req.CodeVerifier},
+	})
+	if err != nil {
+		return OAuthToken{}, fmt.Errorf("failed to exchange the authorization code: %w", err)
+	}
+
+	token.DeviceID = req.DeviceID
+	return token, nil
+}
+
+// Refresh exchanges the refresh token for a new access token; the response may rotate the refresh token too.
+func (o *OAuthClient) Refresh(ctx context.Context, refreshToken string) (OAuthToken, error) {
+	token, err := o.requestToken(ctx, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+		"client_id":     {o.clientID},
+	})
+	if err != nil {
+		return OAuthToken{}, fmt.Errorf("failed to refresh the token: %w", err)
+	}
+	if token.RefreshToken == "" {
+		token.RefreshToken = refreshToken
+	}
+	return token, nil
+}
+
+func (o *OAuthClient) requestToken(ctx context.Context, form url.Values) (OAuthToken, error) {
+	var token OAuthToken
+	err := o.postForm(ctx, o.metadata.TokenEndpoint, form, &token)
+	return token, err
+}
+
+func (o *OAuthClient) postForm(ctx context.Context, endpoint string, form url.Values, respData any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create a request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return o.do(req, respData)
+}
+
+func (o *OAuthClient) getJSON(ctx context.Context, endpoint string, respData any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create a request: %w", err)
+	}
+	return o.do(req, respData)
+}
+
+// do returns an *OAuthError for the errors of the authorization server and an *HTTPError for the other failures.
+func (o *OAuthClient) do(req *http.Request, respData any) error {
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to do a request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		respBody, _ := io.ReadAll(resp.Body)
+		oauthErr := &OAuthError{StatusCode: resp.StatusCode}
+		if json.Unmarshal(respBody, oauthErr) == nil && oauthErr.Code != "" {
+			return oauthErr
+		}
+		return NewHTTPError(resp, respBody)
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(respData)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return nil
+}
+
+// oauthScope requests the access to the client API bound to the device.
+func oauthScope(deviceID string) (string, string, error) {
+	if deviceID == "" {
+		var err error
+		deviceID, err = randomString(8)
+		if err != nil {
+			return "", "", err
+		}
+		deviceID = strings.ToUpper(deviceID)
+	}
+	return deviceID, oauthScopeAPI + " " + oauthScopeDevice + deviceID, nil
+}
+
+// randomString returns n random alphanumeric characters.
+func randomString(n int) (string, error) {
+	const alphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+	buf := make([]byte, n)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate random data: %w", err)
+	}
+	for i, b := range buf {
+		buf[i] = alphabet[int(b)%len(alphabet)]
+	}
+	return string(buf), nil
+}
@@ -0,0 +1,59 @@
+package gomatrix
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+// Option configures the client made by New.
+type Option func(cfg *Config)
+
+// New makes a client of the homeserver configured by the options; NewClientWithConfig takes the whole Config.
+func New(serverURL string, opts ...Option) (*Client, error) {
+	cfg := Config{Credentials: Credentials{Server: serverURL}}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return NewClientWithConfig(cfg)
+}
+
+// WithPassword logs in with the user and the password.
+func WithPassword(user, password string) Option {
+	return func(cfg *Config) {
+		cfg.Credentials.User = user
+		cfg.Credentials.Password = password
+	}
+}
+
+// WithToken uses the access token of the user obtained elsewhere instead of logging in.
+// The user ID may be empty to look it up with WhoAmI.
+func WithToken(userID, accessToken string) Option {
+	return func(cfg *Config) {
+		cfg.Credentials.User = userID
+		cfg.Credentials.AccessToken = accessToken
+	}
+}
+
+// WithSessionStorage keeps the session in the storage, so a restarted client doesn't log in again.
+func WithSessionStorage(storage SessionStorage) Option {
+	return func(cfg *Config) {
+		cfg.SessionStorage = storage
+	}
+}
+
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(cfg *Config) {
+		cfg.HttpClient = httpClient
+	}
+}
+
+func WithLogger(logger *slog.Logger) Option {
+	return func(cfg *Config) {
+		cfg.Logger = logger
+	}
+}
+
+// WithConfig sets the fields of Config having no option of their own.
+func WithConfig(fn func(cfg *Config)) Option {
+	return Option(fn)
+}
@@ -0,0 +1,128 @@
+package gomatrix
+
+import (
+	"context"
+	"slices"
+)
+
+// OneTimeKeyGenerator is the Olm account of the device, e.g. backed by libolm or vodozemac bindings.
+type OneTimeKeyGenerator interface {
+	// GenerateOneTimeKeys creates n curve25519 one-time keys signed by the device, keyed by <algorithm>:<key ID>.
+	GenerateOneTimeKeys(n int) (map[string]OneTimeKey, error)
+	// GenerateFallbackKey creates a signed curve25519 fallback key replacing the previous one.
+	GenerateFallbackKey() (keyID string, key OneTimeKey, err error)
+	// MarkKeysAsPublished is called once the generated keys are uploaded.
+	MarkKeysAsPublished() error
+	// MaxOneTimeKeys is the number of one-time keys the account can hold.
+	MaxOneTimeKeys() int
+}
+
+type OneTimeKeysConfig struct {
+	// Generator enables the replenishment of the one-time keys of the device.
+	Generator OneTimeKeyGenerator
+	// OnError is called when the keys fail to be generated or uploaded; they are retried after the next sync.
+	OnError func(err error)
+}
+
+// oneTimeKeyManager keeps half of the one-time keys the account can hold published on the server,
+// and a fallback key for when they run out, as reported by the syncs.
+type oneTimeKeyManager struct {
+	client  *Client
+	cfg     OneTimeKeysConfig
+	updates chan keyCounts
+}
+
+type keyCounts struct {
+	oneTimeKeys int
+	// fallbackUsed is true if the fallback key has been used or never uploaded
+	fallbackUsed bool
+}
+
+func newOneTimeKeyManager(client *Client, cfg OneTimeKeysConfig) *oneTimeKeyManager {
+	if cfg.Generator == nil {
+		return nil
+	}
+	return &oneTimeKeyManager{client: client, cfg: cfg, updates: make(chan keyCounts, 1)}
+}
+
+// update passes the counts to the background loop, replacing the ones it hasn't picked up yet.
+func (m *oneTimeKeyManager) update(resp *SyncResponse) {
+	if m == nil || resp.DeviceOneTimeKeysCount == nil && resp.DeviceUnusedFallbackKeyTypes == nil {
+		return
+	}
+
+	counts := keyCounts{oneTimeKeys: resp.DeviceOneTimeKeysCount[KeyAlgorithmSignedCurve25519]}
+	if resp.DeviceUnusedFallbackKeyTypes != nil {
+		counts.fallbackUsed = !slices.Contains(resp.DeviceUnusedFallbackKeyTypes, KeyAlgorithmSignedCurve25519)
+	}
+
+	select {
+	case <-m.updates:
+	default:
+	}
+	m.updates <- counts
+}
+
+func (m *oneTimeKeyManager) run(ctx context.Context) {
+	if m == nil {
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case counts := <-m.updates:
+			err := m.replenish(ctx, counts)
+			if err != nil && ctx.Err() == nil && m.cfg.OnError != nil {
+				m.cfg.OnError(err)
+			}
+		}
+	}
+}
+
+// flush replenishes the keys for the key counts not handled yet.
+func (m *oneTimeKeyManager) flush(ctx context.Context) error {
+	if m == nil {
+		return nil
+	}
+
+	select {
+	case counts := <-m.updates:
+		return m.replenish(ctx, counts)
+	default:
+		return nil
+	}
+}
+
+func (m *oneTimeKeyManager) replenish(ctx context.Context, counts keyCounts) error {
+	var req KeysUploadRequest
+
+	target := m.cfg.Generator.MaxOneTimeKeys() / 2
+	if counts.oneTimeKeys < target {
+		keys, err := m.cfg.Generator.GenerateOneTimeKeys(target - counts.oneTimeKeys)
+		if err != nil {
+			return err
+		}
+		req.OneTimeKeys = keys
+	}
+
+	if counts.fallbackUsed {
+		keyID, key, err := m.cfg.Generator.GenerateFallbackKey()
+		if err != nil {
+			return err
+		}
+		key.Fallback = true
+		req.FallbackKeys = map[string]OneTimeKey{keyID: key}
+	}
+
+	if req.OneTimeKeys == nil && req.FallbackKeys == nil {
+		return nil
+	}
+
+	_, err := m.client.UploadKeys(ctx, req)
+	if err != nil {
+		return err
+	}
+	return m.cfg.Generator.MarkKeysAsPublished()
+}
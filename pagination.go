@@ -0,0 +1,50 @@
+package gomatrix
+
+import (
+	"net/url"
+	"strconv"
+)
+
+type Direction string
+
+const (
+	Forward  Direction = "f"
+	Backward Direction = "b"
+)
+
+type PaginationOptions struct {
+	From  string
+	To    string
+	Limit int
+	Dir   Direction
+}
+
+type EventPage struct {
+	Events    []Event `json:"chunk"`
+	NextBatch string  `json:"next_batch,omitempty"`
+	PrevBatch string  `json:"prev_batch,omitempty"`
+}
+
+func (o PaginationOptions) query() url.Values {
+	query := url.Values{}
+	if o.From != "" {
+		query.Set("from", o.From)
+	}
+	if o.To != "" {
+		query.Set("to", o.To)
+	}
+	if o.Limit > 0 {
+		query.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.Dir != "" {
+		query.Set("dir", string(o.Dir))
+	}
+	return query
+}
+
+func withQuery(path string, query url.Values) string {
+	if len(query) == 0 {
+		return path
+	}
+	return path + "?" + query.Encode()
+}
@@ -0,0 +1,167 @@
+package gomatrix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	PrevPageKey = "⬅️"
+	NextPageKey = "➡️"
+)
+
+type Paginator struct {
+	client *Client
+	roomID string
+	pages  []string
+
+	mux         sync.Mutex
+	page        int
+	eventID     string
+	ownReaction map[string]struct{}
+}
+
+// SplitPages splits the text into pages no longer than maxLen bytes, preferring line boundaries.
+// The text is a single page if maxLen is not positive.
+func SplitPages(text string, maxLen int) []string {
+	if maxLen <= 0 {
+		return []string{text}
+	}
+
+	var pages []string
+	var page strings.Builder
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		for len(line) > maxLen {
+			if page.Len() > 0 {
+				pages = append(pages, page.String())
+				page.Reset()
+			}
+			cut := maxLen
+			for cut > 0 && !utf8.RuneStart(line[cut]) {
+				cut--
+			}
+			if cut == 0 {
+				cut = maxLen
+			}
+			pages = append(pages, line[:cut])
+			line = line[cut:]
+		}
+
+		if page.Len()+len(line) > maxLen {
+			pages = append(pages, page.String())
+			page.Reset()
+		}
+		page.WriteString(line)
+	}
+
+	if page.Len() > 0 || len(pages) == 0 {
+		pages = append(pages, page.String())
+	}
+
+	return pages
+}
+
+func (c *Client) SendPaginated(ctx context.Context, roomID string, pages []string) (*Paginator, error) {
+	if len(pages) == 0 {
+		return nil, errors.New("no pages to send")
+	}
+
+	p := &Paginator{
+		client:      c,
+		roomID:      roomID,
+		pages:       pages,
+		ownReaction: make(map[string]struct{}),
+	}
+
+	eventID, err := c.sendMessage(ctx, apiSendMsgReq{
+		RoomID: roomID,
+		Type:   string(Text),
+		Body:   p.render(0),
+	})
+	if err != nil {
+		return nil, err
+	}
+	p.eventID = eventID
+
+	if len(pages) == 1 {
+		return p, nil
+	}
+
+	for _, key := range []string{PrevPageKey, NextPageKey} {
+		reactionID, err := c.SendReaction(ctx, roomID, eventID, key)
+		if err != nil {
+			return nil, err
+		}
+		p.mux.Lock()
+		p.ownReaction[reactionID] = struct{}{}
+		p.mux.Unlock()
+	}
+
+	return p, nil
+}
+
+func (p *Paginator) EventID() string {
+	return p.eventID
+}
+
+func (p *Paginator) Page() int {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	return p.page
+}
+
+// HandleEvent turns the page if the event is a navigation reaction to the paginated message.
+// It reports whether the event was consumed by the paginator.
+func (p *Paginator) HandleEvent(ctx context.Context, ev Event) (bool, error) {
+	if ev.Type != "m.reaction" || ev.RoomID != "" && ev.RoomID != p.roomID {
+		return false, nil
+	}
+
+	rel, ok := ev.Relation()
+	if !ok || rel.Type != RelAnnotation || rel.EventID != p.eventID {
+		return false, nil
+	}
+
+	p.mux.Lock()
+	if _, ok := p.ownReaction[ev.ID]; ok {
+		p.mux.Unlock()
+		return true, nil
+	}
+
+	page := p.page
+	switch rel.Key {
+	case PrevPageKey:
+		page--
+	case NextPageKey:
+		page++
+	default:
+		p.mux.Unlock()
+		return false, nil
+	}
+
+	if page < 0 || page >= len(p.pages) {
+		p.mux.Unlock()
+		return true, nil
+	}
+	p.page = page
+	p.mux.Unlock()
+
+	_, err := p.client.EditText(ctx, p.roomID, p.eventID, p.render(page))
+	if err != nil {
+		return true, fmt.Errorf("failed to turn the page: %w", err)
+	}
+
+	return true, nil
+}
+
+func (p *Paginator) render(page int) string {
+	if len(p.pages) == 1 {
+		return p.pages[0]
+	}
+	return fmt.Sprintf("%s\n\n(%d/%d)", strings.TrimRight(p.pages[page], "\n"), page+1, len(p.pages))
+}
@@ -0,0 +1,66 @@
+package gomatrix
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// RoomPreview is the content of a room read without joining it.
+type RoomPreview struct {
+	State RoomState
+	// Events are the latest timeline events, oldest first.
+	Events []Event
+	// Start is the token to paginate further back with GetMessages.
+	Start string
+	// End is the token to follow the new events with PeekEvents.
+	End string
+}
+
+// PeekRoom reads the current state and up to limit latest events of a world-readable room without joining it.
+// Combined with a guest client (Credentials.Guest) it lets archive viewers render public rooms without an account.
+func (c *Client) PeekRoom(ctx context.Context, roomID string, limit int) (RoomPreview, error) {
+	state, err := c.GetRoomState(ctx, roomID)
+	if err != nil {
+		return RoomPreview{}, err
+	}
+
+	page, err := c.GetMessages(ctx, roomID, PaginationOptions{Dir: Backward, Limit: limit})
+	if err != nil {
+		return RoomPreview{}, err
+	}
+
+	events := slices.Clone(page.Events)
+	slices.Reverse(events)
+
+	// a backward page starts at the current end of the room timeline
+	return RoomPreview{State: state, Events: events, Start: page.End, End: page.Start}, nil
+}
+
+// PeekEvents long-polls for the events sent to a world-readable room after the from token.
+// https://spec.matrix.org/v1.13/client-server-api/#get_matrixclientv3events
+func (c *Client) PeekEvents(ctx context.Context, roomID, from string, timeout time.Duration) (MessagesPage, error) {
+	query := url.Values{}
+	query.Set("room_id", roomID)
+	if from != "" {
+		query.Set("from", from)
+	}
+	query.Set("timeout", strconv.FormatInt(timeout.Milliseconds(), 10))
+
+	var page MessagesPage
+	err := c.doJSON(ctx, http.MethodGet, withQuery("/_matrix/client/v3/events", query), nil, &page)
+	if err != nil {
+		return MessagesPage{}, fmt.Errorf("failed to peek the room events: %w", err)
+	}
+
+	for i := range page.Events {
+		if page.Events[i].RoomID == "" {
+			page.Events[i].RoomID = roomID
+		}
+	}
+	return page, nil
+}
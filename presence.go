@@ -0,0 +1,144 @@
+package gomatrix
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+type Presence string
+
+// https://spec.matrix.org/v1.13/client-server-api/#presence
+const (
+	PresenceOnline      Presence = "online"
+	PresenceOffline     Presence = "offline"
+	PresenceUnavailable Presence = "unavailable"
+)
+
+type PresenceContent struct {
+	Presence        Presence `json:"presence"`
+	StatusMsg       string   `json:"status_msg,omitempty"`
+	LastActiveAgo   int64    `json:"last_active_ago,omitempty"`
+	CurrentlyActive bool     `json:"currently_active,omitempty"`
+	DisplayName     string   `json:"displayname,omitempty"`
+	AvatarURL       MXCURI   `json:"avatar_url,omitempty"`
+}
+
+// UserPresence is the presence of a user as last reported by the server.
+type UserPresence struct {
+	Presence        Presence
+	StatusMsg       string
+	CurrentlyActive bool
+	// LastActive is when the user was last active, zero if unknown.
+	LastActive time.Time
+}
+
+// LastActiveAgo returns how long ago the user was last active, zero if unknown.
+func (p UserPresence) LastActiveAgo() time.Duration {
+	if p.LastActive.IsZero() {
+		return 0
+	}
+	return time.Since(p.LastActive)
+}
+
+func newUserPresence(content PresenceContent, receivedAt time.Time) UserPresence {
+	presence := UserPresence{
+		Presence:        content.Presence,
+		StatusMsg:       content.StatusMsg,
+		CurrentlyActive: content.CurrentlyActive,
+	}
+	if content.LastActiveAgo > 0 || content.CurrentlyActive {
+		presence.LastActive = receivedAt.Add(-time.Duration(content.LastActiveAgo) * time.Millisecond)
+	}
+	return presence
+}
+
+// SetPresence sets the presence and the status message of the current user.
+func (c *Client) SetPresence(ctx context.Context, presence Presence, statusMsg string) error {
+	path, err := c.presencePath(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	err = c.doJSON(ctx, http.MethodPut, path, apiSetPresenceReq{Presence: presence, StatusMsg: statusMsg}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to set the presence: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) GetPresence(ctx context.Context, userID string) (UserPresence, error) {
+	path, err := c.presencePath(ctx, userID)
+	if err != nil {
+		return UserPresence{}, err
+	}
+
+	var content PresenceContent
+	err = c.doJSON(ctx, http.MethodGet, path, nil, &content)
+	if err != nil {
+		return UserPresence{}, fmt.Errorf("failed to get the presence: %w", err)
+	}
+	return newUserPresence(content, time.Now()), nil
+}
+
+func (c *Client) presencePath(ctx context.Context, userID string) (string, error) {
+	if userID == "" {
+		var err error
+		userID, err = c.WhoAmI(ctx)
+		if err != nil {
+			return "", err
+		}
+	}
+	return fmt.Sprintf("/_matrix/client/v3/presence/%s/status", url.PathEscape(userID)), nil
+}
+
+// PresenceStore keeps the latest presence of the users, fed by Listen from the sync presence updates.
+type PresenceStore struct {
+	mux   sync.RWMutex
+	users map[string]UserPresence
+}
+
+func NewPresenceStore() *PresenceStore {
+	return &PresenceStore{users: make(map[string]UserPresence)}
+}
+
+func (s *PresenceStore) Get(userID string) (UserPresence, bool) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	presence, ok := s.users[userID]
+	return presence, ok
+}
+
+// Online returns the IDs of the users currently online.
+func (s *PresenceStore) Online() []string {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	var userIDs []string
+	for userID, presence := range s.users {
+		if presence.Presence == PresenceOnline {
+			userIDs = append(userIDs, userID)
+		}
+	}
+	return userIDs
+}
+
+// Update applies the m.presence events; the other events are ignored.
+func (s *PresenceStore) Update(events []Event) {
+	now := time.Now()
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	for _, ev := range events {
+		var content PresenceContent
+		if ev.Type != "m.presence" || ev.ParseContent(&content) != nil {
+			continue
+		}
+		s.users[ev.Sender] = newUserPresence(content, now)
+	}
+}
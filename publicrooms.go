@@ -0,0 +1,46 @@
+package gomatrix
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+type PublicRoomsOptions struct {
+	// Server is the server whose directory is listed, the homeserver by default.
+	Server string
+	// Filter keeps the rooms whose name, topic or alias contain the term.
+	Filter string
+	Limit  int
+	// Since is the NextBatch or PrevBatch token of a previous page.
+	Since string
+}
+
+type PublicRoomsPage struct {
+	Rooms                  []PublicRoomSummary `json:"chunk"`
+	NextBatch              string              `json:"next_batch,omitempty"`
+	PrevBatch              string              `json:"prev_batch,omitempty"`
+	TotalRoomCountEstimate int                 `json:"total_room_count_estimate,omitempty"`
+}
+
+// GetPublicRooms returns a page of the room directory; PublicRooms iterates over all of them.
+// https://spec.matrix.org/v1.13/client-server-api/#post_matrixclientv3publicrooms
+func (c *Client) GetPublicRooms(ctx context.Context, opts PublicRoomsOptions) (PublicRoomsPage, error) {
+	path := "/_matrix/client/v3/publicRooms"
+	if opts.Server != "" {
+		path = withQuery(path, url.Values{"server": {opts.Server}})
+	}
+
+	reqData := apiPublicRoomsReq{Limit: opts.Limit, Since: opts.Since}
+	if opts.Filter != "" {
+		reqData.Filter = &apiPublicRoomsFilter{GenericSearchTerm: opts.Filter}
+	}
+
+	var page PublicRoomsPage
+	err := c.doJSON(ctx, http.MethodPost, path, reqData, &page)
+	if err != nil {
+		return PublicRoomsPage{}, fmt.Errorf("failed to get the public rooms: %w", err)
+	}
+	return page, nil
+}
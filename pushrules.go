@@ -0,0 +1,234 @@
+package gomatrix
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// https://spec.matrix.org/v1.13/client-server-api/#push-rules
+type PushRules struct {
+	Global PushRuleset `json:"global"`
+}
+
+type PushRuleset struct {
+	Override  []PushRule `json:"override,omitempty"`
+	Content   []PushRule `json:"content,omitempty"`
+	Room      []PushRule `json:"room,omitempty"`
+	Sender    []PushRule `json:"sender,omitempty"`
+	Underride []PushRule `json:"underride,omitempty"`
+}
+
+type PushRule struct {
+	RuleID     string          `json:"rule_id"`
+	Default    bool            `json:"default"`
+	Enabled    bool            `json:"enabled"`
+	Actions    []any           `json:"actions"`
+	Conditions []PushCondition `json:"conditions,omitempty"`
+	// Pattern is the glob the message body is matched against by the content rules.
+	Pattern string `json:"pattern,omitempty"`
+}
+
+type PushCondition struct {
+	Kind    string `json:"kind"`
+	Key     string `json:"key,omitempty"`
+	Pattern string `json:"pattern,omitempty"`
+	Is      string `json:"is,omitempty"`
+	Value   any    `json:"value,omitempty"`
+}
+
+// PushActions are the actions of the matched rule with the tweaks applied.
+type PushActions struct {
+	Notify    bool
+	Highlight bool
+	// Sound is the sound to play, empty for none.
+	Sound string
+}
+
+func (r PushRule) PushActions() PushActions {
+	var actions PushActions
+	for _, action := range r.Actions {
+		switch action := action.(type) {
+		case string:
+			actions.Notify = actions.Notify || action == "notify"
+		case map[string]any:
+			switch action["set_tweak"] {
+			case "highlight":
+				highlight, ok := action["value"].(bool)
+				actions.Highlight = highlight || !ok
+			case "sound":
+				actions.Sound, _ = action["value"].(string)
+			}
+		}
+	}
+	return actions
+}
+
+func (c *Client) GetPushRules(ctx context.Context) (PushRules, error) {
+	var rules PushRules
+	err := c.doJSON(ctx, http.MethodGet, "/_matrix/client/v3/pushrules/", nil, &rules)
+	if err != nil {
+		return PushRules{}, fmt.Errorf("failed to get the push rules: %w", err)
+	}
+	return rules, nil
+}
+
+// PushRoomContext describes the room the evaluated event belongs to.
+type PushRoomContext struct {
+	MemberCount int
+	PowerLevels PowerLevelsContent
+}
+
+// PushEvaluator decides locally which events should alert the user the way the homeserver would.
+type PushEvaluator struct {
+	Rules  PushRuleset
+	UserID string
+	// DisplayName is the display name of the user in the room, matched by the contains_display_name condition.
+	DisplayName string
+}
+
+// Evaluate returns the first enabled rule matching the event; ok is false if none does
+// or the event was sent by the user.
+func (e PushEvaluator) Evaluate(ev Event, room PushRoomContext) (rule PushRule, ok bool) {
+	if ev.Sender == e.UserID {
+		return PushRule{}, false
+	}
+
+	var flat any
+	data, err := json.Marshal(ev)
+	if err != nil || json.Unmarshal(data, &flat) != nil {
+		return PushRule{}, false
+	}
+
+	kinds := []struct {
+		rules []PushRule
+		match func(PushRule) bool
+	}{
+		{e.Rules.Override, func(r PushRule) bool { return e.matchConditions(r, flat, ev, room) }},
+		{e.Rules.Content, func(r PushRule) bool {
+			body, _ := lookupPushKey(flat, "content.body").(string)
+			return matchGlob(r.Pattern, body, true)
+		}},
+		{e.Rules.Room, func(r PushRule) bool { return r.RuleID == ev.RoomID }},
+		{e.Rules.Sender, func(r PushRule) bool { return r.RuleID == ev.Sender }},
+		{e.Rules.Underride, func(r PushRule) bool { return e.matchConditions(r, flat, ev, room) }},
+	}
+
+	for _, kind := range kinds {
+		for _, rule := range kind.rules {
+			if rule.Enabled && kind.match(rule) {
+				return rule, true
+			}
+		}
+	}
+	return PushRule{}, false
+}
+
+func (e PushEvaluator) matchConditions(rule PushRule, flat any, ev Event, room PushRoomContext) bool {
+	for _, cond := range rule.Conditions {
+		if !e.matchCondition(cond, flat, ev, room) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e PushEvaluator) matchCondition(cond PushCondition, flat any, ev Event, room PushRoomContext) bool {
+	switch cond.Kind {
+	case "event_match":
+		value, ok := lookupPushKey(flat, cond.Key).(string)
+		return ok && matchGlob(cond.Pattern, value, false)
+	case "event_property_is":
+		return reflect.DeepEqual(lookupPushKey(flat, cond.Key), cond.Value)
+	case "event_property_contains":
+		values, _ := lookupPushKey(flat, cond.Key).([]any)
+		return slices.ContainsFunc(values, func(v any) bool { return reflect.DeepEqual(v, cond.Value) })
+	case "contains_display_name":
+		body, _ := lookupPushKey(flat, "content.body").(string)
+		return e.DisplayName != "" && matchExpr(regexp.QuoteMeta(e.DisplayName), body, true)
+	case "room_member_count":
+		return matchMemberCount(cond.Is, room.MemberCount)
+	case "sender_notification_permission":
+		required, ok := room.PowerLevels.Notifications[cond.Key]
+		if !ok {
+			required = 50
+		}
+		return room.PowerLevels.UserLevel(ev.Sender) >= required
+	}
+	// unknown conditions never match
+	return false
+}
+
+// lookupPushKey resolves a dot-separated key, where literal dots and backslashes are escaped with a backslash.
+func lookupPushKey(flat any, key string) any {
+	var parts []string
+	var part strings.Builder
+	for i := 0; i < len(key); i++ {
+		switch {
+		case key[i] == '\\' && i+1 < len(key) && (key[i+1] == '.' || key[i+1] == '\\'):
+			i++
+			part.WriteByte(key[i])
+		case key[i] == '.':
+			parts = append(parts, part.String())
+			part.Reset()
+		default:
+			part.WriteByte(key[i])
+		}
+	}
+	parts = append(parts, part.String())
+
+	value := flat
+	for _, name := range parts {
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = obj[name]
+	}
+	return value
+}
+
+// matchGlob matches the case-insensitive glob with * and ? wildcards against the whole value,
+// or against any whole words of it.
+func matchGlob(glob, value string, words bool) bool {
+	return matchExpr(strings.NewReplacer(`\*`, `.*?`, `\?`, `.`).Replace(regexp.QuoteMeta(glob)), value, words)
+}
+
+func matchExpr(expr, value string, words bool) bool {
+	if words {
+		expr = `(?:^|[^\p{L}\p{N}_])` + expr + `(?:[^\p{L}\p{N}_]|$)`
+	} else {
+		expr = `^` + expr + `$`
+	}
+
+	re, err := regexp.Compile(`(?is)` + expr)
+	return err == nil && re.MatchString(value)
+}
+
+func matchMemberCount(is string, count int) bool {
+	op := strings.TrimRight(is, "0123456789")
+	n, err := strconv.Atoi(is[len(op):])
+	if err != nil {
+		return false
+	}
+
+	switch op {
+	case "", "==":
+		return count == n
+	case "<":
+		return count < n
+	case ">":
+		return count > n
+	case "<=":
+		return count <= n
+	case ">=":
+		return count >= n
+	}
+	return false
+}
@@ -0,0 +1,522 @@
+package gomatrix
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// https://github.com/matrix-org/matrix-spec-proposals/pull/4108
+const (
+	qrLoginPrefix  = "MATRIX"
+	qrLoginVersion = 0x02
+
+	qrLoginInitiate = "MATRIX_QR_CODE_LOGIN_INITIATE"
+	qrLoginOK       = "MATRIX_QR_CODE_LOGIN_OK"
+
+	qrLoginProtocol = "device_authorization_grant"
+
+	rendezvousPollInterval = time.Second
+)
+
+type QRLoginIntent byte
+
+const (
+	// QRLoginIntentLogin is shown by the new device to be scanned by a device already logged in.
+	QRLoginIntentLogin QRLoginIntent = 0x00
+	// QRLoginIntentReciprocate is shown by a device already logged in to be scanned by the new device.
+	QRLoginIntentReciprocate QRLoginIntent = 0x01
+)
+
+// QRLoginCode is the content of the QR code linking two devices through a rendezvous session.
+type QRLoginCode struct {
+	Intent QRLoginIntent
+	// PublicKey is the ephemeral curve25519 key of the secure channel.
+	PublicKey     []byte
+	RendezvousURL string
+	// ServerName is the homeserver of the device logged in, only set with QRLoginIntentReciprocate.
+	ServerName string
+}
+
+func (q QRLoginCode) Bytes() []byte {
+	var buf bytes.Buffer
+	buf.WriteString(qrLoginPrefix)
+	buf.WriteByte(qrLoginVersion)
+	buf.WriteByte(byte(q.Intent))
+	buf.Write(q.PublicKey)
+	_ = binary.Write(&buf, binary.BigEndian, uint16(len(q.RendezvousURL)))
+	buf.WriteString(q.RendezvousURL)
+	if q.Intent == QRLoginIntentReciprocate {
+		_ = binary.Write(&buf, binary.BigEndian, uint16(len(q.ServerName)))
+		buf.WriteString(q.ServerName)
+	}
+	return buf.Bytes()
+}
+
+func ParseQRLoginCode(data []byte) (QRLoginCode, error) {
+	r := bytes.NewReader(data)
+
+	header := make([]byte, len(qrLoginPrefix)+2)
+	if _, err := io.ReadFull(r, header); err != nil || string(header[:len(qrLoginPrefix)]) != qrLoginPrefix {
+		return QRLoginCode{}, errors.New("not a login QR code")
+	}
+	if header[len(qrLoginPrefix)] != qrLoginVersion {
+		return QRLoginCode{}, fmt.Errorf("unsupported login QR code version %d", header[len(qrLoginPrefix)])
+	}
+
+	code := QRLoginCode{Intent: QRLoginIntent(header[len(qrLoginPrefix)+1]), PublicKey: make([]byte, 32)}
+	if code.Intent != QRLoginIntentLogin && code.Intent != QRLoginIntentReciprocate {
+		return QRLoginCode{}, fmt.Errorf("unknown login QR code intent %d", code.Intent)
+	}
+	if _, err := io.ReadFull(r, code.PublicKey); err != nil {
+		return QRLoginCode{}, errors.New("truncated login QR code")
+	}
+
+	var err error
+	code.RendezvousURL, err = readQRLoginString(r)
+	if err == nil && code.Intent == QRLoginIntentReciprocate {
+		code.ServerName, err = readQRLoginString(r)
+	}
+	if err != nil {
+		return QRLoginCode{}, err
+	}
+	return code, nil
+}
+
+func readQRLoginString(r io.Reader) (string, error) {
+	var n uint16
+	err := binary.Read(r, binary.BigEndian, &n)
+	if err != nil {
+		return "", errors.New("truncated login QR code")
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", errors.New("truncated login QR code")
+	}
+	return string(buf), nil
+}
+
+// ECIES creates the secure channel of the QR login, e.g. backed by vodozemac bindings.
+type ECIES interface {
+	// PublicKey is the ephemeral curve25519 public key shown in the QR code.
+	PublicKey() []byte
+	// EstablishOutbound opens the channel to the device showing the QR code, encrypting the plaintext
+	// in the initial message.
+	EstablishOutbound(theirKey, plaintext []byte) (SecureChannel, []byte, error)
+	// EstablishInbound opens the channel from the initial message of the device scanning the QR code
+	// and returns its plaintext.
+	EstablishInbound(message []byte) (SecureChannel, []byte, error)
+}
+
+// SecureChannel is an established ECIES channel; the messages are the base64 strings exchanged in the rendezvous session.
+type SecureChannel interface {
+	Encrypt(plaintext []byte) ([]byte, error)
+	Decrypt(message []byte) ([]byte, error)
+	// CheckCode is the two digit code the users compare to detect a man-in-the-middle.
+	CheckCode() string
+}
+
+type QRLoginConfig struct {
+	// Server is the base URL of the homeserver hosting the rendezvous session of a generated QR code.
+	Server     string
+	HttpClient *http.Client
+	Channel    ECIES
+	// ClientID is the OAuth client registered before; a new one is registered with ClientMetadata otherwise.
+	ClientID       string
+	ClientMetadata OAuthClientMetadata
+	// DeviceID is the ID of the new device, generated if it's empty.
+	DeviceID string
+	// OnCheckCode is called with the check code once the secure channel is established.
+	OnCheckCode func(code string)
+}
+
+type QRLoginCrossSigning struct {
+	MasterKey      string `json:"master_key,omitempty"`
+	SelfSigningKey string `json:"self_signing_key,omitempty"`
+	UserSigningKey string `json:"user_signing_key,omitempty"`
+}
+
+type QRLoginBackup struct {
+	Algorithm     string `json:"algorithm"`
+	Key           string `json:"key"`
+	BackupVersion string `json:"backup_version"`
+}
+
+// QRLoginSecrets are the unpadded base64 private keys shared by the device already logged in.
+type QRLoginSecrets struct {
+	CrossSigning *QRLoginCrossSigning
+	Backup       *QRLoginBackup
+}
+
+// QRLoginResult holds the login of the new device; pass OAuth and Token in Credentials.OAuth to create its client.
+type QRLoginResult struct {
+	Homeserver string
+	OAuth      *OAuthClient
+	Token      OAuthToken
+	Secrets    QRLoginSecrets
+}
+
+// QRLoginError is reported when the other device declines or fails the login.
+type QRLoginError struct {
+	Type   string
+	Reason string
+}
+
+func (e *QRLoginError) Error() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("qr login %s: %s", e.Type, e.Reason)
+	}
+	return "qr login " + e.Type
+}
+
+// LoginWithQR signs the new device in from a device already logged in that scans the QR code passed to show.
+// The QR code is valid while LoginWithQR waits.
+func LoginWithQR(ctx context.Context, cfg QRLoginConfig, show func(code QRLoginCode) error) (QRLoginResult, error) {
+	if cfg.Channel == nil {
+		return QRLoginResult{}, errors.New("no secure channel")
+	}
+	cfg = cfg.withDefaults()
+
+	session, err := createRendezvous(ctx, cfg.HttpClient, cfg.Server)
+	if err != nil {
+		return QRLoginResult{}, err
+	}
+	defer session.delete()
+
+	err = show(QRLoginCode{Intent: QRLoginIntentLogin, PublicKey: cfg.Channel.PublicKey(), RendezvousURL: session.url})
+	if err != nil {
+		return QRLoginResult{}, err
+	}
+
+	initial, err := session.receive(ctx)
+	if err != nil {
+		return QRLoginResult{}, err
+	}
+	channel, plaintext, err := cfg.Channel.EstablishInbound(initial)
+	if err != nil {
+		return QRLoginResult{}, fmt.Errorf("failed to establish the secure channel: %w", err)
+	}
+	if string(plaintext) != qrLoginInitiate {
+		return QRLoginResult{}, errors.New("unexpected secure channel initial message")
+	}
+
+	login := &qrLogin{cfg: cfg, session: session, channel: channel}
+	err = login.sendRaw(ctx, []byte(qrLoginOK))
+	if err != nil {
+		return QRLoginResult{}, err
+	}
+	return login.run(ctx)
+}
+
+// LoginWithScannedQR signs the new device in from the QR code shown by a device already logged in.
+func LoginWithScannedQR(ctx context.Context, cfg QRLoginConfig, code QRLoginCode) (QRLoginResult, error) {
+	if cfg.Channel == nil {
+		return QRLoginResult{}, errors.New("no secure channel")
+	}
+	if code.Intent != QRLoginIntentReciprocate {
+		return QRLoginResult{}, errors.New("the QR code is not shown by a device logged in")
+	}
+	if cfg.Server == "" {
+		cfg.Server = "https://" + code.ServerName
+	}
+	cfg = cfg.withDefaults()
+
+	// the session belongs to the other device, which deletes it
+	session := &rendezvousSession{httpClient: cfg.HttpClient, url: code.RendezvousURL}
+
+	// the current ETag of the session is needed to write to it
+	_, _, err := session.poll(ctx)
+	if err != nil {
+		return QRLoginResult{}, err
+	}
+
+	channel, initial, err := cfg.Channel.EstablishOutbound(code.PublicKey, []byte(qrLoginInitiate))
+	if err != nil {
+		return QRLoginResult{}, fmt.Errorf("failed to establish the secure channel: %w", err)
+	}
+	err = session.send(ctx, initial)
+	if err != nil {
+		return QRLoginResult{}, err
+	}
+
+	login := &qrLogin{cfg: cfg, session: session, channel: channel}
+	reply, err := login.receiveRaw(ctx)
+	if err != nil {
+		return QRLoginResult{}, err
+	}
+	if string(reply) != qrLoginOK {
+		return QRLoginResult{}, errors.New("unexpected secure channel confirmation")
+	}
+	return login.run(ctx)
+}
+
+func (cfg QRLoginConfig) withDefaults() QRLoginConfig {
+	if cfg.HttpClient == nil {
+		cfg.HttpClient = &http.Client{Timeout: requestTimeout}
+	}
+	cfg.Server = strings.TrimRight(cfg.Server, "/")
+	return cfg
+}
+
+type qrLogin struct {
+	cfg     QRLoginConfig
+	session *rendezvousSession
+	channel SecureChannel
+}
+
+// run completes the device authorization grant of the new device once the secure channel is established.
+func (l *qrLogin) run(ctx context.Context) (QRLoginResult, error) {
+	if l.cfg.OnCheckCode != nil {
+		l.cfg.OnCheckCode(l.channel.CheckCode())
+	}
+
+	protocols, err := l.receive(ctx, "m.login.protocols")
+	if err != nil {
+		return QRLoginResult{}, err
+	}
+	if !slices.Contains(protocols.Protocols, qrLoginProtocol) {
+		_ = l.send(ctx, apiQRLoginMessage{Type: "m.login.failure", Reason: "unsupported_protocol"})
+		return QRLoginResult{}, errors.New("the other device doesn't support the device authorization grant")
+	}
+
+	result := QRLoginResult{Homeserver: protocols.Homeserver}
+	if result.Homeserver == "" {
+		result.Homeserver = l.cfg.Server
+	}
+
+	result.OAuth, err = NewOAuthClient(ctx, OAuthConfig{
+		Server:     result.Homeserver,
+		HttpClient: l.cfg.HttpClient,
+		ClientID:   l.cfg.ClientID,
+	})
+	if err == nil && l.cfg.ClientID == "" {
+		_, err = result.OAuth.RegisterClient(ctx, l.cfg.ClientMetadata)
+	}
+	if err != nil {
+		_ = l.send(ctx, apiQRLoginMessage{Type: "m.login.failure", Reason: "unexpected_message_received"})
+		return QRLoginResult{}, err
+	}
+
+	auth, err := result.OAuth.StartDeviceLogin(ctx, l.cfg.DeviceID)
+	if err != nil {
+		return QRLoginResult{}, err
+	}
+	err = l.send(ctx, apiQRLoginMessage{
+		Type:     "m.login.protocol",
+		Protocol: qrLoginProtocol,
+		DeviceAuthorizationGrant: &apiDeviceAuthorizationGrant{
+			VerificationURI:         auth.VerificationURI,
+			VerificationURIComplete: auth.VerificationURIComplete,
+		},
+		DeviceID: auth.DeviceID,
+	})
+	if err != nil {
+		return QRLoginResult{}, err
+	}
+
+	_, err = l.receive(ctx, "m.login.protocol_accepted")
+	if err != nil {
+		return QRLoginResult{}, err
+	}
+
+	result.Token, err = result.OAuth.WaitDeviceLogin(ctx, auth)
+	if err != nil {
+		_ = l.send(ctx, apiQRLoginMessage{Type: "m.login.failure", Reason: "authorization_expired"})
+		return QRLoginResult{}, err
+	}
+	err = l.send(ctx, apiQRLoginMessage{Type: "m.login.success"})
+	if err != nil {
+		return QRLoginResult{}, err
+	}
+
+	secrets, err := l.receive(ctx, "m.login.secrets")
+	if err != nil {
+		return QRLoginResult{}, err
+	}
+	result.Secrets = QRLoginSecrets{CrossSigning: secrets.CrossSigning, Backup: secrets.Backup}
+	return result, nil
+}
+
+func (l *qrLogin) send(ctx context.Context, msg apiQRLoginMessage) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the %s message: %w", msg.Type, err)
+	}
+	return l.sendRaw(ctx, payload)
+}
+
+func (l *qrLogin) sendRaw(ctx context.Context, plaintext []byte) error {
+	message, err := l.channel.Encrypt(plaintext)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt the message: %w", err)
+	}
+	return l.session.send(ctx, message)
+}
+
+// receive waits for the next message and fails if it isn't of the expected type.
+func (l *qrLogin) receive(ctx context.Context, msgType string) (apiQRLoginMessage, error) {
+	plaintext, err := l.receiveRaw(ctx)
+	if err != nil {
+		return apiQRLoginMessage{}, err
+	}
+
+	var msg apiQRLoginMessage
+	err = json.Unmarshal(plaintext, &msg)
+	if err != nil {
+		return apiQRLoginMessage{}, fmt.Errorf("failed to unmarshal the message: %w", err)
+	}
+
+	switch {
+	case msg.Type == msgType:
+		return msg, nil
+	case msg.Type == "m.login.failure" || msg.Type == "m.login.declined":
+		return apiQRLoginMessage{}, &QRLoginError{Type: msg.Type, Reason: msg.Reason}
+	default:
+		_ = l.send(ctx, apiQRLoginMessage{Type: "m.login.failure", Reason: "unexpected_message_received"})
+		return apiQRLoginMessage{}, fmt.Errorf("unexpected %s message, expected %s", msg.Type, msgType)
+	}
+}
+
+func (l *qrLogin) receiveRaw(ctx context.Context) ([]byte, error) {
+	message, err := l.session.receive(ctx)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := l.channel.Decrypt(message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt the message: %w", err)
+	}
+	return plaintext, nil
+}
+
+// rendezvousSession is the insecure mailbox the two devices exchange the messages through,
+// each write replacing the previous one.
+type rendezvousSession struct {
+	httpClient *http.Client
+	url        string
+	etag       string
+}
+
+func createRendezvous(ctx context.Context, httpClient *http.Client, server string) (*rendezvousSession, error) {
+	req, err := http.NewRequestWithContext(
+		ctx, http.MethodPost, server+"/_matrix/client/unstable/org.matrix.msc4108/rendezvous", http.NoBody,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the rendezvous session: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("failed to create the rendezvous session: %w", readHTTPError(resp))
+	}
+
+	var respData apiRendezvousResp
+	err = json.NewDecoder(resp.Body).Decode(&respData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return &rendezvousSession{httpClient: httpClient, url: respData.URL, etag: resp.Header.Get("ETag")}, nil
+}
+
+func (s *rendezvousSession) send(ctx context.Context, data []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create a request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	if s.etag != "" {
+		req.Header.Set("If-Match", s.etag)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send to the rendezvous session: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("failed to send to the rendezvous session: %w", readHTTPError(resp))
+	}
+	s.etag = resp.Header.Get("ETag")
+	return nil
+}
+
+// receive polls the session until the other device replaces the data seen last.
+func (s *rendezvousSession) receive(ctx context.Context) ([]byte, error) {
+	for {
+		data, changed, err := s.poll(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if changed && len(data) > 0 {
+			return data, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(rendezvousPollInterval):
+		}
+	}
+}
+
+func (s *rendezvousSession) poll(ctx context.Context) ([]byte, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create a request: %w", err)
+	}
+	if s.etag != "" {
+		req.Header.Set("If-None-Match", s.etag)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to receive from the rendezvous session: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, false, nil
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read the rendezvous session: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		return nil, false, fmt.Errorf("failed to receive from the rendezvous session: %w", NewHTTPError(resp, respBody))
+	}
+
+	etag := resp.Header.Get("ETag")
+	changed := etag != s.etag
+	s.etag = etag
+	return respBody, changed, nil
+}
+
+// delete removes the session; it expires on its own if the request fails.
+func (s *rendezvousSession) delete() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, s.url, nil)
+	if err != nil {
+		return
+	}
+	resp, err := s.httpClient.Do(req)
+	if err == nil {
+		resp.Body.Close()
+	}
+}
@@ -0,0 +1,174 @@
+package gomatrix
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+type QRMode byte
+
+// https://spec.matrix.org/v1.13/client-server-api/#qr-code-format
+const (
+	// QRVerifyUser verifies another user: the first key is the own master key, the second one
+	// is the master key of the other user.
+	QRVerifyUser QRMode = 0x00
+	// QRSelfTrusted verifies an own device by a device trusting the master key: the first key is the master key,
+	// the second one is the key of the other device.
+	QRSelfTrusted QRMode = 0x01
+	// QRSelfUntrusted verifies the master key by a device not trusting it: the first key is the key of the device,
+	// the second one is the master key.
+	QRSelfUntrusted QRMode = 0x02
+)
+
+const (
+	qrPrefix    = "MATRIX"
+	qrVersion   = 0x02
+	qrSecretLen = 16
+)
+
+type QRCode struct {
+	Mode          QRMode
+	TransactionID string
+	FirstKey      []byte
+	SecondKey     []byte
+	Secret        []byte
+}
+
+// Bytes encodes the code into the binary payload to render as a QR code in byte mode.
+func (q QRCode) Bytes() []byte {
+	var buf bytes.Buffer
+	buf.WriteString(qrPrefix)
+	buf.WriteByte(qrVersion)
+	buf.WriteByte(byte(q.Mode))
+	_ = binary.Write(&buf, binary.BigEndian, uint16(len(q.TransactionID)))
+	buf.WriteString(q.TransactionID)
+	buf.Write(q.FirstKey)
+	buf.Write(q.SecondKey)
+	buf.Write(q.Secret)
+	return buf.Bytes()
+}
+
+func ParseQRCode(data []byte) (QRCode, error) {
+	rest, ok := bytes.CutPrefix(data, []byte(qrPrefix))
+	if !ok || len(rest) < 4 {
+		return QRCode{}, errors.New("not a verification QR code")
+	}
+	if rest[0] != qrVersion {
+		return QRCode{}, fmt.Errorf("unsupported QR code version %d", rest[0])
+	}
+
+	q := QRCode{Mode: QRMode(rest[1])}
+	n := int(binary.BigEndian.Uint16(rest[2:4]))
+	rest = rest[4:]
+	if len(rest) < n+64+8 {
+		return QRCode{}, errors.New("QR code is too short")
+	}
+
+	q.TransactionID = string(rest[:n])
+	q.FirstKey, q.SecondKey, q.Secret = rest[n:n+32], rest[n+32:n+64], rest[n+64:]
+	return q, nil
+}
+
+// QRVerification is a verification by QR codes: one side shows the code, the other one scans it
+// and reciprocates by sending back the code secret.
+type QRVerification struct {
+	client    *Client
+	transport VerificationTransport
+	shown     QRCode
+}
+
+// NewQRVerification prepares the code to show to the other device with the keys as described by the mode,
+// decoded from the unpadded base64 form published in the device and cross-signing keys.
+func (c *Client) NewQRVerification(
+	t VerificationTransport, transactionID string, mode QRMode, firstKey, secondKey string,
+) (*QRVerification, error) {
+	first, err := ParseEd25519Key(firstKey)
+	if err != nil {
+		return nil, err
+	}
+	second, err := ParseEd25519Key(secondKey)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := make([]byte, qrSecretLen)
+	_, err = rand.Read(secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate the QR code secret: %w", err)
+	}
+
+	return &QRVerification{
+		client:    c,
+		transport: t,
+		shown: QRCode{
+			Mode:          mode,
+			TransactionID: transactionID,
+			FirstKey:      first,
+			SecondKey:     second,
+			Secret:        secret,
+		},
+	}, nil
+}
+
+// Payload returns the bytes to render as the QR code shown to the other device.
+func (v *QRVerification) Payload() []byte {
+	return v.shown.Bytes()
+}
+
+// HandleStart completes the verification once the other device has scanned the shown code and sent
+// back its secret; the keys in the shown code can be trusted when it succeeds.
+func (v *QRVerification) HandleStart(ctx context.Context, content VerificationStartContent) error {
+	if content.Method != VerificationMethodReciprocate {
+		return fmt.Errorf("unexpected verification method %s", content.Method)
+	}
+
+	secret, err := decodeBase64(content.Secret)
+	if err != nil || subtle.ConstantTimeCompare(secret, v.shown.Secret) != 1 {
+		_ = VerificationCancel(ctx, v.transport, "m.mismatched_sas", "QR code secret mismatch")
+		return errors.New("QR code secret mismatch")
+	}
+
+	return VerificationDone(ctx, v.transport)
+}
+
+// ConfirmScanned checks the code scanned from the other device contains its expected key and the own key
+// as the other device knows it, and reciprocates. The expected other key is the first key of the code
+// and the own key is the second one, as described by the mode. The other key can be trusted when it succeeds.
+func (v *QRVerification) ConfirmScanned(ctx context.Context, data []byte, otherKey, ownKey string) error {
+	scanned, err := ParseQRCode(data)
+	if err != nil {
+		return err
+	}
+	if scanned.TransactionID != v.shown.TransactionID {
+		return errors.New("QR code belongs to another verification")
+	}
+
+	other, err := ParseEd25519Key(otherKey)
+	if err != nil {
+		return err
+	}
+	own, err := ParseEd25519Key(ownKey)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(scanned.FirstKey, other) || !bytes.Equal(scanned.SecondKey, own) {
+		_ = VerificationCancel(ctx, v.transport, "m.key_mismatch", "QR code keys mismatch")
+		return errors.New("QR code keys mismatch")
+	}
+
+	deviceID, err := v.client.DeviceID(ctx)
+	if err != nil {
+		return err
+	}
+	return v.transport.Send(ctx, "m.key.verification.start", map[string]any{
+		"from_device": deviceID,
+		"method":      VerificationMethodReciprocate,
+		"secret":      base64.RawStdEncoding.EncodeToString(scanned.Secret),
+	})
+}
@@ -0,0 +1,186 @@
+package gomatrix
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimit is a token bucket budget: Rate requests per second on average with bursts up to Burst requests.
+// A zero Rate disables the limit.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+type RateLimitConfig struct {
+	// Global limits all requests made by the client.
+	Global RateLimit
+	// RoomSend limits the events sent, state events set and redactions made in every single room.
+	RoomSend RateLimit
+	// Endpoints limit the requests by the path prefix, e.g. "/_matrix/media/"; the longest matching prefix wins.
+	Endpoints map[string]RateLimit
+}
+
+type rateLimiter struct {
+	rate  float64
+	burst float64
+
+	mux    sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(limit RateLimit) *rateLimiter {
+	if limit.Rate <= 0 {
+		return nil
+	}
+
+	burst := float64(max(limit.Burst, 1))
+	return &rateLimiter{rate: limit.Rate, burst: burst, tokens: burst}
+}
+
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mux.Lock()
+	now := time.Now()
+	if !l.last.IsZero() {
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+	// the token is reserved even if the caller gives up waiting, which keeps the limiter simple
+	l.tokens--
+	wait := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mux.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// full reports whether the bucket has refilled since the last request.
+func (l *rateLimiter) full(now time.Time) bool {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	return l.last.IsZero() || l.tokens+now.Sub(l.last).Seconds()*l.rate >= l.burst
+}
+
+// minRoomLimiters is the number of room limiters kept before the idle ones are evicted.
+const minRoomLimiters = 64
+
+type requestLimiter struct {
+	global    *rateLimiter
+	endpoints map[string]*rateLimiter
+	roomSend  RateLimit
+
+	mux   sync.Mutex
+	rooms map[string]*rateLimiter
+	// sweepAt is the number of room limiters the idle ones are evicted at, twice the ones left by the last eviction
+	sweepAt int
+}
+
+func newRequestLimiter(cfg RateLimitConfig) *requestLimiter {
+	l := &requestLimiter{
+		global:    newRateLimiter(cfg.Global),
+		endpoints: make(map[string]*rateLimiter, len(cfg.Endpoints)),
+		roomSend:  cfg.RoomSend,
+		rooms:     make(map[string]*rateLimiter),
+		sweepAt:   minRoomLimiters,
+	}
+	for prefix, limit := range cfg.Endpoints {
+		if limiter := newRateLimiter(limit); limiter != nil {
+			l.endpoints[prefix] = limiter
+		}
+	}
+	return l
+}
+
+func (l *requestLimiter) Wait(ctx context.Context, path string) error {
+	err := l.global.Wait(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = l.endpoint(path).Wait(ctx)
+	if err != nil {
+		return err
+	}
+
+	return l.room(path).Wait(ctx)
+}
+
+func (l *requestLimiter) endpoint(path string) *rateLimiter {
+	var limiter *rateLimiter
+	var longest int
+	for prefix, endpointLimiter := range l.endpoints {
+		if strings.HasPrefix(path, prefix) && len(prefix) > longest {
+			limiter, longest = endpointLimiter, len(prefix)
+		}
+	}
+	return limiter
+}
+
+func (l *requestLimiter) room(path string) *rateLimiter {
+	if l.roomSend.Rate <= 0 {
+		return nil
+	}
+
+	rest, ok := strings.CutPrefix(path, "/_matrix/client/v3/rooms/")
+	if !ok {
+		return nil
+	}
+	segments := strings.SplitN(rest, "/", 3)
+	if len(segments) < 2 {
+		return nil
+	}
+	switch segments[1] {
+	case "send", "state", "redact":
+	default:
+		return nil
+	}
+
+	roomID, err := url.PathUnescape(segments[0])
+	if err != nil {
+		return nil
+	}
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	limiter, ok := l.rooms[roomID]
+	if !ok {
+		if len(l.rooms) >= l.sweepAt {
+			l.evictIdle()
+		}
+		limiter = newRateLimiter(l.roomSend)
+		l.rooms[roomID] = limiter
+	}
+	return limiter
+}
+
+// evictIdle drops the limiters of the rooms whose bucket has refilled, which are no different from new ones;
+// l.mux must be held.
+func (l *requestLimiter) evictIdle() {
+	now := time.Now()
+	for roomID, limiter := range l.rooms {
+		if limiter.full(now) {
+			delete(l.rooms, roomID)
+		}
+	}
+	l.sweepAt = max(2*len(l.rooms), minRoomLimiters)
+}
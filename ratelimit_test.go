@@ -0,0 +1,143 @@
+package gomatrix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterWait(t *testing.T) {
+	tests := []struct {
+		name     string
+		limit    RateLimit
+		requests int
+		minWait  time.Duration
+		maxWait  time.Duration
+	}{
+		{name: "disabled", limit: RateLimit{}, requests: 100, maxWait: 50 * time.Millisecond},
+		{name: "within the burst", limit: RateLimit{Rate: 10, Burst: 5}, requests: 5, maxWait: 50 * time.Millisecond},
+		{name: "burst of at least one", limit: RateLimit{Rate: 10}, requests: 2, minWait: 80 * time.Millisecond},
+		{name: "over the burst", limit: RateLimit{Rate: 20, Burst: 2}, requests: 4, minWait: 80 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newRateLimiter(tt.limit)
+			start := time.Now()
+			for range tt.requests {
+				err := l.Wait(context.Background())
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			elapsed := time.Since(start)
+			if elapsed < tt.minWait || (tt.maxWait > 0 && elapsed > tt.maxWait) {
+				t.Fatalf("%d requests took %s, want %s to %s", tt.requests, elapsed, tt.minWait, tt.maxWait)
+			}
+		})
+	}
+}
+
+func TestRateLimiterWaitCanceled(t *testing.T) {
+	l := newRateLimiter(RateLimit{Rate: 1, Burst: 1})
+	err := l.Wait(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = l.Wait(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestRequestLimiterEndpoint(t *testing.T) {
+	l := newRequestLimiter(RateLimitConfig{Endpoints: map[string]RateLimit{
+		"/_matrix/media/":              {Rate: 1},
+		"/_matrix/media/v3/upload":     {Rate: 2},
+		"/_matrix/client/v3/disabled/": {},
+	}})
+
+	tests := []struct {
+		path string
+		want *rateLimiter
+	}{
+		{path: "/_matrix/media/v3/download/example.org/abc", want: l.endpoints["/_matrix/media/"]},
+		{path: "/_matrix/media/v3/upload", want: l.endpoints["/_matrix/media/v3/upload"]},
+		{path: "/_matrix/client/v3/sync", want: nil},
+		{path: "/_matrix/client/v3/disabled/x", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := l.endpoint(tt.path); got != tt.want {
+				t.Fatalf("endpoint(%q) = %p, want %p", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestLimiterRoom(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		roomID string
+	}{
+		{name: "send", path: "/_matrix/client/v3/rooms/!a:example.org/send/m.room.message/txn", roomID: "!a:example.org"},
+		{name: "state", path: "/_matrix/client/v3/rooms/!a:example.org/state/m.room.name/", roomID: "!a:example.org"},
+		{name: "redact", path: "/_matrix/client/v3/rooms/!a:example.org/redact/$e/txn", roomID: "!a:example.org"},
+		{name: "escaped room ID", path: "/_matrix/client/v3/rooms/%21b%3Aexample.org/send/m.room.message/txn", roomID: "!b:example.org"},
+		{name: "messages", path: "/_matrix/client/v3/rooms/!a:example.org/messages"},
+		{name: "room only", path: "/_matrix/client/v3/rooms/!a:example.org"},
+		{name: "other endpoint", path: "/_matrix/client/v3/sync"},
+		{name: "invalid escape", path: "/_matrix/client/v3/rooms/%zz/send/m.room.message/txn"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newRequestLimiter(RateLimitConfig{RoomSend: RateLimit{Rate: 1}})
+			got := l.room(tt.path)
+			if tt.roomID == "" {
+				if got != nil {
+					t.Fatalf("room(%q) returned a limiter, want none", tt.path)
+				}
+				return
+			}
+			if got == nil || l.rooms[tt.roomID] != got {
+				t.Fatalf("room(%q) didn't return the limiter of %s", tt.path, tt.roomID)
+			}
+			if l.room(tt.path) != got {
+				t.Fatalf("room(%q) returned a new limiter for the same room", tt.path)
+			}
+		})
+	}
+}
+
+func TestRequestLimiterEvictIdle(t *testing.T) {
+	l := newRequestLimiter(RateLimitConfig{RoomSend: RateLimit{Rate: 0.001}})
+
+	// the busy rooms have used their token, the idle one has a full bucket like a new limiter
+	for i := range minRoomLimiters - 1 {
+		err := l.Wait(context.Background(), fmt.Sprintf("/_matrix/client/v3/rooms/!%d:example.org/send/m.room.message/txn", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	l.room("/_matrix/client/v3/rooms/!idle:example.org/send/m.room.message/txn")
+
+	l.room("/_matrix/client/v3/rooms/!new:example.org/send/m.room.message/txn")
+	if _, ok := l.rooms["!idle:example.org"]; ok {
+		t.Fatal("the idle room limiter wasn't evicted")
+	}
+	if len(l.rooms) != minRoomLimiters {
+		t.Fatalf("got %d room limiters, want %d", len(l.rooms), minRoomLimiters)
+	}
+	if want := 2 * (minRoomLimiters - 1); l.sweepAt != want {
+		t.Fatalf("got sweepAt %d, want %d", l.sweepAt, want)
+	}
+}
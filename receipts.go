@@ -0,0 +1,67 @@
+package gomatrix
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// https://spec.matrix.org/v1.13/client-server-api/#receipts
+const (
+	ReceiptRead        = "m.read"
+	ReceiptReadPrivate = "m.read.private"
+
+	// MainThread is the pseudo-thread of the events that do not belong to any thread.
+	MainThread = "main"
+)
+
+// SendReceipt marks the event as read. An empty threadID sends an unthreaded receipt,
+// MainThread or a thread root event ID limits the receipt to the given thread.
+func (c *Client) SendReceipt(ctx context.Context, roomID, eventID, receiptType, threadID string) error {
+	err := c.doJSON(ctx, http.MethodPost, c.roomPath(roomID, "receipt", receiptType, eventID), apiReceiptReq{
+		ThreadID: threadID,
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to send a receipt: %w", err)
+	}
+	return nil
+}
+
+// MarkedUnreadAccountDataType is the room account data flagging a room the user marked as unread.
+const MarkedUnreadAccountDataType = "m.marked_unread"
+
+// MarkRoomRead moves the read receipt and the fully read marker to the latest event of the room
+// and clears the marked unread flag.
+func (c *Client) MarkRoomRead(ctx context.Context, roomID string) error {
+	page, err := c.GetMessages(ctx, roomID, PaginationOptions{Dir: Backward, Limit: 1})
+	if err != nil {
+		return err
+	}
+
+	if len(page.Events) > 0 {
+		eventID := page.Events[0].ID
+		err = c.doJSON(ctx, http.MethodPost, c.roomPath(roomID, "read_markers"), apiReadMarkersReq{
+			FullyRead: eventID,
+			Read:      eventID,
+		}, nil)
+		if err != nil {
+			return fmt.Errorf("failed to set the read markers: %w", err)
+		}
+	}
+
+	var marked apiMarkedUnread
+	err = c.GetRoomAccountData(ctx, roomID, MarkedUnreadAccountDataType, &marked)
+	if err != nil && !IsNotFound(err) {
+		return err
+	}
+	if !marked.Unread {
+		return nil
+	}
+	return c.SetRoomAccountData(ctx, roomID, MarkedUnreadAccountDataType, apiMarkedUnread{Unread: false})
+}
+
+// MarkRoomUnread sets or clears the flag the user marks a room with to come back to it later.
+// https://spec.matrix.org/v1.13/client-server-api/#unread-markers
+func (c *Client) MarkRoomUnread(ctx context.Context, roomID string, unread bool) error {
+	return c.SetRoomAccountData(ctx, roomID, MarkedUnreadAccountDataType, apiMarkedUnread{Unread: unread})
+}
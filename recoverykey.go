@@ -0,0 +1,150 @@
+package gomatrix
+
+import (
+	"crypto/rand"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+const (
+	base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+	passphraseAlgorithm  = "m.pbkdf2"
+	passphraseIterations = 500000
+)
+
+var recoveryKeyPrefix = []byte{0x8b, 0x01}
+
+// EncodeRecoveryKey formats the 32-byte secret storage or key backup key as a recovery key for the user to write down.
+// https://spec.matrix.org/v1.13/client-server-api/#key-representation
+func EncodeRecoveryKey(key []byte) string {
+	data := append(append([]byte{}, recoveryKeyPrefix...), key...)
+	var parity byte
+	for _, b := range data {
+		parity ^= b
+	}
+	data = append(data, parity)
+
+	encoded := base58Encode(data)
+
+	var out strings.Builder
+	for i := 0; i < len(encoded); i += 4 {
+		if i > 0 {
+			out.WriteByte(' ')
+		}
+		out.WriteString(encoded[i:min(i+4, len(encoded))])
+	}
+	return out.String()
+}
+
+// ParseRecoveryKey decodes the recovery key as entered by the user, ignoring the whitespace.
+func ParseRecoveryKey(s string) ([]byte, error) {
+	data, err := base58Decode(strings.Join(strings.Fields(s), ""))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) != len(recoveryKeyPrefix)+32+1 {
+		return nil, errors.New("invalid recovery key length")
+	}
+	if data[0] != recoveryKeyPrefix[0] || data[1] != recoveryKeyPrefix[1] {
+		return nil, errors.New("invalid recovery key prefix")
+	}
+
+	var parity byte
+	for _, b := range data {
+		parity ^= b
+	}
+	if parity != 0 {
+		return nil, errors.New("invalid recovery key parity")
+	}
+
+	return data[len(recoveryKeyPrefix) : len(data)-1], nil
+}
+
+// NewPassphraseKey derives a new key from the passphrase with a random salt; the returned parameters
+// are published with the key so the other clients can derive it again.
+func NewPassphraseKey(passphrase string) ([]byte, SecretStoragePassphrase, error) {
+	salt := make([]byte, 32)
+	for i := range salt {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(base58Alphabet))))
+		if err != nil {
+			return nil, SecretStoragePassphrase{}, fmt.Errorf("failed to generate the salt: %w", err)
+		}
+		salt[i] = base58Alphabet[n.Int64()]
+	}
+
+	params := SecretStoragePassphrase{
+		Algorithm:  passphraseAlgorithm,
+		Salt:       string(salt),
+		Iterations: passphraseIterations,
+		Bits:       256,
+	}
+	key, err := DeriveKeyFromPassphrase(passphrase, params)
+	return key, params, err
+}
+
+// DeriveKeyFromPassphrase derives the key with PBKDF2-SHA-512 and the published parameters.
+func DeriveKeyFromPassphrase(passphrase string, params SecretStoragePassphrase) ([]byte, error) {
+	if params.Algorithm != passphraseAlgorithm {
+		return nil, fmt.Errorf("unsupported passphrase algorithm %q", params.Algorithm)
+	}
+	// the parameters come from the account data, so a crafted description must not make the derivation endless
+	if params.Iterations <= 0 || params.Iterations > maxImportRounds {
+		return nil, fmt.Errorf("invalid passphrase iterations %d", params.Iterations)
+	}
+
+	bits := params.Bits
+	if bits == 0 {
+		bits = 256
+	}
+	if bits < 0 || bits > 512 || bits%8 != 0 {
+		return nil, errors.New("invalid passphrase key size")
+	}
+	return pbkdf2(sha512.New, []byte(passphrase), []byte(params.Salt), params.Iterations, bits/8), nil
+}
+
+func base58Encode(data []byte) string {
+	n := new(big.Int).SetBytes(data)
+	radix := big.NewInt(int64(len(base58Alphabet)))
+	mod := new(big.Int)
+
+	var out []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, radix, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+	for _, b := range data {
+		if b != 0 {
+			break
+		}
+		out = append(out, base58Alphabet[0])
+	}
+
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+func base58Decode(s string) ([]byte, error) {
+	n := new(big.Int)
+	radix := big.NewInt(int64(len(base58Alphabet)))
+	for _, r := range s {
+		i := strings.IndexRune(base58Alphabet, r)
+		if i < 0 {
+			return nil, fmt.Errorf("invalid base58 character %q", r)
+		}
+		n.Mul(n, radix)
+		n.Add(n, big.NewInt(int64(i)))
+	}
+
+	var zeros int
+	for zeros < len(s) && s[zeros] == base58Alphabet[0] {
+		zeros++
+	}
+	return append(make([]byte, zeros), n.Bytes()...), nil
+}
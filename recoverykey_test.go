@@ -0,0 +1,121 @@
+package gomatrix_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	gomatrix "github.com/beldeveloper/go-matrix"
+)
+
+func TestRecoveryKeyRoundTrip(t *testing.T) {
+	random := make([]byte, 32)
+	_, _ = rand.Read(random)
+
+	tests := []struct {
+		name   string
+		key    []byte
+		want   string
+		format func(string) string
+	}{
+		{name: "random", key: random},
+		{name: "zeros", key: make([]byte, 32), want: "EsSz ygLv VP1b xF1C v7kE eBQx MxDP buG5 w25T L3b6 hfyG Kkrd"},
+		{name: "ones", key: bytes.Repeat([]byte{0xff}, 32)},
+		{name: "without spaces", key: random, format: func(s string) string { return strings.ReplaceAll(s, " ", "") }},
+		{name: "extra whitespace", key: random, format: func(s string) string { return "  " + strings.ReplaceAll(s, " ", "\n\t") + " " }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded := gomatrix.EncodeRecoveryKey(tt.key)
+			if tt.want != "" && encoded != tt.want {
+				t.Fatalf("EncodeRecoveryKey returned %q, want %q", encoded, tt.want)
+			}
+			for _, group := range strings.Fields(encoded) {
+				if len(group) > 4 {
+					t.Fatalf("EncodeRecoveryKey returned group %q longer than 4 characters", group)
+				}
+			}
+			if tt.format != nil {
+				encoded = tt.format(encoded)
+			}
+
+			got, err := gomatrix.ParseRecoveryKey(encoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.key) {
+				t.Fatalf("ParseRecoveryKey returned %x, want %x", got, tt.key)
+			}
+		})
+	}
+}
+
+func TestParseRecoveryKeyInvalid(t *testing.T) {
+	valid := strings.ReplaceAll(gomatrix.EncodeRecoveryKey(make([]byte, 32)), " ", "")
+
+	tests := []struct {
+		name string
+		key  string
+		want string
+	}{
+		{name: "empty", key: "", want: "length"},
+		{name: "not base58", key: "0" + valid[1:], want: "base58"},
+		{name: "truncated", key: valid[:len(valid)-4], want: "length"},
+		{name: "too long", key: valid + "1111", want: "length"},
+		// the last digit changed, and so the parity byte
+		{name: "wrong parity", key: valid[:len(valid)-1] + "e", want: "parity"},
+		// the zero key with the 0x8b 0x02 prefix and a valid parity
+		{name: "wrong prefix", key: "EsUK2TRoZKTBCKmvwEDAo6rqtTYuaKzpeJ9f95nM3VHkXbsE", want: "prefix"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := gomatrix.ParseRecoveryKey(tt.key)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("ParseRecoveryKey(%q) = %v, want a %s error", tt.key, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeriveKeyFromPassphrase(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  gomatrix.SecretStoragePassphrase
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "default size",
+			params: gomatrix.SecretStoragePassphrase{Algorithm: "m.pbkdf2", Salt: "salt", Iterations: 1},
+			want:   "867f70cf1ade02cff3752599a3a53dc4af34c7a669815ae5d513554e1c8cf252",
+		},
+		{
+			name:   "512 bits",
+			params: gomatrix.SecretStoragePassphrase{Algorithm: "m.pbkdf2", Salt: "salt", Iterations: 2, Bits: 512},
+			want:   "e1d9c16aa681708a45f5c7c4e215ceb66e011a2e9f0040713f18aefdb866d53cf76cab2868a39b9f7840edce4fef5a82be67335c77a6068e04112754f27ccf4e",
+		},
+		{name: "unknown algorithm", params: gomatrix.SecretStoragePassphrase{Algorithm: "m.scrypt", Iterations: 1}, wantErr: true},
+		{name: "no iterations", params: gomatrix.SecretStoragePassphrase{Algorithm: "m.pbkdf2"}, wantErr: true},
+		{name: "negative iterations", params: gomatrix.SecretStoragePassphrase{Algorithm: "m.pbkdf2", Iterations: -1}, wantErr: true},
+		{name: "too many iterations", params: gomatrix.SecretStoragePassphrase{Algorithm: "m.pbkdf2", Iterations: 1 << 30}, wantErr: true},
+		{name: "negative size", params: gomatrix.SecretStoragePassphrase{Algorithm: "m.pbkdf2", Iterations: 1, Bits: -8}, wantErr: true},
+		{name: "size too big", params: gomatrix.SecretStoragePassphrase{Algorithm: "m.pbkdf2", Iterations: 1, Bits: 1024}, wantErr: true},
+		{name: "size not in bytes", params: gomatrix.SecretStoragePassphrase{Algorithm: "m.pbkdf2", Iterations: 1, Bits: 100}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := gomatrix.DeriveKeyFromPassphrase("password", tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %t", err, tt.wantErr)
+			}
+			if hex.EncodeToString(got) != tt.want {
+				t.Fatalf("got %x, want %s", got, tt.want)
+			}
+		})
+	}
+}
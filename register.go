@@ -0,0 +1,68 @@
+package gomatrix
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// RegisterRequest is a new account. Only the password is required; the server picks a user name if it's empty.
+type RegisterRequest struct {
+	Username                 string
+	Password                 string
+	DeviceID                 string
+	InitialDeviceDisplayName string
+	// Login makes the server log the new account in and return its access token.
+	Login bool
+}
+
+type RegisterResponse struct {
+	UserID string
+	// AccessToken and DeviceID are set if RegisterRequest.Login was.
+	AccessToken string
+	DeviceID    string
+	ExpiresAt   time.Time
+}
+
+// Register creates an account, completing the user-interactive authentication of the registration with uia,
+// e.g. the registration token of a closed homeserver. The client stays logged in as its own user.
+// https://spec.matrix.org/v1.13/client-server-api/#post_matrixclientv3register
+func (c *Client) Register(ctx context.Context, req RegisterRequest, uia UIA) (RegisterResponse, error) {
+	reqData := apiRegisterReq{
+		Username:                 req.Username,
+		Password:                 req.Password,
+		DeviceID:                 req.DeviceID,
+		InitialDeviceDisplayName: req.InitialDeviceDisplayName,
+		InhibitLogin:             !req.Login,
+	}
+
+	var respData apiLoginResp
+	err := uia.Do(ctx, func(ctx context.Context, auth map[string]any) error {
+		reqData.Auth = auth
+		return c.postAuth(ctx, "/_matrix/client/v3/register?kind=user", reqData, &respData)
+	})
+	if err != nil {
+		return RegisterResponse{}, fmt.Errorf("failed to register: %w", err)
+	}
+
+	return RegisterResponse{
+		UserID:      respData.UserID,
+		AccessToken: respData.AccessToken,
+		DeviceID:    respData.DeviceID,
+		ExpiresAt:   expiresAt(time.Duration(respData.ExpiresInMs) * time.Millisecond),
+	}, nil
+}
+
+// CheckRegistrationToken reports whether the registration token can still be used to register.
+// https://spec.matrix.org/v1.13/client-server-api/#get_matrixclientv1registermloginregistration_tokenvalidity
+func (c *Client) CheckRegistrationToken(ctx context.Context, token string) (bool, error) {
+	var respData apiRegistrationTokenValidityResp
+	path := "/_matrix/client/v1/register/m.login.registration_token/validity?token=" + url.QueryEscape(token)
+	err := c.doJSON(ctx, http.MethodGet, path, nil, &respData)
+	if err != nil {
+		return false, fmt.Errorf("failed to check the registration token: %w", err)
+	}
+	return respData.Valid, nil
+}
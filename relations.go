@@ -0,0 +1,103 @@
+package gomatrix
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+func (c *Client) SendReaction(ctx context.Context, roomID, eventID, key string) (string, error) {
+	reactionID, err := c.sendEvent(ctx, roomID, "m.reaction", apiReactionReq{
+		RelatesTo: Relation{
+			Type:    RelAnnotation,
+			EventID: eventID,
+			Key:     key,
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to send a reaction: %w", err)
+	}
+	return reactionID, nil
+}
+
+func (c *Client) EditText(ctx context.Context, roomID, eventID, text string) (string, error) {
+	return c.sendMessage(ctx, apiSendMsgReq{
+		RoomID: roomID,
+		Type:   string(Text),
+		Body:   "* " + text,
+		NewContent: &apiSendMsgReq{
+			Type: string(Text),
+			Body: text,
+		},
+		RelatesTo: &Relation{
+			Type:    RelReplace,
+			EventID: eventID,
+		},
+	})
+}
+
+type RelationsOptions struct {
+	PaginationOptions
+	// Recurse includes the events relating indirectly to the parent, e.g. reactions to thread replies.
+	Recurse bool
+}
+
+// GetEventRelations returns the events relating to the given parent event.
+// The relation type and the event type are optional; the event type requires the relation type.
+func (c *Client) GetEventRelations(
+	ctx context.Context, roomID, eventID, relType, eventType string, opts RelationsOptions,
+) (EventPage, error) {
+	path := fmt.Sprintf("/_matrix/client/v1/rooms/%s/relations/%s", url.PathEscape(roomID), url.PathEscape(eventID))
+	if relType != "" {
+		path += "/" + url.PathEscape(relType)
+		if eventType != "" {
+			path += "/" + url.PathEscape(eventType)
+		}
+	}
+
+	query := opts.query()
+	if opts.Recurse {
+		query.Set("recurse", "true")
+	}
+
+	var page EventPage
+	err := c.doJSON(ctx, http.MethodGet, withQuery(path, query), nil, &page)
+	if err != nil {
+		return EventPage{}, fmt.Errorf("failed to get event relations: %w", err)
+	}
+
+	for i := range page.Events {
+		page.Events[i].RoomID = roomID
+	}
+
+	return page, nil
+}
+
+// https://spec.matrix.org/v1.13/client-server-api/#get_matrixclientv1roomsroomidthreads
+const (
+	ThreadsAll          = "all"
+	ThreadsParticipated = "participated"
+)
+
+// GetThreads returns the thread root events of the room, most recent first.
+// The include argument is either ThreadsAll or ThreadsParticipated; only From and Limit of the options are used.
+func (c *Client) GetThreads(ctx context.Context, roomID, include string, opts PaginationOptions) (EventPage, error) {
+	query := PaginationOptions{From: opts.From, Limit: opts.Limit}.query()
+	if include != "" {
+		query.Set("include", include)
+	}
+
+	var page EventPage
+	path := fmt.Sprintf("/_matrix/client/v1/rooms/%s/threads", url.PathEscape(roomID))
+	err := c.doJSON(ctx, http.MethodGet, withQuery(path, query), nil, &page)
+	if err != nil {
+		return EventPage{}, fmt.Errorf("failed to get threads: %w", err)
+	}
+
+	for i := range page.Events {
+		page.Events[i].RoomID = roomID
+	}
+
+	return page, nil
+}
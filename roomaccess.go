@@ -0,0 +1,56 @@
+package gomatrix
+
+import (
+	"context"
+	"fmt"
+)
+
+type HistoryVisibility string
+
+// https://spec.matrix.org/v1.13/client-server-api/#mroomhistory_visibility
+const (
+	// HistoryInvited shows the members the events sent since they were invited.
+	HistoryInvited HistoryVisibility = "invited"
+	// HistoryJoined shows the members the events sent since they joined.
+	HistoryJoined HistoryVisibility = "joined"
+	// HistoryShared shows the members the whole history, including the events sent before they joined.
+	HistoryShared HistoryVisibility = "shared"
+	// HistoryWorldReadable shows anyone the whole history, without joining the room.
+	HistoryWorldReadable HistoryVisibility = "world_readable"
+)
+
+type HistoryVisibilityContent struct {
+	HistoryVisibility HistoryVisibility `json:"history_visibility"`
+}
+
+type GuestAccess string
+
+// https://spec.matrix.org/v1.13/client-server-api/#mroomguest_access
+const (
+	GuestCanJoin   GuestAccess = "can_join"
+	GuestForbidden GuestAccess = "forbidden"
+)
+
+type GuestAccessContent struct {
+	GuestAccess GuestAccess `json:"guest_access"`
+}
+
+// SetHistoryVisibility sets who can read the history of the room. The change applies to the events sent afterwards.
+func (c *Client) SetHistoryVisibility(ctx context.Context, roomID string, visibility HistoryVisibility) (string, error) {
+	switch visibility {
+	case HistoryInvited, HistoryJoined, HistoryShared, HistoryWorldReadable:
+	default:
+		return "", fmt.Errorf("unknown history visibility %q", visibility)
+	}
+	return c.SetStateEvent(ctx, roomID, "m.room.history_visibility", "", HistoryVisibilityContent{HistoryVisibility: visibility})
+}
+
+// SetGuestAccess sets whether the guest accounts can join the room, provided its join rules let them.
+func (c *Client) SetGuestAccess(ctx context.Context, roomID string, access GuestAccess) (string, error) {
+	switch access {
+	case GuestCanJoin, GuestForbidden:
+	default:
+		return "", fmt.Errorf("unknown guest access %q", access)
+	}
+	return c.SetStateEvent(ctx, roomID, "m.room.guest_access", "", GuestAccessContent{GuestAccess: access})
+}
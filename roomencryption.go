@@ -0,0 +1,30 @@
+package gomatrix
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrEncryptionEnabled is returned by EnableEncryption for the rooms already encrypted.
+var ErrEncryptionEnabled = errors.New("encryption is already enabled in the room")
+
+// EnableEncryption turns on the Megolm encryption of the room with the recommended session rotation:
+// a week or 100 messages. Encryption can't be turned off once enabled, so a room already encrypted
+// is left as is and ErrEncryptionEnabled is returned, rather than overwriting its settings.
+// https://spec.matrix.org/v1.13/client-server-api/#mroomencryption
+func (c *Client) EnableEncryption(ctx context.Context, roomID string) (string, error) {
+	var current EncryptionContent
+	err := c.GetStateEvent(ctx, roomID, "m.room.encryption", "", &current)
+	if err == nil {
+		return "", ErrEncryptionEnabled
+	}
+	if !IsNotFound(err) {
+		return "", err
+	}
+
+	return c.SetStateEvent(ctx, roomID, "m.room.encryption", "", EncryptionContent{
+		Algorithm:          AlgorithmMegolm,
+		RotationPeriodMs:   defaultRotationPeriod.Milliseconds(),
+		RotationPeriodMsgs: defaultRotationMessages,
+	})
+}
@@ -0,0 +1,176 @@
+package gomatrix
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/google/uuid"
+)
+
+func (c *Client) JoinRoom(ctx context.Context, roomIDOrAlias string) (string, error) {
+	var respData apiJoinRoomResp
+	err := c.doJSON(ctx, http.MethodPost, "/_matrix/client/v3/join/"+url.PathEscape(roomIDOrAlias), struct{}{}, &respData)
+	if err != nil {
+		return "", fmt.Errorf("failed to join the room: %w", err)
+	}
+	return respData.RoomID, nil
+}
+
+// ResolveAlias returns the room ID of the room alias and the servers aware of the room.
+func (c *Client) ResolveAlias(ctx context.Context, alias string) (string, []string, error) {
+	var respData apiRoomAliasResp
+	err := c.doJSON(ctx, http.MethodGet, "/_matrix/client/v3/directory/room/"+url.PathEscape(alias), nil, &respData)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to resolve the room alias: %w", err)
+	}
+	return respData.RoomID, respData.Servers, nil
+}
+
+func (c *Client) LeaveRoom(ctx context.Context, roomID string) error {
+	err := c.doJSON(ctx, http.MethodPost, c.roomPath(roomID, "leave"), struct{}{}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to leave the room: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) Invite(ctx context.Context, roomID, userID, reason string) error {
+	err := c.doJSON(ctx, http.MethodPost, c.roomPath(roomID, "invite"), apiMembershipReq{UserID: userID, Reason: reason}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to invite the user: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) Kick(ctx context.Context, roomID, userID, reason string) error {
+	err := c.doJSON(ctx, http.MethodPost, c.roomPath(roomID, "kick"), apiMembershipReq{UserID: userID, Reason: reason}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to kick the user: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) Ban(ctx context.Context, roomID, userID, reason string) error {
+	err := c.doJSON(ctx, http.MethodPost, c.roomPath(roomID, "ban"), apiMembershipReq{UserID: userID, Reason: reason}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to ban the user: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) Unban(ctx context.Context, roomID, userID string) error {
+	err := c.doJSON(ctx, http.MethodPost, c.roomPath(roomID, "unban"), apiMembershipReq{UserID: userID}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to unban the user: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) Redact(ctx context.Context, roomID, eventID, reason string) (string, error) {
+	var respData apiSendEventResp
+	err := c.doJSON(
+		ctx, http.MethodPut, c.roomPath(roomID, "redact", eventID, uuid.NewString()), apiRedactReq{Reason: reason}, &respData,
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to redact the event: %w", err)
+	}
+	return respData.EventID, nil
+}
+
+func (c *Client) roomPath(roomID string, segments ...string) string {
+	path := "/_matrix/client/v3/rooms/" + url.PathEscape(roomID)
+	for _, segment := range segments {
+		path += "/" + url.PathEscape(segment)
+	}
+	return path
+}
+
+type JoinedMember struct {
+	DisplayName string `json:"display_name"`
+	AvatarURL   MXCURI `json:"avatar_url"`
+}
+
+func (c *Client) GetJoinedRooms(ctx context.Context) ([]string, error) {
+	var respData apiJoinedRoomsResp
+	err := c.doJSON(ctx, http.MethodGet, "/_matrix/client/v3/joined_rooms", nil, &respData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get joined rooms: %w", err)
+	}
+	return respData.JoinedRooms, nil
+}
+
+// GetJoinedMembers returns the members currently joined to the room keyed by user ID.
+func (c *Client) GetJoinedMembers(ctx context.Context, roomID string) (map[string]JoinedMember, error) {
+	var respData apiJoinedMembersResp
+	err := c.doJSON(ctx, http.MethodGet, c.roomPath(roomID, "joined_members"), nil, &respData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get joined members: %w", err)
+	}
+	return respData.Joined, nil
+}
+
+type MembershipFilter struct {
+	// Membership keeps only the members with the given membership.
+	Membership Membership
+	// NotMembership excludes the members with the given membership.
+	NotMembership Membership
+}
+
+// GetRoomMembers returns the m.room.member state events of the room.
+// If at is not empty, the membership is returned as of the given sync token.
+func (c *Client) GetRoomMembers(ctx context.Context, roomID, at string, filter MembershipFilter) ([]Event, error) {
+	query := url.Values{}
+	if at != "" {
+		query.Set("at", at)
+	}
+	if filter.Membership != "" {
+		query.Set("membership", string(filter.Membership))
+	}
+	if filter.NotMembership != "" {
+		query.Set("not_membership", string(filter.NotMembership))
+	}
+
+	var respData apiRoomMembersResp
+	err := c.doJSON(ctx, http.MethodGet, withQuery(c.roomPath(roomID, "members"), query), nil, &respData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get room members: %w", err)
+	}
+
+	for i := range respData.Chunk {
+		respData.Chunk[i].RoomID = roomID
+	}
+
+	return respData.Chunk, nil
+}
+
+type MessagesPage struct {
+	Events []Event `json:"chunk"`
+	Start  string  `json:"start"`
+	End    string  `json:"end,omitempty"`
+	State  []Event `json:"state,omitempty"`
+}
+
+// GetMessages paginates the room timeline; the End token of the page continues the pagination
+// and is empty when there are no more events.
+func (c *Client) GetMessages(ctx context.Context, roomID string, opts PaginationOptions) (MessagesPage, error) {
+	if opts.Dir == "" {
+		opts.Dir = Backward
+	}
+
+	var page MessagesPage
+	err := c.doJSON(ctx, http.MethodGet, withQuery(c.roomPath(roomID, "messages"), opts.query()), nil, &page)
+	if err != nil {
+		return MessagesPage{}, fmt.Errorf("failed to get room messages: %w", err)
+	}
+
+	for i := range page.Events {
+		page.Events[i].RoomID = roomID
+	}
+	for i := range page.State {
+		page.State[i].RoomID = roomID
+	}
+
+	return page, nil
+}
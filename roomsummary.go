@@ -0,0 +1,48 @@
+package gomatrix
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// PublicRoomSummary describes a room the client doesn't have to be joined to.
+// https://github.com/matrix-org/matrix-spec-proposals/pull/3266
+type PublicRoomSummary struct {
+	RoomID           string     `json:"room_id"`
+	CanonicalAlias   string     `json:"canonical_alias,omitempty"`
+	Name             string     `json:"name,omitempty"`
+	Topic            string     `json:"topic,omitempty"`
+	AvatarURL        MXCURI     `json:"avatar_url,omitempty"`
+	NumJoinedMembers int        `json:"num_joined_members"`
+	JoinRule         JoinRule   `json:"join_rule,omitempty"`
+	RoomType         string     `json:"room_type,omitempty"`
+	RoomVersion      string     `json:"room_version,omitempty"`
+	WorldReadable    bool       `json:"world_readable"`
+	GuestCanJoin     bool       `json:"guest_can_join"`
+	Encryption       string     `json:"encryption,omitempty"`
+	Membership       Membership `json:"membership,omitempty"`
+	AllowedRoomIDs   []string   `json:"allowed_room_ids,omitempty"`
+}
+
+// GetRoomSummary previews the room, e.g. before joining it. The via servers are asked about the rooms
+// unknown to the homeserver. Homeservers implementing only the unstable version of the API are supported.
+func (c *Client) GetRoomSummary(ctx context.Context, roomIDOrAlias string, via []string) (PublicRoomSummary, error) {
+	query := url.Values{}
+	for _, server := range via {
+		query.Add("via", server)
+	}
+
+	var summary PublicRoomSummary
+	path := withQuery("/_matrix/client/v1/room_summary/"+url.PathEscape(roomIDOrAlias), query)
+	err := c.doJSON(ctx, http.MethodGet, path, nil, &summary)
+	if ErrCode(err) == "M_UNRECOGNIZED" {
+		path = withQuery("/_matrix/client/unstable/im.nheko.summary/rooms/"+url.PathEscape(roomIDOrAlias)+"/summary", query)
+		err = c.doJSON(ctx, http.MethodGet, path, nil, &summary)
+	}
+	if err != nil {
+		return PublicRoomSummary{}, fmt.Errorf("failed to get the room summary: %w", err)
+	}
+	return summary, nil
+}
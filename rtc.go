@@ -0,0 +1,198 @@
+package gomatrix
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// https://github.com/matrix-org/matrix-spec-proposals/pull/3401
+// https://github.com/matrix-org/matrix-spec-proposals/pull/4143
+const (
+	// CallEventType describes a group call of the room, its state key being the call ID.
+	CallEventType = "org.matrix.msc3401.call"
+	// CallMemberEventType is the membership of a device in the call sessions of the room, as Element Call sends it.
+	CallMemberEventType = "org.matrix.msc3401.call.member"
+	// StableCallMemberEventType is the name CallMemberEventType takes once MSC3401 is accepted.
+	StableCallMemberEventType = "m.call.member"
+
+	// CallApplication is the application of the Element Call memberships.
+	CallApplication = "m.call"
+	// CallScopeRoom is the scope of the call every member of the room can join.
+	CallScopeRoom = "m.room"
+
+	FocusTypeLiveKit = "livekit"
+
+	// the to-device events the devices of a full mesh call set up their peer connections with
+	CallInviteEventType       = "m.call.invite"
+	CallCandidatesEventType   = "m.call.candidates"
+	CallAnswerEventType       = "m.call.answer"
+	CallSelectAnswerEventType = "m.call.select_answer"
+	CallHangupEventType       = "m.call.hangup"
+	// CallEncryptionKeysEventType shares the media encryption keys of a device with the other call members.
+	CallEncryptionKeysEventType = "io.element.call.encryption_keys"
+
+	defaultCallMembershipExpiry = 4 * time.Hour
+)
+
+type CallContent struct {
+	// Intent is m.ring, m.prompt or m.room.
+	Intent string `json:"m.intent,omitempty"`
+	// Type is m.voice or m.video.
+	Type       string `json:"m.type,omitempty"`
+	Name       string `json:"m.name,omitempty"`
+	Terminated bool   `json:"m.terminated,omitempty"`
+}
+
+// Focus is the media server, e.g. a LiveKit SFU, the call members exchange the media through.
+type Focus struct {
+	Type string `json:"type"`
+	// LiveKitServiceURL is where the members get the LiveKit JWT from.
+	LiveKitServiceURL string `json:"livekit_service_url,omitempty"`
+	LiveKitAlias      string `json:"livekit_alias,omitempty"`
+	// FocusSelection tells how the active focus is chosen, e.g. oldest_membership.
+	FocusSelection string `json:"focus_selection,omitempty"`
+}
+
+// CallMembership is the membership of a device in a call session, the content of a CallMemberEventType event.
+// The content is empty once the device has left.
+type CallMembership struct {
+	Application string `json:"application,omitempty"`
+	// CallID is empty for the call of the room.
+	CallID   string `json:"call_id"`
+	Scope    string `json:"scope,omitempty"`
+	DeviceID string `json:"device_id,omitempty"`
+	// Expires is how long after its creation the membership is valid, in milliseconds.
+	Expires       int64   `json:"expires,omitempty"`
+	CreatedTS     int64   `json:"created_ts,omitempty"`
+	FocusActive   *Focus  `json:"focus_active,omitempty"`
+	FociPreferred []Focus `json:"foci_preferred,omitempty"`
+}
+
+// CallMember is a device taking part in a call.
+type CallMember struct {
+	UserID     string
+	Membership CallMembership
+}
+
+// CallMembershipStateKey returns the state key of the membership of the device.
+func CallMembershipStateKey(userID, deviceID string) string {
+	return "_" + userID + "_" + deviceID
+}
+
+// CallMembership returns the call membership of a call member event and the user it belongs to, its sender;
+// ok is false for the other events, the devices having left, the expired memberships and the events whose
+// state key isn't one of the sender, see CallMembershipStateKey.
+func (e Event) CallMembership(now time.Time) (member CallMember, ok bool) {
+	var membership CallMembership
+	isMember := e.Type == CallMemberEventType || e.Type == StableCallMemberEventType
+	if !isMember || e.ParseContent(&membership) != nil || membership.DeviceID == "" {
+		return CallMember{}, false
+	}
+
+	created := membership.CreatedTS
+	if created == 0 {
+		created = e.OriginServerTS
+	}
+	if membership.Expires > 0 && created+membership.Expires < now.UnixMilli() {
+		return CallMember{}, false
+	}
+
+	// the state keys of a user are writable by them only when prefixed with their ID, so a state key naming
+	// another user is forged
+	if !strings.HasPrefix(e.GetStateKey(), "_"+e.Sender+"_") {
+		return CallMember{}, false
+	}
+	return CallMember{UserID: e.Sender, Membership: membership}, true
+}
+
+// GetCallMembers returns the devices taking part in the call of the room with the given ID, empty for the call
+// of the room.
+func (c *Client) GetCallMembers(ctx context.Context, roomID, callID string) ([]CallMember, error) {
+	state, err := c.GetRoomState(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var members []CallMember
+	for _, ev := range append(state.OfType(CallMemberEventType), state.OfType(StableCallMemberEventType)...) {
+		member, ok := ev.CallMembership(now)
+		if ok && member.Membership.CallID == callID {
+			members = append(members, member)
+		}
+	}
+	return members, nil
+}
+
+// JoinCall announces the device of the client as a member of the call, with the foci it prefers.
+// The membership expires after 4 hours unless Expires is set; join again to extend it.
+func (c *Client) JoinCall(ctx context.Context, roomID string, membership CallMembership) error {
+	if len(membership.FociPreferred) == 0 {
+		return errors.New("at least one preferred focus is required")
+	}
+
+	userID, err := c.WhoAmI(ctx)
+	if err != nil {
+		return err
+	}
+	deviceID, err := c.DeviceID(ctx)
+	if err != nil {
+		return err
+	}
+
+	membership.DeviceID = deviceID
+	if membership.Application == "" {
+		membership.Application = CallApplication
+	}
+	if membership.Scope == "" {
+		membership.Scope = CallScopeRoom
+	}
+	if membership.Expires == 0 {
+		membership.Expires = defaultCallMembershipExpiry.Milliseconds()
+	}
+	if membership.FocusActive == nil {
+		membership.FocusActive = &Focus{Type: membership.FociPreferred[0].Type, FocusSelection: "oldest_membership"}
+	}
+
+	_, err = c.SetStateEvent(ctx, roomID, CallMemberEventType, CallMembershipStateKey(userID, deviceID), membership)
+	return err
+}
+
+// LeaveCall withdraws the call membership of the device of the client.
+func (c *Client) LeaveCall(ctx context.Context, roomID string) error {
+	userID, err := c.WhoAmI(ctx)
+	if err != nil {
+		return err
+	}
+	deviceID, err := c.DeviceID(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.SetStateEvent(ctx, roomID, CallMemberEventType, CallMembershipStateKey(userID, deviceID), struct{}{})
+	return err
+}
+
+// CallSignal holds the fields shared by the to-device events of the full mesh calls.
+type CallSignal struct {
+	CallID  string `json:"call_id"`
+	PartyID string `json:"party_id"`
+	// ConfID is the ID of the group call the peer connection belongs to.
+	ConfID          string `json:"conf_id"`
+	DeviceID        string `json:"device_id"`
+	SenderSessionID string `json:"sender_session_id"`
+	DestSessionID   string `json:"dest_session_id"`
+	Version         string `json:"version"`
+	// Seq orders the signals of the peer connection.
+	Seq int `json:"seq"`
+}
+
+// SendCallSignal sends a to-device event of the call to a device of a call member; content usually embeds
+// CallSignal with the fields of the event type, e.g. the SDP offer of m.call.invite.
+func (c *Client) SendCallSignal(ctx context.Context, member CallMember, eventType string, content any) error {
+	return c.SendToDevice(ctx, eventType, map[string]map[string]any{
+		member.UserID: {member.Membership.DeviceID: content},
+	})
+}
@@ -0,0 +1,229 @@
+package gomatrix
+
+import (
+	"html"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// maxHTMLNesting is the nesting depth the clients should render at most, deeper tags are dropped.
+const maxHTMLNesting = 100
+
+// allowedHTMLTags are the tags the clients may render and their attributes.
+// https://spec.matrix.org/v1.13/client-server-api/#mroommessage-msgtypes
+var allowedHTMLTags = map[string][]string{
+	"font":       {"data-mx-bg-color", "data-mx-color", "color"},
+	"del":        nil,
+	"h1":         nil,
+	"h2":         nil,
+	"h3":         nil,
+	"h4":         nil,
+	"h5":         nil,
+	"h6":         nil,
+	"blockquote": nil,
+	"p":          nil,
+	"a":          {"name", "target", "href"},
+	"ul":         nil,
+	"ol":         {"start"},
+	"sup":        nil,
+	"sub":        nil,
+	"li":         nil,
+	"b":          nil,
+	"i":          nil,
+	"u":          nil,
+	"strong":     nil,
+	"em":         nil,
+	"s":          nil,
+	"code":       {"class"},
+	"hr":         nil,
+	"br":         nil,
+	"div":        {"data-mx-maths"},
+	"table":      nil,
+	"thead":      nil,
+	"tbody":      nil,
+	"tr":         nil,
+	"th":         nil,
+	"td":         nil,
+	"caption":    nil,
+	"pre":        nil,
+	"span":       {"data-mx-bg-color", "data-mx-color", "data-mx-spoiler", "data-mx-maths"},
+	"img":        {"width", "height", "alt", "title", "src", "data-mx-emoticon"},
+	"details":    nil,
+	"summary":    nil,
+}
+
+var voidHTMLTags = []string{"br", "hr", "img"}
+
+// droppedHTMLTags are removed with their content: the reply fallback and the elements whose content is not text.
+var droppedHTMLTags = []string{"mx-reply", "script", "style", "iframe", "noscript", "textarea", "title", "template", "object"}
+
+var (
+	htmlTagName  = regexp.MustCompile(`^<(/?)([A-Za-z][A-Za-z0-9-]*)`)
+	htmlAttr     = regexp.MustCompile(`^[\s/]*([^\s/>="'<]+)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+)))?`)
+	htmlColor    = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+	htmlNumber   = regexp.MustCompile(`^[0-9]+$`)
+	htmlLanguage = regexp.MustCompile(`^language-[A-Za-z0-9_+#.-]+$`)
+	linkSchemes  = []string{"https", "http", "ftp", "mailto", "magnet"}
+)
+
+// SanitizeHTML keeps the tags and attributes of the formatted body the spec allows, dropping the others
+// along with the reply fallback, so the HTML of the events can be rendered safely. The text of the dropped
+// tags is kept, except for the content of scripts, styles and similar elements. Links are kept to the web,
+// mail and magnet URLs and images to mxc URIs.
+func SanitizeHTML(input string) string {
+	var out strings.Builder
+	var open []string
+	for len(input) > 0 {
+		lt := strings.IndexByte(input, '<')
+		if lt != 0 {
+			if lt < 0 {
+				lt = len(input)
+			}
+			out.WriteString(html.EscapeString(html.UnescapeString(input[:lt])))
+			input = input[lt:]
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(input, "<!--"):
+			input = skipPast(input, "-->")
+			continue
+		case strings.HasPrefix(input, "<!"), strings.HasPrefix(input, "<?"):
+			input = skipPast(input, ">")
+			continue
+		}
+
+		match := htmlTagName.FindStringSubmatch(input)
+		if match == nil {
+			out.WriteString("&lt;")
+			input = input[1:]
+			continue
+		}
+		closing, name := match[1] == "/", strings.ToLower(match[2])
+		attrs, rest := parseHTMLAttrs(input[len(match[0]):])
+		input = rest
+
+		switch {
+		case closing:
+			if i := lastIndex(open, name); i >= 0 {
+				for _, tag := range slices.Backward(open[i:]) {
+					out.WriteString("</" + tag + ">")
+				}
+				open = open[:i]
+			}
+		case slices.Contains(droppedHTMLTags, name):
+			input = skipElement(input, name)
+		case !isAllowedTag(name), len(open) >= maxHTMLNesting:
+		default:
+			out.WriteString("<" + name)
+			for _, attr := range attrs {
+				if value, ok := sanitizeHTMLAttr(name, attr[0], attr[1]); ok {
+					out.WriteString(" " + attr[0] + `="` + html.EscapeString(value) + `"`)
+				}
+			}
+			out.WriteString(">")
+			if !slices.Contains(voidHTMLTags, name) {
+				open = append(open, name)
+			}
+		}
+	}
+
+	for _, tag := range slices.Backward(open) {
+		out.WriteString("</" + tag + ">")
+	}
+	return out.String()
+}
+
+// SanitizedHTML returns the HTML to render the message with: the sanitized formatted body, or the escaped
+// plain text body with its line breaks if the message has no HTML.
+func (m MessageContent) SanitizedHTML() string {
+	if m.Format == "org.matrix.custom.html" && m.FormattedBody != "" {
+		return SanitizeHTML(m.FormattedBody)
+	}
+	return strings.ReplaceAll(html.EscapeString(m.Body), "\n", "<br>")
+}
+
+func isAllowedTag(name string) bool {
+	_, ok := allowedHTMLTags[name]
+	return ok
+}
+
+func lastIndex(tags []string, name string) int {
+	for i := len(tags) - 1; i >= 0; i-- {
+		if tags[i] == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseHTMLAttrs parses the attributes of a tag up to its end, returning them lowercased and unescaped.
+func parseHTMLAttrs(input string) ([][2]string, string) {
+	var attrs [][2]string
+	for {
+		match := htmlAttr.FindStringSubmatch(input)
+		if match == nil {
+			return attrs, skipPast(input, ">")
+		}
+		value := match[2] + match[3] + match[4]
+		attrs = append(attrs, [2]string{strings.ToLower(match[1]), html.UnescapeString(value)})
+		input = input[len(match[0]):]
+	}
+}
+
+func sanitizeHTMLAttr(tag, name, value string) (string, bool) {
+	if !slices.Contains(allowedHTMLTags[tag], name) {
+		return "", false
+	}
+
+	switch name {
+	case "color", "data-mx-color", "data-mx-bg-color":
+		return value, htmlColor.MatchString(value)
+	case "width", "height", "start":
+		return value, htmlNumber.MatchString(value)
+	case "class":
+		return value, htmlLanguage.MatchString(value)
+	case "href":
+		scheme, _, ok := strings.Cut(value, ":")
+		return value, ok && slices.Contains(linkSchemes, strings.ToLower(strings.TrimSpace(scheme)))
+	case "src":
+		_, err := ParseMXC(value)
+		return value, err == nil
+	}
+	return value, true
+}
+
+// skipElement skips the content of the element up to its end tag, counting the nested elements of the same name.
+func skipElement(input, name string) string {
+	depth := 1
+	for depth > 0 {
+		lt := strings.IndexByte(input, '<')
+		if lt < 0 {
+			return ""
+		}
+		input = input[lt:]
+
+		match := htmlTagName.FindStringSubmatch(input)
+		if match == nil || !strings.EqualFold(match[2], name) {
+			input = input[1:]
+			continue
+		}
+		if match[1] == "/" {
+			depth--
+		} else {
+			depth++
+		}
+		input = skipPast(input, ">")
+	}
+	return input
+}
+
+// skipPast returns what follows the first occurrence of end, nothing if there's none.
+func skipPast(input, end string) string {
+	i := strings.Index(input, end)
+	if i < 0 {
+		return ""
+	}
+	return input[i+len(end):]
+}
@@ -0,0 +1,78 @@
+package gomatrix_test
+
+import (
+	"strings"
+	"testing"
+
+	gomatrix "github.com/beldeveloper/go-matrix"
+)
+
+func TestSanitizeHTML(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "allowed tags", input: `<b>bold</b> <em>em</em><br>`, want: `<b>bold</b> <em>em</em><br>`},
+		{name: "text escaped", input: `a & b > c`, want: `a &amp; b &gt; c`},
+		{name: "unknown tag keeps its text", input: `<marquee>text</marquee>`, want: `text`},
+		{name: "script dropped with content", input: `a<script>alert("x")</script>b`, want: `ab`},
+		{name: "uppercase script", input: `a<SCRIPT>alert(1)</SCRIPT>b`, want: `ab`},
+		{name: "style dropped with content", input: `<style>p { color: red }</style><p>text</p>`, want: `<p>text</p>`},
+		{
+			name:  "reply fallback dropped",
+			input: `<mx-reply><blockquote><a href="https://matrix.to/#/!r:x/$e">In reply to</a></blockquote></mx-reply>answer`,
+			want:  `answer`,
+		},
+		{name: "nested dropped element", input: `<mx-reply><mx-reply>a</mx-reply>b</mx-reply>c`, want: `c`},
+		{name: "unclosed script", input: `a<script>alert(1)`, want: `a`},
+		{name: "https link", input: `<a href="https://example.org">x</a>`, want: `<a href="https://example.org">x</a>`},
+		{name: "javascript link", input: `<a href="javascript:alert(1)">x</a>`, want: `<a>x</a>`},
+		{name: "uppercase javascript link", input: `<a href="JavaScript:alert(1)">x</a>`, want: `<a>x</a>`},
+		{name: "javascript link with leading space", input: `<a href=" javascript:alert(1)">x</a>`, want: `<a>x</a>`},
+		{name: "javascript link with tab", input: "<a href=\"java\tscript:alert(1)\">x</a>", want: `<a>x</a>`},
+		{name: "javascript link with newline", input: "<a href=\"java\nscript:alert(1)\">x</a>", want: `<a>x</a>`},
+		{name: "javascript link with entities", input: `<a href="&#106;avascript:alert(1)">x</a>`, want: `<a>x</a>`},
+		{name: "javascript link with encoded tab", input: `<a href="java&#9;script:alert(1)">x</a>`, want: `<a>x</a>`},
+		{name: "relative link", input: `<a href="/path">x</a>`, want: `<a>x</a>`},
+		{name: "mxc image", input: `<img src="mxc://example.org/abc">`, want: `<img src="mxc://example.org/abc">`},
+		{name: "https image", input: `<img src="https://example.org/a.png" alt="a">`, want: `<img alt="a">`},
+		{name: "data image", input: `<img src="data:image/png;base64,AAAA">`, want: `<img>`},
+		{name: "event handler", input: `<b onclick="alert(1)">x</b>`, want: `<b>x</b>`},
+		{name: "unquoted attribute", input: `<font color=#ff0000>x</font>`, want: `<font color="#ff0000">x</font>`},
+		{name: "single quoted attribute", input: `<ol start='3'><li>x</li></ol>`, want: `<ol start="3"><li>x</li></ol>`},
+		{name: "invalid color", input: `<font color="red">x</font>`, want: `<font>x</font>`},
+		{name: "quote in attribute escaped", input: `<img alt='"><script>'>`, want: `<img alt="&#34;&gt;&lt;script&gt;">`},
+		{name: "attribute without value", input: `<details open>x</details>`, want: `<details>x</details>`},
+		{name: "unterminated attribute", input: `<a href="https://example.org>x`, want: `<a>x</a>`},
+		{name: "unterminated tag", input: `a<b`, want: `a<b></b>`},
+		{name: "lone less-than", input: `1 < 2`, want: `1 &lt; 2`},
+		{name: "unclosed tags closed", input: `<p><b>x`, want: `<p><b>x</b></p>`},
+		{name: "misnested tags", input: `<b><i>x</b>y</i>`, want: `<b><i>x</i></b>y`},
+		{name: "stray end tag", input: `x</p>`, want: `x`},
+		{name: "comment", input: `a<!-- <script>alert(1)</script> -->b`, want: `ab`},
+		{name: "unclosed comment", input: `a<!-- b`, want: `a`},
+		{name: "doctype", input: `<!DOCTYPE html>a`, want: `a`},
+		{name: "language class", input: `<code class="language-go">x</code>`, want: `<code class="language-go">x</code>`},
+		{name: "other class", input: `<code class="evil">x</code>`, want: `<code>x</code>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := gomatrix.SanitizeHTML(tt.input)
+			if got != tt.want {
+				t.Fatalf("SanitizeHTML(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeHTMLNesting(t *testing.T) {
+	input := strings.Repeat("<div>", 150) + "x" + strings.Repeat("</div>", 150)
+	want := strings.Repeat("<div>", 100) + "x" + strings.Repeat("</div>", 100)
+
+	got := gomatrix.SanitizeHTML(input)
+	if got != want {
+		t.Fatalf("got %d opening tags, want 100", strings.Count(got, "<div>"))
+	}
+}
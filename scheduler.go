@@ -0,0 +1,294 @@
+package gomatrix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ScheduleAccountDataType = "io.github.beldeveloper.gomatrix.schedules"
+	defaultScheduleCommand  = "!schedule"
+)
+
+type Schedule struct {
+	ID        string `json:"id"`
+	Cron      string `json:"cron"`
+	Text      string `json:"text"`
+	CreatedBy string `json:"created_by,omitempty"`
+}
+
+type SchedulerConfig struct {
+	// Command is the chat command prefix, "!schedule" by default.
+	Command string
+	// Admins are the users allowed to manage schedules via chat.
+	Admins []string
+	// Location is the time zone the cron expressions are evaluated in, the local one by default.
+	Location *time.Location
+	// OnError is called when a scheduled announcement fails to be sent.
+	OnError func(roomID string, schedule Schedule, err error)
+}
+
+type Scheduler struct {
+	client *Client
+	cfg    SchedulerConfig
+
+	mux   sync.Mutex
+	rooms map[string][]scheduledAnnouncement
+	// saveMux orders the changes of the schedules, saved without holding mux so the announcements
+	// and the listings don't wait for the server
+	saveMux sync.Mutex
+}
+
+type scheduledAnnouncement struct {
+	Schedule
+	cron Cron
+}
+
+func NewScheduler(client *Client, cfg SchedulerConfig) *Scheduler {
+	if cfg.Command == "" {
+		cfg.Command = defaultScheduleCommand
+	}
+	if cfg.Location == nil {
+		cfg.Location = time.Local
+	}
+
+	return &Scheduler{
+		client: client,
+		cfg:    cfg,
+		rooms:  make(map[string][]scheduledAnnouncement),
+	}
+}
+
+// LoadRoom reads the schedules persisted in the room account data and starts executing them.
+func (s *Scheduler) LoadRoom(ctx context.Context, roomID string) error {
+	s.saveMux.Lock()
+	defer s.saveMux.Unlock()
+
+	var data apiSchedules
+	err := s.client.GetRoomAccountData(ctx, roomID, ScheduleAccountDataType, &data)
+	if err != nil && !IsNotFound(err) {
+		return fmt.Errorf("failed to load schedules: %w", err)
+	}
+
+	entries := make([]scheduledAnnouncement, 0, len(data.Schedules))
+	for _, sch := range data.Schedules {
+		entry, err := s.parse(sch)
+		if err != nil {
+			return fmt.Errorf("invalid schedule %s: %w", sch.ID, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	s.mux.Lock()
+	s.rooms[roomID] = entries
+	s.mux.Unlock()
+
+	return nil
+}
+
+func (s *Scheduler) List(roomID string) []Schedule {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	schedules := make([]Schedule, 0, len(s.rooms[roomID]))
+	for _, entry := range s.rooms[roomID] {
+		schedules = append(schedules, entry.Schedule)
+	}
+	return schedules
+}
+
+func (s *Scheduler) Add(ctx context.Context, roomID string, sch Schedule) (Schedule, error) {
+	if sch.ID == "" {
+		sch.ID = uuid.NewString()[:8]
+	}
+	if strings.TrimSpace(sch.Text) == "" {
+		return Schedule{}, errors.New("announcement text is empty")
+	}
+
+	entry, err := s.parse(sch)
+	if err != nil {
+		return Schedule{}, err
+	}
+
+	s.saveMux.Lock()
+	defer s.saveMux.Unlock()
+
+	s.mux.Lock()
+	entries := append(slices.Clone(s.rooms[roomID]), entry)
+	s.mux.Unlock()
+
+	err = s.save(ctx, roomID, entries)
+	if err != nil {
+		return Schedule{}, err
+	}
+
+	return entry.Schedule, nil
+}
+
+func (s *Scheduler) Remove(ctx context.Context, roomID, id string) error {
+	s.saveMux.Lock()
+	defer s.saveMux.Unlock()
+
+	s.mux.Lock()
+	current := s.rooms[roomID]
+	s.mux.Unlock()
+
+	entries := slices.DeleteFunc(slices.Clone(current), func(entry scheduledAnnouncement) bool {
+		return entry.ID == id
+	})
+	if len(entries) == len(current) {
+		return fmt.Errorf("schedule %s not found", id)
+	}
+
+	return s.save(ctx, roomID, entries)
+}
+
+// Run sends the due announcements at the start of every minute until the context is done.
+func (s *Scheduler) Run(ctx context.Context) error {
+	for {
+		now := time.Now()
+		timer := time.NewTimer(now.Truncate(time.Minute).Add(time.Minute).Sub(now))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case t := <-timer.C:
+			s.fire(ctx, t)
+		}
+	}
+}
+
+// HandleEvent executes the schedule management command contained in the message event.
+// It reports whether the event was a scheduler command.
+func (s *Scheduler) HandleEvent(ctx context.Context, ev Event) (bool, error) {
+	if ev.Type != "m.room.message" {
+		return false, nil
+	}
+
+	var content MessageContent
+	if ev.ParseContent(&content) != nil {
+		return false, nil
+	}
+
+	args := strings.Fields(content.Body)
+	if len(args) == 0 || args[0] != s.cfg.Command {
+		return false, nil
+	}
+
+	if !slices.Contains(s.cfg.Admins, ev.Sender) {
+		_, err := s.client.SendNotice(ctx, ev.RoomID, "You are not allowed to manage schedules.")
+		return true, err
+	}
+
+	reply := s.execute(ctx, ev, args[1:])
+	_, err := s.client.SendNotice(ctx, ev.RoomID, reply)
+	return true, err
+}
+
+func (s *Scheduler) execute(ctx context.Context, ev Event, args []string) string {
+	usage := fmt.Sprintf(
+		"Usage:\n%[1]s add <minute> <hour> <day of month> <month> <day of week> <text>\n%[1]s list\n%[1]s remove <id>",
+		s.cfg.Command,
+	)
+
+	if len(args) == 0 {
+		return usage
+	}
+
+	switch args[0] {
+	case "add":
+		if len(args) < 7 {
+			return usage
+		}
+		sch, err := s.Add(ctx, ev.RoomID, Schedule{
+			Cron:      strings.Join(args[1:6], " "),
+			Text:      strings.Join(args[6:], " "),
+			CreatedBy: ev.Sender,
+		})
+		if err != nil {
+			return "Failed to add the schedule: " + err.Error()
+		}
+		return fmt.Sprintf("Schedule %s added.", sch.ID)
+	case "list":
+		schedules := s.List(ev.RoomID)
+		if len(schedules) == 0 {
+			return "No schedules in this room."
+		}
+		lines := make([]string, 0, len(schedules))
+		for _, sch := range schedules {
+			lines = append(lines, fmt.Sprintf("%s: %s %s", sch.ID, sch.Cron, sch.Text))
+		}
+		return strings.Join(lines, "\n")
+	case "remove":
+		if len(args) != 2 {
+			return usage
+		}
+		err := s.Remove(ctx, ev.RoomID, args[1])
+		if err != nil {
+			return "Failed to remove the schedule: " + err.Error()
+		}
+		return fmt.Sprintf("Schedule %s removed.", args[1])
+	}
+
+	return usage
+}
+
+func (s *Scheduler) fire(ctx context.Context, t time.Time) {
+	type job struct {
+		roomID   string
+		schedule Schedule
+	}
+
+	var due []job
+	s.mux.Lock()
+	for roomID, entries := range s.rooms {
+		for _, entry := range entries {
+			if entry.cron.Match(t) {
+				due = append(due, job{roomID: roomID, schedule: entry.Schedule})
+			}
+		}
+	}
+	s.mux.Unlock()
+
+	for _, j := range due {
+		_, err := s.client.SendText(ctx, j.roomID, j.schedule.Text)
+		if err != nil && s.cfg.OnError != nil {
+			s.cfg.OnError(j.roomID, j.schedule, err)
+		}
+	}
+}
+
+func (s *Scheduler) parse(sch Schedule) (scheduledAnnouncement, error) {
+	cron, err := ParseCron(sch.Cron)
+	if err != nil {
+		return scheduledAnnouncement{}, err
+	}
+	sch.Cron = cron.String()
+	return scheduledAnnouncement{Schedule: sch, cron: cron.In(s.cfg.Location)}, nil
+}
+
+// save stores the schedules of the room and starts executing them; s.saveMux must be held.
+func (s *Scheduler) save(ctx context.Context, roomID string, entries []scheduledAnnouncement) error {
+	data := apiSchedules{Schedules: make([]Schedule, 0, len(entries))}
+	for _, entry := range entries {
+		data.Schedules = append(data.Schedules, entry.Schedule)
+	}
+
+	err := s.client.SetRoomAccountData(ctx, roomID, ScheduleAccountDataType, data)
+	if err != nil {
+		return fmt.Errorf("failed to save schedules: %w", err)
+	}
+
+	s.mux.Lock()
+	s.rooms[roomID] = entries
+	s.mux.Unlock()
+	return nil
+}
@@ -0,0 +1,261 @@
+package gomatrix
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// https://spec.matrix.org/v1.13/client-server-api/#secret-storage
+const (
+	SecretStorageAlgorithm      = "m.secret_storage.v2.aes-hmac-sha2"
+	SecretStorageDefaultKeyType = "m.secret_storage.default_key"
+	secretStorageKeyTypePrefix  = "m.secret_storage.key."
+)
+
+type SecretStorageKey struct {
+	ID  string
+	Key []byte
+}
+
+type SecretStorageKeyDescription struct {
+	Name       string                   `json:"name,omitempty"`
+	Algorithm  string                   `json:"algorithm"`
+	Passphrase *SecretStoragePassphrase `json:"passphrase,omitempty"`
+	IV         string                   `json:"iv,omitempty"`
+	MAC        string                   `json:"mac,omitempty"`
+}
+
+type SecretStoragePassphrase struct {
+	Algorithm  string `json:"algorithm"`
+	Salt       string `json:"salt"`
+	Iterations int    `json:"iterations"`
+	Bits       int    `json:"bits,omitempty"`
+}
+
+type encryptedSecret struct {
+	IV         string `json:"iv"`
+	Ciphertext string `json:"ciphertext"`
+	MAC        string `json:"mac"`
+}
+
+type apiSecret struct {
+	Encrypted map[string]encryptedSecret `json:"encrypted"`
+}
+
+type apiDefaultKey struct {
+	Key string `json:"key"`
+}
+
+// GenerateSecretStorageKey creates a random secret storage key.
+func GenerateSecretStorageKey() (SecretStorageKey, error) {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	if err != nil {
+		return SecretStorageKey{}, fmt.Errorf("failed to generate the key: %w", err)
+	}
+	id := make([]byte, 24)
+	_, err = rand.Read(id)
+	if err != nil {
+		return SecretStorageKey{}, fmt.Errorf("failed to generate the key ID: %w", err)
+	}
+	return SecretStorageKey{ID: base64.RawURLEncoding.EncodeToString(id), Key: key}, nil
+}
+
+// AddSecretStorageKey publishes the description of the key letting other clients check the key is right,
+// and makes it the default one if requested.
+func (c *Client) AddSecretStorageKey(
+	ctx context.Context, key SecretStorageKey, passphrase *SecretStoragePassphrase, makeDefault bool,
+) error {
+	// the MAC of encrypted zeros proves the key without revealing it
+	iv, _, mac, err := encryptSecret(key.Key, "", make([]byte, 32))
+	if err != nil {
+		return err
+	}
+
+	err = c.SetAccountData(ctx, secretStorageKeyTypePrefix+key.ID, SecretStorageKeyDescription{
+		Algorithm:  SecretStorageAlgorithm,
+		Passphrase: passphrase,
+		IV:         iv,
+		MAC:        mac,
+	})
+	if err != nil {
+		return err
+	}
+
+	if !makeDefault {
+		return nil
+	}
+	return c.SetAccountData(ctx, SecretStorageDefaultKeyType, apiDefaultKey{Key: key.ID})
+}
+
+// GetDefaultSecretStorageKeyID returns the ID of the default secret storage key, empty if there is none.
+func (c *Client) GetDefaultSecretStorageKeyID(ctx context.Context) (string, error) {
+	var data apiDefaultKey
+	err := c.GetAccountData(ctx, SecretStorageDefaultKeyType, &data)
+	if err != nil && !IsNotFound(err) {
+		return "", err
+	}
+	return data.Key, nil
+}
+
+// CheckSecretStorageKey reports whether the key matches its published description.
+func (c *Client) CheckSecretStorageKey(ctx context.Context, key SecretStorageKey) (bool, error) {
+	var desc SecretStorageKeyDescription
+	err := c.GetAccountData(ctx, secretStorageKeyTypePrefix+key.ID, &desc)
+	if err != nil {
+		return false, err
+	}
+	if desc.IV == "" || desc.MAC == "" {
+		// the old key descriptions can't be checked
+		return true, nil
+	}
+
+	// the description comes from the server, so it's checked before use
+	ivBytes, err := decodeBase64(desc.IV)
+	if err != nil {
+		return false, fmt.Errorf("failed to decode the key IV: %w", err)
+	}
+	if len(ivBytes) != aes.BlockSize {
+		return false, errors.New("invalid key IV size")
+	}
+	sum, err := decodeBase64(desc.MAC)
+	if err != nil {
+		return false, fmt.Errorf("failed to decode the key MAC: %w", err)
+	}
+
+	_, _, mac, err := encryptSecretWithIV(key.Key, "", make([]byte, 32), ivBytes)
+	if err != nil {
+		return false, err
+	}
+	macBytes, err := decodeBase64(mac)
+	if err != nil {
+		return false, fmt.Errorf("failed to decode the key MAC: %w", err)
+	}
+	return hmac.Equal(macBytes, sum), nil
+}
+
+// StoreSecret encrypts the secret with the key and saves it in the account data under the secret name.
+func (c *Client) StoreSecret(ctx context.Context, name string, secret []byte, key SecretStorageKey) error {
+	iv, ciphertext, mac, err := encryptSecret(key.Key, name, secret)
+	if err != nil {
+		return err
+	}
+
+	var data apiSecret
+	err = c.GetAccountData(ctx, name, &data)
+	if err != nil && !IsNotFound(err) {
+		return err
+	}
+	if data.Encrypted == nil {
+		data.Encrypted = make(map[string]encryptedSecret)
+	}
+	data.Encrypted[key.ID] = encryptedSecret{IV: iv, Ciphertext: ciphertext, MAC: mac}
+
+	return c.SetAccountData(ctx, name, data)
+}
+
+// GetSecret decrypts the secret stored under the name with the key.
+func (c *Client) GetSecret(ctx context.Context, name string, key SecretStorageKey) ([]byte, error) {
+	var data apiSecret
+	err := c.GetAccountData(ctx, name, &data)
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, ok := data.Encrypted[key.ID]
+	if !ok {
+		return nil, fmt.Errorf("secret %s is not encrypted with key %s", name, key.ID)
+	}
+	return decryptSecret(key.Key, name, encrypted)
+}
+
+func encryptSecret(key []byte, name string, secret []byte) (iv, ciphertext, mac string, err error) {
+	ivBytes := make([]byte, aes.BlockSize)
+	_, err = rand.Read(ivBytes)
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to generate the IV: %w", err)
+	}
+	// clearing bit 63 leaves room for the counter, as other implementations expect
+	ivBytes[8] &= 0x7f
+
+	return encryptSecretWithIV(key, name, secret, ivBytes)
+}
+
+func encryptSecretWithIV(key []byte, name string, secret, iv []byte) (string, string, string, error) {
+	aesKey, hmacKey, err := deriveSecretKeys(key, name)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	block, err := aes.NewCipher(aesKey)
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to create the cipher: %w", err)
+	}
+	if len(iv) != aes.BlockSize {
+		return "", "", "", errors.New("invalid IV size")
+	}
+	ciphertext := make([]byte, len(secret))
+	cipher.NewCTR(block, iv).XORKeyStream(ciphertext, secret)
+
+	mac := hmac.New(sha256.New, hmacKey)
+	mac.Write(ciphertext)
+
+	return base64.StdEncoding.EncodeToString(iv),
+		base64.StdEncoding.EncodeToString(ciphertext),
+		base64.StdEncoding.EncodeToString(mac.Sum(nil)),
+		nil
+}
+
+func decryptSecret(key []byte, name string, encrypted encryptedSecret) ([]byte, error) {
+	aesKey, hmacKey, err := deriveSecretKeys(key, name)
+	if err != nil {
+		return nil, err
+	}
+
+	iv, err := decodeBase64(encrypted.IV)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the IV: %w", err)
+	}
+	ciphertext, err := decodeBase64(encrypted.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the ciphertext: %w", err)
+	}
+	sum, err := decodeBase64(encrypted.MAC)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the MAC: %w", err)
+	}
+
+	mac := hmac.New(sha256.New, hmacKey)
+	mac.Write(ciphertext)
+	if !hmac.Equal(mac.Sum(nil), sum) {
+		return nil, errors.New("wrong secret storage key or corrupted secret")
+	}
+
+	block, err := aes.NewCipher(aesKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the cipher: %w", err)
+	}
+	if len(iv) != aes.BlockSize {
+		return nil, errors.New("invalid IV size")
+	}
+	secret := make([]byte, len(ciphertext))
+	cipher.NewCTR(block, iv).XORKeyStream(secret, ciphertext)
+	return secret, nil
+}
+
+func deriveSecretKeys(key []byte, name string) (aesKey, hmacKey []byte, err error) {
+	derived := make([]byte, 64)
+	_, err = io.ReadFull(hkdf(sha256.New, key, make([]byte, 32), []byte(name)), derived)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to derive the keys: %w", err)
+	}
+	return derived[:32], derived[32:], nil
+}
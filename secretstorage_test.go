@@ -0,0 +1,111 @@
+package gomatrix_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	gomatrix "github.com/beldeveloper/go-matrix"
+)
+
+// newAccountDataClient returns a client of a homeserver keeping the account data in memory.
+func newAccountDataClient(t *testing.T) (*gomatrix.Client, map[string]string) {
+	var mux sync.Mutex
+	data := make(map[string]string)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, eventType, ok := strings.Cut(r.URL.Path, "/account_data/")
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		mux.Lock()
+		defer mux.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			data[eventType] = string(body)
+			_, _ = w.Write([]byte(`{}`))
+		case http.MethodGet:
+			content, ok := data[eventType]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"errcode":"M_NOT_FOUND","error":"not found"}`))
+				return
+			}
+			_, _ = w.Write([]byte(content))
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := gomatrix.NewClientWithConfig(gomatrix.Config{Credentials: gomatrix.Credentials{
+		Server: srv.URL, User: "@bot:example.org", AccessToken: "token",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, data
+}
+
+func TestCheckSecretStorageKey(t *testing.T) {
+	key, err := gomatrix.GenerateSecretStorageKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := gomatrix.GenerateSecretStorageKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other.ID = key.ID
+
+	tests := []struct {
+		name    string
+		key     gomatrix.SecretStorageKey
+		tamper  func(desc *gomatrix.SecretStorageKeyDescription)
+		want    bool
+		wantErr bool
+	}{
+		{name: "right key", key: key, want: true},
+		{name: "wrong key", key: other, want: false},
+		{name: "legacy description", key: other, tamper: func(desc *gomatrix.SecretStorageKeyDescription) { desc.IV, desc.MAC = "", "" }, want: true},
+		{name: "short IV", key: key, tamper: func(desc *gomatrix.SecretStorageKeyDescription) { desc.IV = "AAAA" }, wantErr: true},
+		{name: "long IV", key: key, tamper: func(desc *gomatrix.SecretStorageKeyDescription) { desc.IV = strings.Repeat("A", 44) }, wantErr: true},
+		{name: "IV not base64", key: key, tamper: func(desc *gomatrix.SecretStorageKeyDescription) { desc.IV = "!!!" }, wantErr: true},
+		{name: "MAC not base64", key: key, tamper: func(desc *gomatrix.SecretStorageKeyDescription) { desc.MAC = "!!!" }, wantErr: true},
+		{name: "truncated MAC", key: key, tamper: func(desc *gomatrix.SecretStorageKeyDescription) { desc.MAC = desc.MAC[:8] }, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			client, data := newAccountDataClient(t)
+			err := client.AddSecretStorageKey(ctx, key, nil, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.tamper != nil {
+				eventType := "m.secret_storage.key." + key.ID
+				var desc gomatrix.SecretStorageKeyDescription
+				_ = json.Unmarshal([]byte(data[eventType]), &desc)
+				tt.tamper(&desc)
+				content, _ := json.Marshal(desc)
+				data[eventType] = string(content)
+			}
+
+			ok, err := client.CheckSecretStorageKey(ctx, tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %t", err, tt.wantErr)
+			}
+			if ok != tt.want {
+				t.Fatalf("got %t, want %t", ok, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package gomatrix
+
+import (
+	"context"
+	"sync"
+)
+
+// sendQueue serializes the sends to the same room in the order they were enqueued,
+// while the sends to different rooms still run concurrently.
+type sendQueue struct {
+	mux  sync.Mutex
+	tail map[string]chan struct{}
+}
+
+func newSendQueue() *sendQueue {
+	return &sendQueue{tail: make(map[string]chan struct{})}
+}
+
+func (q *sendQueue) Do(ctx context.Context, roomID string, fn func() error) error {
+	if q == nil {
+		return fn()
+	}
+
+	done := make(chan struct{})
+
+	q.mux.Lock()
+	prev := q.tail[roomID]
+	q.tail[roomID] = done
+	q.mux.Unlock()
+
+	if prev != nil {
+		select {
+		case <-prev:
+		case <-ctx.Done():
+			// keep the chain intact for the sends enqueued after this one
+			go func() {
+				<-prev
+				q.release(roomID, done)
+			}()
+			return ctx.Err()
+		}
+	}
+
+	defer q.release(roomID, done)
+	return fn()
+}
+
+func (q *sendQueue) release(roomID string, done chan struct{}) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+
+	close(done)
+	if q.tail[roomID] == done {
+		delete(q.tail, roomID)
+	}
+}
@@ -0,0 +1,67 @@
+package gomatrix
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// https://spec.matrix.org/v1.13/client-server-api/#room-tagging
+const (
+	TagsAccountDataType = "m.tag"
+	// TagServerNotice marks the room the server operators send notices to the user in.
+	// https://spec.matrix.org/v1.13/client-server-api/#server-notices
+	TagServerNotice = "m.server_notice"
+)
+
+type Tag struct {
+	Order *float64 `json:"order,omitempty"`
+}
+
+type TagsContent struct {
+	Tags map[string]Tag `json:"tags"`
+}
+
+// Tags returns the tags of the room; ok is false if they haven't changed since the previous sync.
+func (r JoinedRoom) Tags() (tags map[string]Tag, ok bool) {
+	for _, ev := range r.AccountData.Events {
+		var content TagsContent
+		if ev.Type == TagsAccountDataType && ev.ParseContent(&content) == nil {
+			tags, ok = content.Tags, true
+		}
+	}
+	return tags, ok
+}
+
+func (c *Client) GetRoomTags(ctx context.Context, roomID string) (map[string]Tag, error) {
+	userID, err := c.WhoAmI(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var respData TagsContent
+	path := fmt.Sprintf("/_matrix/client/v3/user/%s/rooms/%s/tags", url.PathEscape(userID), url.PathEscape(roomID))
+	err = c.doJSON(ctx, http.MethodGet, path, nil, &respData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the room tags: %w", err)
+	}
+	return respData.Tags, nil
+}
+
+// IsServerNoticeRoom reports whether the joined room is tagged as the server notices room. The tags come from
+// the syncs seen by Listen; those of a room not tagged by them yet, e.g. after resuming from a stored sync
+// position, are fetched once.
+func (c *Client) IsServerNoticeRoom(ctx context.Context, roomID string) (bool, error) {
+	tags, ok := c.summaries.tags(roomID)
+	if !ok {
+		var err error
+		tags, err = c.GetRoomTags(ctx, roomID)
+		if err != nil {
+			return false, err
+		}
+		c.summaries.setTags(roomID, tags)
+	}
+	_, ok = tags[TagServerNotice]
+	return ok, nil
+}
@@ -1,10 +1,46 @@
 package gomatrix
 
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"sync"
+	"time"
+)
+
+// SessionVersion is the current version of the stored sessions. The client upgrades the sessions stored
+// with an older version, the ones stored before the versioning having version 0, and saves them back.
+const SessionVersion = 1
+
 type Session struct {
+	Version     int    `json:"version,omitempty"`
 	AccessToken string `json:"access_token"`
 	DeviceID    string `json:"device_id"`
+	// RefreshToken renews the expiring access token.
+	RefreshToken string `json:"refresh_token,omitempty"`
+	// ExpiresAt is the expiry of the access token, zero if it doesn't expire.
+	ExpiresAt time.Time `json:"expires_at"`
+	UserID    string    `json:"user_id,omitempty"`
+	// Homeserver is the base URL of the homeserver the session belongs to.
+	Homeserver string `json:"homeserver,omitempty"`
+	// TokenOrigin is the hash of the configured access token the session started from, so the client notices
+	// the configuration being given another token; empty if the session started from a login.
+	TokenOrigin string `json:"token_origin,omitempty"`
 }
 
+// tokenOrigin returns the TokenOrigin of the sessions started from the configured access token, if any.
+func (c Credentials) tokenOrigin() string {
+	token := c.AccessToken
+	if token == "" && c.OAuth != nil {
+		token = c.OAuth.Token.AccessToken
+	}
+	if token == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(token))
+	return base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+// SessionStorage persists the login of the client; the sessions are meant to be stored as JSON.
 type SessionStorage interface {
 	Set(session Session) error
 	Get() (Session, error)
@@ -26,3 +62,37 @@ func (s *InMemorySessionStorage) Set(session Session) error {
 func (s *InMemorySessionStorage) Get() (Session, error) {
 	return s.session, nil
 }
+
+// SyncState is the sync position of Listen which lets a restarted client resume where it left off.
+type SyncState struct {
+	NextBatch        string `json:"next_batch"`
+	FilterID         string `json:"filter_id,omitempty"`
+	FilterDefinition string `json:"filter_definition,omitempty"`
+}
+
+type SyncStore interface {
+	SetSyncState(state SyncState) error
+	GetSyncState() (SyncState, error)
+}
+
+type InMemorySyncStore struct {
+	mux   sync.Mutex
+	state SyncState
+}
+
+func NewInMemorySyncStore() *InMemorySyncStore {
+	return &InMemorySyncStore{}
+}
+
+func (s *InMemorySyncStore) SetSyncState(state SyncState) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.state = state
+	return nil
+}
+
+func (s *InMemorySyncStore) GetSyncState() (SyncState, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.state, nil
+}
@@ -0,0 +1,114 @@
+package gomatrix
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// SignJSON signs the JSON object with the ed25519 key and returns it with the signature added
+// under signatures.<userID>.<keyID>, keeping the existing signatures.
+// https://spec.matrix.org/v1.13/appendices/#signing-json
+func SignJSON(data []byte, userID, keyID string, key ed25519.PrivateKey) ([]byte, error) {
+	obj, signed, err := signedPart(data)
+	if err != nil {
+		return nil, err
+	}
+
+	signatures := map[string]map[string]string{}
+	if raw, ok := obj["signatures"]; ok {
+		err = json.Unmarshal(raw, &signatures)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal the signatures: %w", err)
+		}
+	}
+	if signatures[userID] == nil {
+		signatures[userID] = map[string]string{}
+	}
+	signatures[userID][keyID] = base64.RawStdEncoding.EncodeToString(ed25519.Sign(key, signed))
+
+	obj["signatures"], err = json.Marshal(signatures)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal the signatures: %w", err)
+	}
+	return json.Marshal(obj)
+}
+
+// VerifySignature checks the JSON object carries a valid signature of the user made with the ed25519 key.
+func VerifySignature(data []byte, userID, keyID string, key ed25519.PublicKey) error {
+	obj, signed, err := signedPart(data)
+	if err != nil {
+		return err
+	}
+
+	var signatures map[string]map[string]string
+	err = json.Unmarshal(obj["signatures"], &signatures)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal the signatures: %w", err)
+	}
+
+	signature, ok := signatures[userID][keyID]
+	if !ok {
+		return fmt.Errorf("no signature of %s by %s", userID, keyID)
+	}
+	sig, err := decodeBase64(signature)
+	if err != nil {
+		return fmt.Errorf("failed to decode the signature: %w", err)
+	}
+	if len(key) != ed25519.PublicKeySize || !ed25519.Verify(key, signed, sig) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+// ParseEd25519Key decodes an unpadded base64 ed25519 public key as published in the device keys.
+func ParseEd25519Key(s string) (ed25519.PublicKey, error) {
+	key, err := decodeBase64(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the key: %w", err)
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid ed25519 key size")
+	}
+	return key, nil
+}
+
+// signedPart returns the object and the canonical JSON of it without the signatures and unsigned data.
+func signedPart(data []byte) (map[string]json.RawMessage, []byte, error) {
+	var obj map[string]json.RawMessage
+	err := json.Unmarshal(data, &obj)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal the signed object: %w", err)
+	}
+
+	stripped := make(map[string]json.RawMessage, len(obj))
+	for key, value := range obj {
+		if key != "signatures" && key != "unsigned" {
+			stripped[key] = value
+		}
+	}
+
+	raw, err := json.Marshal(stripped)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal the signed object: %w", err)
+	}
+	signed, err := CanonicalJSON(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	return obj, signed, nil
+}
+
+// decodeBase64 accepts both unpadded and padded base64, as some implementations pad the values despite the spec.
+func decodeBase64(s string) ([]byte, error) {
+	return base64.RawStdEncoding.DecodeString(trimPadding(s))
+}
+
+func trimPadding(s string) string {
+	for len(s) > 0 && s[len(s)-1] == '=' {
+		s = s[:len(s)-1]
+	}
+	return s
+}
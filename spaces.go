@@ -0,0 +1,193 @@
+package gomatrix
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+)
+
+// https://spec.matrix.org/v1.13/client-server-api/#spaces
+const (
+	RoomTypeSpace = "m.space"
+
+	SpaceChildEventType  = "m.space.child"
+	SpaceParentEventType = "m.space.parent"
+)
+
+// SpaceChildContent links a room to the space; the link is removed by setting empty content, without Via.
+type SpaceChildContent struct {
+	// Via are the servers to join the room through.
+	Via []string `json:"via,omitempty"`
+	// Order sorts the children lexicographically, the rooms without it coming last.
+	Order     string `json:"order,omitempty"`
+	Suggested bool   `json:"suggested,omitempty"`
+}
+
+type SpaceParentContent struct {
+	Via       []string `json:"via,omitempty"`
+	Canonical bool     `json:"canonical,omitempty"`
+}
+
+// SpaceHierarchyRoom is a room of the space hierarchy with the m.space.child events of the spaces.
+type SpaceHierarchyRoom struct {
+	PublicRoomSummary
+	ChildrenState []Event `json:"children_state"`
+}
+
+type SpaceHierarchyOptions struct {
+	// MaxDepth limits how deep the hierarchy is walked, unlimited if zero.
+	MaxDepth      int
+	SuggestedOnly bool
+}
+
+type apiSpaceHierarchyResp struct {
+	Rooms     []SpaceHierarchyRoom `json:"rooms"`
+	NextBatch string               `json:"next_batch,omitempty"`
+}
+
+// GetSpaceHierarchy returns the space and the rooms and spaces it contains, as seen by the homeserver,
+// paginating through the whole hierarchy; SpaceRooms iterates over it page by page.
+// https://spec.matrix.org/v1.13/client-server-api/#get_matrixclientv1roomsroomidhierarchy
+func (c *Client) GetSpaceHierarchy(ctx context.Context, spaceID string, opts SpaceHierarchyOptions) ([]SpaceHierarchyRoom, error) {
+	var rooms []SpaceHierarchyRoom
+	for room, err := range c.SpaceRooms(ctx, spaceID, opts) {
+		if err != nil {
+			return nil, err
+		}
+		rooms = append(rooms, room)
+	}
+	return rooms, nil
+}
+
+func (c *Client) getSpaceHierarchyPage(
+	ctx context.Context, spaceID string, opts SpaceHierarchyOptions, from string,
+) (apiSpaceHierarchyResp, error) {
+	query := url.Values{}
+	if opts.MaxDepth > 0 {
+		query.Set("max_depth", strconv.Itoa(opts.MaxDepth))
+	}
+	if opts.SuggestedOnly {
+		query.Set("suggested_only", "true")
+	}
+	if from != "" {
+		query.Set("from", from)
+	}
+
+	var respData apiSpaceHierarchyResp
+	path := fmt.Sprintf("/_matrix/client/v1/rooms/%s/hierarchy", url.PathEscape(spaceID))
+	err := c.doJSON(ctx, http.MethodGet, withQuery(path, query), nil, &respData)
+	if err != nil {
+		return apiSpaceHierarchyResp{}, fmt.Errorf("failed to get the space hierarchy: %w", err)
+	}
+	return respData, nil
+}
+
+// SpaceTree maps the spaces to their children, keyed by room ID.
+type SpaceTree map[string]map[string]SpaceChildContent
+
+// GetSpaceTree returns the tree of the space and its subspaces.
+func (c *Client) GetSpaceTree(ctx context.Context, spaceID string) (SpaceTree, error) {
+	rooms, err := c.GetSpaceHierarchy(ctx, spaceID, SpaceHierarchyOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	tree := make(SpaceTree)
+	for _, room := range rooms {
+		if room.RoomType != RoomTypeSpace {
+			continue
+		}
+
+		children := make(map[string]SpaceChildContent)
+		for _, ev := range room.ChildrenState {
+			var child SpaceChildContent
+			if ev.Type == SpaceChildEventType && ev.ParseContent(&child) == nil && len(child.Via) > 0 {
+				children[ev.GetStateKey()] = child
+			}
+		}
+		tree[room.RoomID] = children
+	}
+	return tree, nil
+}
+
+// Descendants returns the rooms and spaces contained in the space, directly or through subspaces, sorted.
+// A space nested in itself, directly or not, is walked once.
+func (t SpaceTree) Descendants(spaceID string) []string {
+	seen := map[string]bool{spaceID: true}
+	var descendants []string
+	queue := []string{spaceID}
+	for len(queue) > 0 {
+		space := queue[0]
+		queue = queue[1:]
+		for roomID := range t[space] {
+			if seen[roomID] {
+				continue
+			}
+			seen[roomID] = true
+			descendants = append(descendants, roomID)
+			queue = append(queue, roomID)
+		}
+	}
+
+	slices.Sort(descendants)
+	return descendants
+}
+
+// SpaceLink is an m.space.child event of a space.
+type SpaceLink struct {
+	SpaceID string
+	RoomID  string
+	Content SpaceChildContent
+}
+
+// SpaceDiff is the changes turning a space tree into another.
+type SpaceDiff struct {
+	Add    []SpaceLink
+	Update []SpaceLink
+	Remove []SpaceLink
+}
+
+func (d SpaceDiff) Empty() bool {
+	return len(d.Add) == 0 && len(d.Update) == 0 && len(d.Remove) == 0
+}
+
+// DiffSpaceTree returns the changes turning the actual tree into the desired one. Only the spaces of the desired
+// tree are compared, so the children of the spaces missing from it are left alone; a desired space with no
+// children has all its children removed.
+func DiffSpaceTree(actual, desired SpaceTree) SpaceDiff {
+	var diff SpaceDiff
+	for _, spaceID := range slices.Sorted(maps.Keys(desired)) {
+		children := actual[spaceID]
+		for _, roomID := range slices.Sorted(maps.Keys(desired[spaceID])) {
+			want := desired[spaceID][roomID]
+			link := SpaceLink{SpaceID: spaceID, RoomID: roomID, Content: want}
+			if have, ok := children[roomID]; !ok {
+				diff.Add = append(diff.Add, link)
+			} else if !slices.Equal(have.Via, want.Via) || have.Order != want.Order || have.Suggested != want.Suggested {
+				diff.Update = append(diff.Update, link)
+			}
+		}
+		for _, roomID := range slices.Sorted(maps.Keys(children)) {
+			if _, ok := desired[spaceID][roomID]; !ok {
+				diff.Remove = append(diff.Remove, SpaceLink{SpaceID: spaceID, RoomID: roomID})
+			}
+		}
+	}
+	return diff
+}
+
+// ApplySpaceDiff sets the m.space.child events of the diff, stopping at the first failure. The rooms added to
+// the spaces don't get an m.space.parent event; set one with SetStateEvent to make a space their canonical parent.
+func (c *Client) ApplySpaceDiff(ctx context.Context, diff SpaceDiff) error {
+	for _, link := range slices.Concat(diff.Add, diff.Update, diff.Remove) {
+		_, err := c.SetStateEvent(ctx, link.SpaceID, SpaceChildEventType, link.RoomID, link.Content)
+		if err != nil {
+			return fmt.Errorf("failed to link %s to the space %s: %w", link.RoomID, link.SpaceID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+package gomatrix
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var cryptoSchema = []string{
+	`CREATE TABLE IF NOT EXISTS crypto_account (
+		id INTEGER PRIMARY KEY CHECK (id = 0),
+		pickle BLOB NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS crypto_devices (
+		user_id TEXT NOT NULL,
+		device_id TEXT NOT NULL,
+		keys TEXT NOT NULL,
+		PRIMARY KEY (user_id, device_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS crypto_inbound_sessions (
+		room_id TEXT NOT NULL,
+		sender_key TEXT NOT NULL,
+		session_id TEXT NOT NULL,
+		session TEXT NOT NULL,
+		PRIMARY KEY (room_id, sender_key, session_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS crypto_outbound_sessions (
+		room_id TEXT PRIMARY KEY,
+		session TEXT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS crypto_trust (
+		user_id TEXT NOT NULL,
+		device_id TEXT NOT NULL,
+		trust TEXT NOT NULL,
+		PRIMARY KEY (user_id, device_id)
+	)`,
+}
+
+// SQLCryptoStore is a CryptoStore in a SQLite database, each change writing only the rows it affects.
+// The database is opened by the caller with the driver of their choice, e.g. modernc.org/sqlite
+// or github.com/mattn/go-sqlite3, so the client doesn't depend on one.
+type SQLCryptoStore struct {
+	db *sql.DB
+}
+
+// NewSQLCryptoStore creates the tables of the store in the database unless they exist.
+func NewSQLCryptoStore(ctx context.Context, db *sql.DB) (*SQLCryptoStore, error) {
+	for _, stmt := range cryptoSchema {
+		_, err := db.ExecContext(ctx, stmt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the crypto store tables: %w", err)
+		}
+	}
+	return &SQLCryptoStore{db: db}, nil
+}
+
+func (s *SQLCryptoStore) PutAccount(pickle []byte) error {
+	_, err := s.db.Exec(`INSERT INTO crypto_account (id, pickle) VALUES (0, ?)
+		ON CONFLICT (id) DO UPDATE SET pickle = excluded.pickle`, pickle)
+	if err != nil {
+		return fmt.Errorf("failed to store the account: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLCryptoStore) GetAccount() ([]byte, error) {
+	var pickle []byte
+	err := s.db.QueryRow(`SELECT pickle FROM crypto_account WHERE id = 0`).Scan(&pickle)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the account: %w", err)
+	}
+	return pickle, nil
+}
+
+func (s *SQLCryptoStore) PutDevices(userID string, devices map[string]DeviceKeys) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to store the devices: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM crypto_devices WHERE user_id = ?`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to store the devices: %w", err)
+	}
+	for deviceID, keys := range devices {
+		data, err := json.Marshal(keys)
+		if err != nil {
+			return fmt.Errorf("failed to marshal the device keys: %w", err)
+		}
+		_, err = tx.Exec(`INSERT INTO crypto_devices (user_id, device_id, keys) VALUES (?, ?, ?)`, userID, deviceID, string(data))
+		if err != nil {
+			return fmt.Errorf("failed to store the devices: %w", err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to store the devices: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLCryptoStore) GetDevices(userID string) (map[string]DeviceKeys, error) {
+	rows, err := s.db.Query(`SELECT device_id, keys FROM crypto_devices WHERE user_id = ?`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the devices: %w", err)
+	}
+	defer rows.Close()
+
+	devices := make(map[string]DeviceKeys)
+	for rows.Next() {
+		var deviceID, data string
+		err = rows.Scan(&deviceID, &data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the devices: %w", err)
+		}
+		var keys DeviceKeys
+		err = json.Unmarshal([]byte(data), &keys)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal the device keys: %w", err)
+		}
+		devices[deviceID] = keys
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load the devices: %w", err)
+	}
+	if len(devices) == 0 {
+		return nil, nil
+	}
+	return devices, nil
+}
+
+func (s *SQLCryptoStore) PutInboundGroupSession(session InboundGroupSession) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the inbound group session: %w", err)
+	}
+	_, err = s.db.Exec(`INSERT INTO crypto_inbound_sessions (room_id, sender_key, session_id, session) VALUES (?, ?, ?, ?)
+		ON CONFLICT (room_id, sender_key, session_id) DO UPDATE SET session = excluded.session`,
+		session.RoomID, session.SenderKey, session.SessionID, string(data))
+	if err != nil {
+		return fmt.Errorf("failed to store the inbound group session: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLCryptoStore) GetInboundGroupSession(roomID, senderKey, sessionID string) (InboundGroupSession, bool, error) {
+	var session InboundGroupSession
+	ok, err := s.getJSON(&session, `SELECT session FROM crypto_inbound_sessions
+		WHERE room_id = ? AND sender_key = ? AND session_id = ?`, roomID, senderKey, sessionID)
+	if err != nil {
+		return InboundGroupSession{}, false, fmt.Errorf("failed to load the inbound group session: %w", err)
+	}
+	return session, ok, nil
+}
+
+func (s *SQLCryptoStore) PutOutboundGroupSession(session OutboundGroupSession) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the outbound group session: %w", err)
+	}
+	_, err = s.db.Exec(`INSERT INTO crypto_outbound_sessions (room_id, session) VALUES (?, ?)
+		ON CONFLICT (room_id) DO UPDATE SET session = excluded.session`, session.RoomID, string(data))
+	if err != nil {
+		return fmt.Errorf("failed to store the outbound group session: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLCryptoStore) GetOutboundGroupSession(roomID string) (OutboundGroupSession, bool, error) {
+	var session OutboundGroupSession
+	ok, err := s.getJSON(&session, `SELECT session FROM crypto_outbound_sessions WHERE room_id = ?`, roomID)
+	if err != nil {
+		return OutboundGroupSession{}, false, fmt.Errorf("failed to load the outbound group session: %w", err)
+	}
+	return session, ok, nil
+}
+
+func (s *SQLCryptoStore) DeleteOutboundGroupSession(roomID string) error {
+	_, err := s.db.Exec(`DELETE FROM crypto_outbound_sessions WHERE room_id = ?`, roomID)
+	if err != nil {
+		return fmt.Errorf("failed to delete the outbound group session: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLCryptoStore) SetTrust(userID, deviceID string, trust TrustState) error {
+	var device DeviceKeys
+	ok, err := s.getJSON(&device, `SELECT keys FROM crypto_devices WHERE user_id = ? AND device_id = ?`, userID, deviceID)
+	if err != nil {
+		return fmt.Errorf("failed to load the device: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("unknown device %s of %s", deviceID, userID)
+	}
+
+	data, err := json.Marshal(newDeviceTrust(device, trust))
+	if err != nil {
+		return fmt.Errorf("failed to marshal the trust: %w", err)
+	}
+	_, err = s.db.Exec(`INSERT INTO crypto_trust (user_id, device_id, trust) VALUES (?, ?, ?)
+		ON CONFLICT (user_id, device_id) DO UPDATE SET trust = excluded.trust`, userID, deviceID, string(data))
+	if err != nil {
+		return fmt.Errorf("failed to store the trust: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLCryptoStore) GetTrust(userID, deviceID string) (TrustState, error) {
+	var trust, keys string
+	err := s.db.QueryRow(`SELECT t.trust, d.keys FROM crypto_trust t
+		JOIN crypto_devices d ON d.user_id = t.user_id AND d.device_id = t.device_id
+		WHERE t.user_id = ? AND t.device_id = ?`, userID, deviceID).Scan(&trust, &keys)
+	if errors.Is(err, sql.ErrNoRows) {
+		return TrustUnset, nil
+	}
+	if err != nil {
+		return TrustUnset, fmt.Errorf("failed to load the trust: %w", err)
+	}
+
+	var deviceTrust DeviceTrust
+	var device DeviceKeys
+	if json.Unmarshal([]byte(trust), &deviceTrust) != nil || json.Unmarshal([]byte(keys), &device) != nil {
+		return TrustUnset, errors.New("failed to unmarshal the trust")
+	}
+	return deviceTrust.For(device), nil
+}
+
+// getJSON scans the JSON column of the single row of the query into v, reporting whether there's a row.
+func (s *SQLCryptoStore) getJSON(v any, query string, args ...any) (bool, error) {
+	var data string
+	err := s.db.QueryRow(query, args...).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal([]byte(data), v)
+}
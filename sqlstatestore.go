@@ -0,0 +1,115 @@
+package gomatrix
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var stateSchema = []string{
+	`CREATE TABLE IF NOT EXISTS room_state (
+		room_id TEXT NOT NULL,
+		event_type TEXT NOT NULL,
+		state_key TEXT NOT NULL,
+		event TEXT NOT NULL,
+		PRIMARY KEY (room_id, event_type, state_key)
+	)`,
+}
+
+// SQLStateStore is a StateStore in a SQLite database, each sync writing only the state events it carries.
+// The database is opened by the caller with the driver of their choice, as for SQLCryptoStore, and may be
+// shared with it.
+type SQLStateStore struct {
+	db *sql.DB
+}
+
+// NewSQLStateStore creates the tables of the store in the database unless they exist.
+func NewSQLStateStore(ctx context.Context, db *sql.DB) (*SQLStateStore, error) {
+	for _, stmt := range stateSchema {
+		_, err := db.ExecContext(ctx, stmt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the state store tables: %w", err)
+		}
+	}
+	return &SQLStateStore{db: db}, nil
+}
+
+func (s *SQLStateStore) SetState(roomID string, events []Event) error {
+	if !containsState(events) {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to store the room state: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, ev := range events {
+		if ev.StateKey == nil {
+			continue
+		}
+		ev.RoomID = roomID
+		data, err := json.Marshal(ev)
+		if err != nil {
+			return fmt.Errorf("failed to marshal the room state: %w", err)
+		}
+		_, err = tx.Exec(`INSERT INTO room_state (room_id, event_type, state_key, event) VALUES (?, ?, ?, ?)
+			ON CONFLICT (room_id, event_type, state_key) DO UPDATE SET event = excluded.event`,
+			roomID, ev.Type, *ev.StateKey, string(data))
+		if err != nil {
+			return fmt.Errorf("failed to store the room state: %w", err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to store the room state: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStateStore) ForgetRoom(roomID string) error {
+	_, err := s.db.Exec(`DELETE FROM room_state WHERE room_id = ?`, roomID)
+	if err != nil {
+		return fmt.Errorf("failed to forget the room state: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStateStore) GetState(roomID, eventType, stateKey string) (Event, bool, error) {
+	var data string
+	err := s.db.QueryRow(`SELECT event FROM room_state WHERE room_id = ? AND event_type = ? AND state_key = ?`,
+		roomID, eventType, stateKey).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Event{}, false, nil
+	}
+	if err != nil {
+		return Event{}, false, fmt.Errorf("failed to load the room state: %w", err)
+	}
+
+	var ev Event
+	err = json.Unmarshal([]byte(data), &ev)
+	if err != nil {
+		return Event{}, false, fmt.Errorf("failed to unmarshal the room state: %w", err)
+	}
+	return ev, true, nil
+}
+
+func (s *SQLStateStore) GetMember(roomID, userID string) (MemberContent, bool, error) {
+	return getStateContent[MemberContent](s, roomID, "m.room.member", userID)
+}
+
+func (s *SQLStateStore) GetPowerLevels(roomID string) (PowerLevelsContent, error) {
+	return getPowerLevels(s, roomID)
+}
+
+func (s *SQLStateStore) GetEncryptionState(roomID string) (EncryptionContent, bool, error) {
+	return getStateContent[EncryptionContent](s, roomID, "m.room.encryption", "")
+}
+
+func (s *SQLStateStore) IsJoined(roomID, userID string) (bool, error) {
+	return isJoined(s, roomID, userID)
+}
@@ -0,0 +1,96 @@
+package gomatrix
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+type RoomState []Event
+
+func (s RoomState) Get(eventType, stateKey string) (Event, bool) {
+	for _, ev := range s {
+		if ev.Type == eventType && ev.GetStateKey() == stateKey {
+			return ev, true
+		}
+	}
+	return Event{}, false
+}
+
+func (s RoomState) OfType(eventType string) []Event {
+	var events []Event
+	for _, ev := range s {
+		if ev.Type == eventType {
+			events = append(events, ev)
+		}
+	}
+	return events
+}
+
+// GetRoomState returns all current state events of the room.
+func (c *Client) GetRoomState(ctx context.Context, roomID string) (RoomState, error) {
+	var events RoomState
+	err := c.doJSON(ctx, http.MethodGet, c.roomPath(roomID, "state"), nil, &events)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get room state: %w", err)
+	}
+
+	for i := range events {
+		events[i].RoomID = roomID
+	}
+
+	return events, nil
+}
+
+// GetStateEvent decodes the content of the room state event into v.
+// Use IsNotFound to check whether the event does not exist.
+func (c *Client) GetStateEvent(ctx context.Context, roomID, eventType, stateKey string, v any) error {
+	err := c.doJSON(ctx, http.MethodGet, c.roomPath(roomID, "state", eventType, stateKey), nil, v)
+	if err != nil {
+		return fmt.Errorf("failed to get %s state event: %w", eventType, err)
+	}
+	return nil
+}
+
+// GetMember fetches the membership of the user in the room, e.g. for the members
+// not delivered by sync because of lazy loading.
+func (c *Client) GetMember(ctx context.Context, roomID, userID string) (MemberContent, error) {
+	var member MemberContent
+	err := c.GetStateEvent(ctx, roomID, "m.room.member", userID, &member)
+	return member, err
+}
+
+func (c *Client) GetPowerLevels(ctx context.Context, roomID string) (PowerLevelsContent, error) {
+	var levels PowerLevelsContent
+	err := c.GetStateEvent(ctx, roomID, "m.room.power_levels", "", &levels)
+	return levels, err
+}
+
+// SetStateEvent sends the room state event and returns its ID.
+func (c *Client) SetStateEvent(ctx context.Context, roomID, eventType, stateKey string, content any) (string, error) {
+	err := c.validateOutgoing(eventType, content)
+	if err != nil {
+		return "", err
+	}
+
+	var respData apiSendEventResp
+	err = c.doJSON(ctx, http.MethodPut, c.roomPath(roomID, "state", eventType, stateKey), content, &respData)
+	if err != nil {
+		return "", fmt.Errorf("failed to set %s state event: %w", eventType, err)
+	}
+	return respData.EventID, nil
+}
+
+// GetRoomVersion returns the version of the room from its creation event.
+func (c *Client) GetRoomVersion(ctx context.Context, roomID string) (string, error) {
+	var create CreateContent
+	err := c.GetStateEvent(ctx, roomID, "m.room.create", "", &create)
+	if err != nil {
+		return "", err
+	}
+	if create.RoomVersion == "" {
+		// the rooms created before room versions were introduced are version 1
+		return "1", nil
+	}
+	return create.RoomVersion, nil
+}
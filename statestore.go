@@ -0,0 +1,121 @@
+package gomatrix
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// StateStore keeps the current state of the rooms the client is in, fed by Listen from the sync responses,
+// so the event handlers can look it up without requesting the homeserver.
+type StateStore interface {
+	// SetState stores the state events of the room, replacing the previous ones with the same type and state key.
+	SetState(roomID string, events []Event) error
+	// ForgetRoom drops the state of the room the client has left.
+	ForgetRoom(roomID string) error
+	GetState(roomID, eventType, stateKey string) (Event, bool, error)
+	GetMember(roomID, userID string) (MemberContent, bool, error)
+	// GetPowerLevels returns the spec defaults if the room has no power levels event.
+	GetPowerLevels(roomID string) (PowerLevelsContent, error)
+	// GetEncryptionState returns the encryption settings of the room, ok is false if the room is not encrypted.
+	GetEncryptionState(roomID string) (content EncryptionContent, ok bool, err error)
+	IsJoined(roomID, userID string) (bool, error)
+}
+
+type InMemoryStateStore struct {
+	mux   sync.RWMutex
+	rooms map[string]map[stateKey]Event
+}
+
+type stateKey struct {
+	eventType string
+	stateKey  string
+}
+
+func NewInMemoryStateStore() *InMemoryStateStore {
+	return &InMemoryStateStore{rooms: make(map[string]map[stateKey]Event)}
+}
+
+func (s *InMemoryStateStore) SetState(roomID string, events []Event) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	for _, ev := range events {
+		if ev.StateKey == nil {
+			continue
+		}
+		room, ok := s.rooms[roomID]
+		if !ok {
+			room = make(map[stateKey]Event)
+			s.rooms[roomID] = room
+		}
+		ev.RoomID = roomID
+		room[stateKey{eventType: ev.Type, stateKey: *ev.StateKey}] = ev
+	}
+	return nil
+}
+
+func (s *InMemoryStateStore) ForgetRoom(roomID string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	delete(s.rooms, roomID)
+	return nil
+}
+
+func (s *InMemoryStateStore) GetState(roomID, eventType, key string) (Event, bool, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	ev, ok := s.rooms[roomID][stateKey{eventType: eventType, stateKey: key}]
+	return ev, ok, nil
+}
+
+func (s *InMemoryStateStore) GetMember(roomID, userID string) (MemberContent, bool, error) {
+	return getStateContent[MemberContent](s, roomID, "m.room.member", userID)
+}
+
+func (s *InMemoryStateStore) GetPowerLevels(roomID string) (PowerLevelsContent, error) {
+	return getPowerLevels(s, roomID)
+}
+
+func (s *InMemoryStateStore) GetEncryptionState(roomID string) (EncryptionContent, bool, error) {
+	return getStateContent[EncryptionContent](s, roomID, "m.room.encryption", "")
+}
+
+func (s *InMemoryStateStore) IsJoined(roomID, userID string) (bool, error) {
+	return isJoined(s, roomID, userID)
+}
+
+// stateGetter is the part of a StateStore the typed getters are derived from.
+type stateGetter interface {
+	GetState(roomID, eventType, stateKey string) (Event, bool, error)
+}
+
+func getStateContent[T any](store stateGetter, roomID, eventType, key string) (T, bool, error) {
+	var content T
+	ev, ok, err := store.GetState(roomID, eventType, key)
+	if err != nil || !ok {
+		return content, false, err
+	}
+
+	err = ev.ParseContent(&content)
+	if err != nil {
+		return content, false, err
+	}
+	return content, true, nil
+}
+
+func getPowerLevels(store stateGetter, roomID string) (PowerLevelsContent, error) {
+	levels, ok, err := getStateContent[PowerLevelsContent](store, roomID, "m.room.power_levels", "")
+	if err != nil || ok {
+		return levels, err
+	}
+
+	err = json.Unmarshal([]byte("{}"), &levels)
+	return levels, err
+}
+
+func isJoined(store stateGetter, roomID, userID string) (bool, error) {
+	member, ok, err := getStateContent[MemberContent](store, roomID, "m.room.member", userID)
+	return ok && member.Membership == MembershipJoin, err
+}
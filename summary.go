@@ -0,0 +1,97 @@
+package gomatrix
+
+import "sync"
+
+// RoomSummary is the overview of a joined room accumulated by Listen from the sync responses, e.g. for unread badges.
+type RoomSummary struct {
+	// Heroes are the users to name the room after if it has no name.
+	Heroes             []string
+	JoinedMemberCount  int
+	InvitedMemberCount int
+	UnreadNotificationCounts
+	// ThreadNotifications counts the unread notifications per thread root, if enabled by the sync filter.
+	ThreadNotifications map[string]UnreadNotificationCounts
+	MarkedUnread        bool
+	Tags                map[string]Tag
+}
+
+type roomSummaries struct {
+	mux   sync.RWMutex
+	rooms map[string]RoomSummary
+	// tagged holds the rooms whose tags are known, the syncs resuming from a stored position carry them
+	// only once they change
+	tagged map[string]bool
+}
+
+func newRoomSummaries() *roomSummaries {
+	return &roomSummaries{rooms: make(map[string]RoomSummary), tagged: make(map[string]bool)}
+}
+
+func (s *roomSummaries) update(roomID string, room JoinedRoom) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	summary := s.rooms[roomID]
+	if room.Summary.Heroes != nil {
+		summary.Heroes = room.Summary.Heroes
+	}
+	if room.Summary.JoinedMemberCount != nil {
+		summary.JoinedMemberCount = *room.Summary.JoinedMemberCount
+	}
+	if room.Summary.InvitedMemberCount != nil {
+		summary.InvitedMemberCount = *room.Summary.InvitedMemberCount
+	}
+	if room.UnreadNotifications != nil {
+		summary.UnreadNotificationCounts = *room.UnreadNotifications
+	}
+	if room.UnreadThreadNotifications != nil {
+		summary.ThreadNotifications = room.UnreadThreadNotifications
+	}
+	if unread, ok := room.MarkedUnread(); ok {
+		summary.MarkedUnread = unread
+	}
+	if tags, ok := room.Tags(); ok {
+		summary.Tags = tags
+		s.tagged[roomID] = true
+	}
+	s.rooms[roomID] = summary
+}
+
+// tags returns the tags of the room, ok is false if they're not known yet.
+func (s *roomSummaries) tags(roomID string) (map[string]Tag, bool) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	return s.rooms[roomID].Tags, s.tagged[roomID]
+}
+
+// setTags records the tags fetched from the server unless a sync has brought them meanwhile.
+func (s *roomSummaries) setTags(roomID string, tags map[string]Tag) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.tagged[roomID] {
+		return
+	}
+	summary := s.rooms[roomID]
+	summary.Tags = tags
+	s.rooms[roomID] = summary
+	s.tagged[roomID] = true
+}
+
+func (s *roomSummaries) remove(roomID string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	delete(s.rooms, roomID)
+	delete(s.tagged, roomID)
+}
+
+// RoomSummary returns the summary of the joined room as of the latest sync seen by Listen.
+func (c *Client) RoomSummary(roomID string) (RoomSummary, bool) {
+	c.summaries.mux.RLock()
+	defer c.summaries.mux.RUnlock()
+
+	summary, ok := c.summaries.rooms[roomID]
+	return summary, ok
+}
@@ -0,0 +1,550 @@
+package gomatrix
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSyncTimeout   = 30 * time.Second
+	maxSyncBackoff       = time.Minute
+	defaultBackfillLimit = 100
+)
+
+type SyncOptions struct {
+	Since   string
+	Filter  string
+	Timeout time.Duration
+	// SetPresence is the presence the sync sets, online if empty; PresenceOffline keeps a bot from appearing online.
+	SetPresence Presence
+	// FullState returns the whole state of the rooms instead of the changes since the Since position.
+	FullState bool
+}
+
+type SyncResponse struct {
+	NextBatch   string      `json:"next_batch"`
+	Rooms       SyncRooms   `json:"rooms"`
+	AccountData EventList   `json:"account_data"`
+	Presence    EventList   `json:"presence"`
+	ToDevice    EventList   `json:"to_device"`
+	DeviceLists DeviceLists `json:"device_lists"`
+	// DeviceOneTimeKeysCount is the number of unclaimed one-time keys of the device per algorithm.
+	DeviceOneTimeKeysCount map[string]int `json:"device_one_time_keys_count,omitempty"`
+	// DeviceUnusedFallbackKeyTypes lists the algorithms of the fallback keys not used yet;
+	// nil if the server doesn't support fallback keys.
+	DeviceUnusedFallbackKeyTypes []string `json:"device_unused_fallback_key_types,omitempty"`
+}
+
+// DeviceLists are the users whose device keys changed and the users no longer sharing an encrypted room.
+type DeviceLists struct {
+	Changed []string `json:"changed,omitempty"`
+	Left    []string `json:"left,omitempty"`
+}
+
+type SyncRooms struct {
+	Join   map[string]JoinedRoom  `json:"join"`
+	Invite map[string]InvitedRoom `json:"invite"`
+	Leave  map[string]LeftRoom    `json:"leave"`
+}
+
+type JoinedRoom struct {
+	State       EventList       `json:"state"`
+	Timeline    Timeline        `json:"timeline"`
+	Ephemeral   EventList       `json:"ephemeral"`
+	AccountData EventList       `json:"account_data"`
+	Summary     SyncRoomSummary `json:"summary"`
+	// UnreadNotifications counts the unread notifications of the whole room, or of the main timeline
+	// if the sync filter enables UnreadThreadNotifications.
+	UnreadNotifications       *UnreadNotificationCounts           `json:"unread_notifications,omitempty"`
+	UnreadThreadNotifications map[string]UnreadNotificationCounts `json:"unread_thread_notifications,omitempty"`
+}
+
+// SyncRoomSummary holds the room summary fields changed since the previous sync; the omitted ones are nil.
+type SyncRoomSummary struct {
+	Heroes             []string `json:"m.heroes,omitempty"`
+	JoinedMemberCount  *int     `json:"m.joined_member_count,omitempty"`
+	InvitedMemberCount *int     `json:"m.invited_member_count,omitempty"`
+}
+
+type UnreadNotificationCounts struct {
+	HighlightCount    int `json:"highlight_count"`
+	NotificationCount int `json:"notification_count"`
+}
+
+// MarkedUnread reports the marked unread flag of the room; ok is false if the flag hasn't changed since the previous sync.
+func (r JoinedRoom) MarkedUnread() (unread, ok bool) {
+	for _, ev := range r.AccountData.Events {
+		var marked apiMarkedUnread
+		if ev.Type == MarkedUnreadAccountDataType && ev.ParseContent(&marked) == nil {
+			unread, ok = marked.Unread, true
+		}
+	}
+	return unread, ok
+}
+
+type InvitedRoom struct {
+	InviteState EventList `json:"invite_state"`
+}
+
+type LeftRoom struct {
+	State       EventList `json:"state"`
+	Timeline    Timeline  `json:"timeline"`
+	AccountData EventList `json:"account_data"`
+}
+
+type Timeline struct {
+	Events    []Event `json:"events"`
+	Limited   bool    `json:"limited"`
+	PrevBatch string  `json:"prev_batch"`
+}
+
+type EventList struct {
+	Events []Event `json:"events"`
+}
+
+type EventHandler func(ctx context.Context, ev Event)
+
+func (c *Client) Sync(ctx context.Context, opts SyncOptions) (*SyncResponse, error) {
+	query := url.Values{}
+	if opts.Since != "" {
+		query.Set("since", opts.Since)
+	}
+	if opts.Filter != "" {
+		query.Set("filter", opts.Filter)
+	}
+	query.Set("timeout", strconv.FormatInt(opts.Timeout.Milliseconds(), 10))
+	if opts.SetPresence != "" {
+		query.Set("set_presence", string(opts.SetPresence))
+	}
+	if opts.FullState {
+		query.Set("full_state", "true")
+	}
+
+	var resp SyncResponse
+	err := c.doJSON(ctx, http.MethodGet, "/_matrix/client/v3/sync?"+query.Encode(), nil, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sync: %w", err)
+	}
+
+	return &resp, nil
+}
+
+// Listen long-polls the homeserver and calls the handler for every new timeline event
+// in the joined rooms and for every invite. Without a stored sync position the history returned
+// by the initial sync is skipped; with a SyncStore configured, Listen resumes from the stored position.
+// Transient failures are retried with backoff; Listen returns when the context is done
+// or on a non-retryable error, once the handler is done with the events received.
+func (c *Client) Listen(ctx context.Context, handler EventHandler) (err error) {
+	done, err := c.lifecycle.listen()
+	if err != nil {
+		return err
+	}
+	defer done()
+	defer func() {
+		if c.lifecycle.stopped() {
+			err = ErrClosed
+		}
+	}()
+
+	var pool *workerPool
+	if c.workers.Size > 0 {
+		pool = newWorkerPool(c, c.workers, handler)
+		defer pool.stop()
+		handler = pool.dispatch
+	}
+
+	return c.listen(ctx, c.syncStore, c.syncFilter, func(ctx context.Context, since string, resp *SyncResponse) func() bool {
+		if pool == nil {
+			c.dispatchSync(ctx, since, resp, handler)
+			return nil
+		}
+		batch := pool.startBatch()
+		c.dispatchSync(ctx, since, resp, handler)
+		return batch.handled
+	})
+}
+
+// ToDeviceHandler receives the decrypted to-device events and the device list changes of a sync.
+type ToDeviceHandler func(ctx context.Context, events []Event, deviceLists DeviceLists)
+
+// ListenToDevice is a lightweight Listen syncing with ToDeviceOnlyFilter, for the components existing only
+// to manage the encryption keys. The forwarded room keys are imported and the one-time keys are replenished
+// as with Listen; the handler is called for the syncs with to-device events or device list changes.
+// Its sync position is kept in Config.ToDeviceSyncStore.
+func (c *Client) ListenToDevice(ctx context.Context, handler ToDeviceHandler) (err error) {
+	syncFilter, err := ToDeviceOnlyFilter().encode()
+	if err != nil {
+		return err
+	}
+
+	done, err := c.lifecycle.listen()
+	if err != nil {
+		return err
+	}
+	defer done()
+	defer func() {
+		if c.lifecycle.stopped() {
+			err = ErrClosed
+		}
+	}()
+
+	return c.listen(ctx, c.toDeviceStore, syncFilter, func(ctx context.Context, since string, resp *SyncResponse) func() bool {
+		events := c.handleToDevice(ctx, resp.ToDevice.Events)
+		if len(events) == 0 && len(resp.DeviceLists.Changed) == 0 && len(resp.DeviceLists.Left) == 0 {
+			return nil
+		}
+		handler(ctx, events, resp.DeviceLists)
+		return nil
+	})
+}
+
+// syncDispatcher passes a new sync to the handler. It returns nil once the events are handled, or a function
+// waiting for the events left to other goroutines and reporting whether they were all handled.
+type syncDispatcher func(ctx context.Context, since string, resp *SyncResponse) (handled func() bool)
+
+// pendingPosition is a sync position to save once the events of the sync are handled.
+type pendingPosition struct {
+	state   SyncState
+	handled func() bool
+}
+
+// maxPendingPositions is how many syncs may wait for their events to be handled before the sync loop blocks.
+const maxPendingPositions = 16
+
+// listen runs the sync loop of Listen with the filter definition, passing the new syncs to dispatch;
+// the sync position is kept in the store.
+func (c *Client) listen(ctx context.Context, store SyncStore, syncFilter string, dispatch syncDispatcher) error {
+	state, err := loadSyncState(store)
+	if err != nil {
+		return err
+	}
+
+	filter, err := c.listenFilter(ctx, store, syncFilter, &state)
+	if err != nil {
+		return err
+	}
+
+	// the key uploads in progress are waited for, so Close flushes what they leave pending
+	var keys sync.WaitGroup
+	defer keys.Wait()
+	handlerCtx := ctx
+	ctx, cancel := c.lifecycle.stopOnClose(ctx)
+	defer cancel()
+	keys.Add(2)
+	go func() {
+		defer keys.Done()
+		c.oneTimeKeys.run(ctx)
+	}()
+	go func() {
+		defer keys.Done()
+		c.keyBackup.run(ctx)
+	}()
+
+	if state.NextBatch == "" {
+		resp, err := c.Sync(ctx, SyncOptions{Filter: filter, SetPresence: c.syncPresence})
+		if err != nil {
+			return err
+		}
+		err = c.observeSync(resp)
+		if err != nil {
+			return err
+		}
+		state.NextBatch = resp.NextBatch
+		err = saveSyncState(store, state)
+		if err != nil {
+			return err
+		}
+	}
+
+	positions := make(chan pendingPosition, maxPendingPositions)
+	saveErr := make(chan error, 1)
+	saved := make(chan struct{})
+	go func() {
+		defer close(saved)
+		savePositions(store, positions, saveErr)
+	}()
+	defer func() {
+		close(positions)
+		<-saved
+	}()
+
+	backoff := time.Second
+	for {
+		select {
+		case err := <-saveErr:
+			return err
+		default:
+		}
+
+		fullState := c.fullState.Swap(false)
+		resp, err := c.Sync(ctx, SyncOptions{
+			Since:       state.NextBatch,
+			Filter:      filter,
+			Timeout:     c.syncTimeout,
+			SetPresence: c.syncPresence,
+			FullState:   fullState,
+		})
+		if err != nil {
+			if fullState {
+				c.fullState.Store(true)
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if !isTransient(err) {
+				return err
+			}
+			c.logger.Warn("sync failed, retrying", "backoff", backoff, "error", err)
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, maxSyncBackoff)
+			continue
+		}
+
+		backoff = time.Second
+		err = c.observeSync(resp)
+		if err != nil {
+			return err
+		}
+		handled := dispatch(handlerCtx, state.NextBatch, resp)
+
+		// the position is saved after the events are handled, so none of them is lost on a restart
+		state.NextBatch = resp.NextBatch
+		if handled != nil {
+			positions <- pendingPosition{state: state, handled: handled}
+			continue
+		}
+		err = saveSyncState(store, state)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// savePositions saves the positions in order, each once the events of its sync are handled. The positions
+// following a sync whose events were dropped are not saved, so the events are received again on a restart.
+func savePositions(store SyncStore, positions <-chan pendingPosition, saveErr chan<- error) {
+	failed := false
+	for pos := range positions {
+		if failed {
+			continue
+		}
+		if !pos.handled() {
+			failed = true
+			continue
+		}
+		err := saveSyncState(store, pos.state)
+		if err != nil {
+			saveErr <- err
+			failed = true
+		}
+	}
+}
+
+// RequestFullState makes the next sync of Listen return the whole state of the rooms,
+// e.g. to refresh a StateStore suspected to be out of date.
+func (c *Client) RequestFullState() {
+	c.fullState.Store(true)
+}
+
+// listenFilter returns the filter ID to sync with. Without a sync store the filter is sent inline,
+// otherwise it is uploaded once and its ID is kept in the store until the filter definition changes.
+func (c *Client) listenFilter(ctx context.Context, store SyncStore, syncFilter string, state *SyncState) (string, error) {
+	if syncFilter == "" || store == nil {
+		state.FilterID, state.FilterDefinition = "", ""
+		return syncFilter, nil
+	}
+
+	if state.FilterID != "" && state.FilterDefinition == syncFilter {
+		return state.FilterID, nil
+	}
+
+	var filter Filter
+	err := json.Unmarshal([]byte(syncFilter), &filter)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal the sync filter: %w", err)
+	}
+
+	filterID, err := c.CreateFilter(ctx, filter)
+	if err != nil {
+		return "", err
+	}
+
+	state.FilterID, state.FilterDefinition = filterID, syncFilter
+	return filterID, saveSyncState(store, *state)
+}
+
+func loadSyncState(store SyncStore) (SyncState, error) {
+	if store == nil {
+		return SyncState{}, nil
+	}
+
+	state, err := store.GetSyncState()
+	if err != nil {
+		return SyncState{}, fmt.Errorf("failed to load the sync state: %w", err)
+	}
+	return state, nil
+}
+
+func saveSyncState(store SyncStore, state SyncState) error {
+	if store == nil {
+		return nil
+	}
+
+	err := store.SetSyncState(state)
+	if err != nil {
+		return fmt.Errorf("failed to save the sync state: %w", err)
+	}
+	return nil
+}
+
+// observeSync updates the client caches with the sync response, including the initial one whose events aren't dispatched.
+func (c *Client) observeSync(resp *SyncResponse) error {
+	if c.presence != nil {
+		c.presence.Update(resp.Presence.Events)
+	}
+	c.oneTimeKeys.update(resp)
+	c.withheld.observe(resp.ToDevice.Events)
+
+	for roomID, room := range resp.Rooms.Join {
+		c.members.observe(roomID, room.State.Events)
+		c.members.observe(roomID, room.Timeline.Events)
+		c.summaries.update(roomID, room)
+	}
+	for roomID := range resp.Rooms.Leave {
+		c.members.invalidate(roomID)
+		c.summaries.remove(roomID)
+	}
+
+	if c.stateStore == nil {
+		return nil
+	}
+
+	for roomID, room := range resp.Rooms.Join {
+		// the state block precedes the timeline, whose state events are the most recent
+		err := c.stateStore.SetState(roomID, slices.Concat(room.State.Events, room.Timeline.Events))
+		if err != nil {
+			return fmt.Errorf("failed to store the room state: %w", err)
+		}
+	}
+	for roomID := range resp.Rooms.Leave {
+		err := c.stateStore.ForgetRoom(roomID)
+		if err != nil {
+			return fmt.Errorf("failed to forget the room state: %w", err)
+		}
+	}
+	return nil
+}
+
+func (c *Client) dispatchSync(ctx context.Context, since string, resp *SyncResponse, handler EventHandler) {
+	// the forwarded keys may be needed to decrypt the room events of the same sync
+	c.handleToDevice(ctx, resp.ToDevice.Events)
+
+	c.autoJoin(ctx, resp.Rooms.Invite)
+
+	for roomID, room := range resp.Rooms.Invite {
+		for _, ev := range room.InviteState.Events {
+			ev.RoomID = roomID
+			handler(ctx, ev)
+		}
+	}
+
+	for roomID, room := range resp.Rooms.Join {
+		if c.skipNotices {
+			notices, err := c.IsServerNoticeRoom(ctx, roomID)
+			if err != nil {
+				// the tags are fetched again with the next sync of the room
+				c.logger.Warn("failed to check the server notices room", "room_id", roomID, "error", err)
+			}
+			if notices {
+				continue
+			}
+		}
+
+		var events []Event
+		if room.Timeline.Limited && room.Timeline.PrevBatch != "" {
+			events = c.backfill(ctx, roomID, room.Timeline.PrevBatch, since)
+		}
+
+		events = append(events, room.Timeline.Events...)
+		c.dispatchMembershipChanges(ctx, roomID, events)
+
+		for _, ev := range events {
+			ev.RoomID = roomID
+			ev, ok := c.decrypt(ctx, ev)
+			if !ok {
+				continue
+			}
+			if c.strict && !c.valid(ev) {
+				continue
+			}
+			handler(ctx, ev)
+		}
+	}
+
+	for roomID, room := range resp.Rooms.Leave {
+		c.dispatchMembershipChanges(ctx, roomID, room.Timeline.Events)
+	}
+}
+
+// backfill recovers up to the configured number of events missing between the previous sync position
+// and a limited timeline, oldest first. The recovery is best effort: on failure the events fetched so far are returned.
+func (c *Client) backfill(ctx context.Context, roomID, from, to string) []Event {
+	if c.backfillLimit <= 0 {
+		return nil
+	}
+
+	var events []Event
+	for len(events) < c.backfillLimit {
+		page, err := c.GetMessages(ctx, roomID, PaginationOptions{
+			From:  from,
+			To:    to,
+			Dir:   Backward,
+			Limit: min(c.backfillLimit-len(events), 100),
+		})
+		if err != nil || len(page.Events) == 0 {
+			break
+		}
+
+		events = append(events, page.Events...)
+		if page.End == "" {
+			break
+		}
+		from = page.End
+	}
+
+	slices.Reverse(events)
+	return events
+}
+
+// isTransient reports whether the failed request may succeed when retried: the network failures and the server
+// errors are, while the rejected requests and the logins the client can't renew on its own are not.
+func isTransient(err error) bool {
+	if IsAccountRestricted(err) ||
+		errors.Is(err, ErrNoPassword) || errors.Is(err, ErrGuestExpired) || errors.Is(err, ErrNoRefreshToken) {
+		return false
+	}
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode >= 500 || httpErr.StatusCode == http.StatusTooManyRequests
+	}
+	var oauthErr *OAuthError
+	if errors.As(err, &oauthErr) {
+		return oauthErr.StatusCode >= 500
+	}
+
+	return true
+}
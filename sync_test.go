@@ -0,0 +1,51 @@
+package gomatrix_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	gomatrix "github.com/beldeveloper/go-matrix"
+)
+
+func TestListenStopsOnRejectedLogin(t *testing.T) {
+	tests := []struct {
+		name        string
+		credentials gomatrix.Credentials
+		want        error
+	}{
+		{name: "no password", credentials: gomatrix.Credentials{User: "@bot:example.org", AccessToken: "revoked"}, want: gomatrix.ErrNoPassword},
+		{name: "guest", credentials: gomatrix.Credentials{Guest: true}, want: gomatrix.ErrGuestExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				if r.URL.Path == "/_matrix/client/v3/register" {
+					_, _ = w.Write([]byte(`{"user_id":"@guest:example.org","access_token":"guest","device_id":"GUEST"}`))
+					return
+				}
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = w.Write([]byte(`{"errcode":"M_UNKNOWN_TOKEN","error":"Invalid access token"}`))
+			}))
+			defer srv.Close()
+
+			tt.credentials.Server = srv.URL
+			client, err := gomatrix.NewClientWithConfig(gomatrix.Config{Credentials: tt.credentials})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			err = client.Listen(ctx, func(context.Context, gomatrix.Event) {})
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Listen returned %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package gomatrix
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+)
+
+const defaultValidationPollInterval = 5 * time.Second
+
+type ThreePIDMedium string
+
+const (
+	MediumEmail  ThreePIDMedium = "email"
+	MediumMSISDN ThreePIDMedium = "msisdn"
+)
+
+// TokenPurpose is what the validated email address or phone number is for.
+type TokenPurpose string
+
+// https://spec.matrix.org/v1.13/client-server-api/#adding-account-administrative-contact-information
+const (
+	// PurposeRegister validates the contact of a new account, completing m.login.email.identity or m.login.msisdn.
+	PurposeRegister TokenPurpose = "register"
+	// PurposePasswordReset proves the ownership of a contact of the account whose password is forgotten.
+	PurposePasswordReset TokenPurpose = "account/password"
+	// PurposeAddThreePID validates a contact to add to the account with AddThreePID.
+	PurposeAddThreePID TokenPurpose = "account/3pid"
+)
+
+type TokenRequestOptions struct {
+	// SendAttempt must be increased to make the server send the token again, 1 by default.
+	SendAttempt int
+	// NextLink is where the validation link of the email redirects to once followed.
+	NextLink string
+}
+
+// ValidationSession is a pending validation of an email address or a phone number. The user validates an email
+// address by following the link of the email; the token texted to a phone number is submitted with SubmitToken.
+type ValidationSession struct {
+	Medium       ThreePIDMedium
+	SID          string
+	ClientSecret string
+	// SubmitURL is where the texted token is submitted to, empty if the server validates the contact otherwise.
+	SubmitURL string
+}
+
+// ThreePIDCreds returns the credentials of the validated session for the auth dict of m.login.email.identity
+// and m.login.msisdn.
+func (s ValidationSession) ThreePIDCreds() map[string]any {
+	return map[string]any{"threepid_creds": map[string]string{"sid": s.SID, "client_secret": s.ClientSecret}}
+}
+
+// RequestEmailToken makes the homeserver email a validation link to the address.
+// https://spec.matrix.org/v1.13/client-server-api/#post_matrixclientv3registeremailrequesttoken
+func (c *Client) RequestEmailToken(ctx context.Context, purpose TokenPurpose, email string, opts TokenRequestOptions) (ValidationSession, error) {
+	return c.requestToken(ctx, purpose, MediumEmail, apiRequestTokenReq{Email: email, NextLink: opts.NextLink}, opts)
+}
+
+// RequestMSISDNToken makes the homeserver text a validation token to the phone number, given with
+// the two-letter country code the number is local to, e.g. GB.
+// https://spec.matrix.org/v1.13/client-server-api/#post_matrixclientv3registermsisdnrequesttoken
+func (c *Client) RequestMSISDNToken(
+	ctx context.Context, purpose TokenPurpose, country, phoneNumber string, opts TokenRequestOptions,
+) (ValidationSession, error) {
+	return c.requestToken(ctx, purpose, MediumMSISDN, apiRequestTokenReq{Country: country, PhoneNumber: phoneNumber}, opts)
+}
+
+func (c *Client) requestToken(
+	ctx context.Context, purpose TokenPurpose, medium ThreePIDMedium, reqData apiRequestTokenReq, opts TokenRequestOptions,
+) (ValidationSession, error) {
+	secret, err := randomString(32)
+	if err != nil {
+		return ValidationSession{}, err
+	}
+	reqData.ClientSecret = secret
+	reqData.SendAttempt = max(opts.SendAttempt, 1)
+
+	var respData apiRequestTokenResp
+	path := fmt.Sprintf("/_matrix/client/v3/%s/%s/requestToken", purpose, medium)
+	if purpose == PurposeAddThreePID {
+		err = c.doJSON(ctx, http.MethodPost, path, reqData, &respData)
+	} else {
+		// the users registering or having forgotten the password have no access token yet
+		err = c.postAuth(ctx, path, reqData, &respData)
+	}
+	if err != nil {
+		return ValidationSession{}, fmt.Errorf("failed to request the %s validation token: %w", medium, err)
+	}
+
+	return ValidationSession{Medium: medium, SID: respData.SID, ClientSecret: secret, SubmitURL: respData.SubmitURL}, nil
+}
+
+// SubmitToken submits the token texted to the phone number of the session.
+func (c *Client) SubmitToken(ctx context.Context, session ValidationSession, token string) error {
+	if session.SubmitURL == "" {
+		return errors.New("the homeserver gave no URL to submit the token to")
+	}
+
+	payload, err := json.Marshal(apiSubmitTokenReq{SID: session.SID, ClientSecret: session.ClientSecret, Token: token})
+	if err != nil {
+		return fmt.Errorf("failed to marshal the token: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, session.SubmitURL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create a request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to submit the token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("failed to submit the token: %w", readHTTPError(resp))
+	}
+	var respData apiSubmitTokenResp
+	err = json.NewDecoder(resp.Body).Decode(&respData)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal the token submission: %w", err)
+	}
+	if !respData.Success {
+		return errors.New("the token was not accepted")
+	}
+	return nil
+}
+
+// AddThreePID adds the validated email address or phone number to the account.
+// https://spec.matrix.org/v1.13/client-server-api/#post_matrixclientv3account3pidadd
+func (c *Client) AddThreePID(ctx context.Context, session ValidationSession, uia UIA) error {
+	reqData := map[string]any{"sid": session.SID, "client_secret": session.ClientSecret}
+	err := c.doJSONWithUIA(ctx, http.MethodPost, "/_matrix/client/v3/account/3pid/add", reqData, uia, nil)
+	if err != nil {
+		return fmt.Errorf("failed to add the %s: %w", session.Medium, err)
+	}
+	return nil
+}
+
+// IsNotValidated reports whether the request failed because the contact of the session isn't validated yet.
+func IsNotValidated(err error) bool {
+	return slices.Contains([]string{"M_THREEPID_AUTH_FAILED", "M_UNAUTHORIZED"}, ErrCode(err))
+}
+
+// WaitValidated retries the request needing the validated contact, e.g. AddThreePID, every interval,
+// 5 seconds by default, while the user hasn't followed the validation link or submitted the token yet.
+func WaitValidated(ctx context.Context, interval time.Duration, request func(ctx context.Context) error) error {
+	if interval <= 0 {
+		interval = defaultValidationPollInterval
+	}
+
+	for {
+		err := request(ctx)
+		if !IsNotValidated(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// ResetPassword resets the forgotten password of the account the email address belongs to: it makes the homeserver
+// email a validation link to the address, waits for the user to follow it, then sets the new password,
+// logging out all the devices of the account. The context bounds the wait.
+// https://spec.matrix.org/v1.13/client-server-api/#post_matrixclientv3accountpassword
+func (c *Client) ResetPassword(ctx context.Context, email, newPassword string) error {
+	session, err := c.RequestEmailToken(ctx, PurposePasswordReset, email, TokenRequestOptions{})
+	if err != nil {
+		return err
+	}
+
+	uia := UIA{Interactive: func(ctx context.Context, state UIAState) (map[string]any, error) {
+		if state.Stage != AuthEmailIdentity {
+			return nil, fmt.Errorf("unsupported stage %s", state.Stage)
+		}
+		return session.ThreePIDCreds(), nil
+	}}
+	reqData := apiPasswordResetReq{NewPassword: newPassword, LogoutDevices: true}
+
+	err = WaitValidated(ctx, 0, func(ctx context.Context) error {
+		return uia.Do(ctx, func(ctx context.Context, auth map[string]any) error {
+			reqData.Auth = auth
+			return c.postAuth(ctx, "/_matrix/client/v3/account/password", reqData, &struct{}{})
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reset the password: %w", err)
+	}
+	return nil
+}
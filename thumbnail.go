@@ -0,0 +1,185 @@
+package gomatrix
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"os/exec"
+)
+
+const (
+	defaultThumbnailWidth  = 800
+	defaultThumbnailHeight = 600
+)
+
+// ThumbnailEncoder produces a downscaled preview of the media fitting into the given bounds.
+type ThumbnailEncoder interface {
+	Thumbnail(ctx context.Context, r io.Reader, mimeType string, maxWidth, maxHeight int) (Thumbnail, error)
+}
+
+type Thumbnail struct {
+	Data     []byte
+	MimeType string
+	Width    int
+	Height   int
+}
+
+type ThumbnailOptions struct {
+	// MaxWidth and MaxHeight bound the thumbnail size, 800x600 by default.
+	MaxWidth  int
+	MaxHeight int
+}
+
+// AttachThumbnail generates a thumbnail of the media content read from r, uploads it
+// and sets the thumbnail URI and info of the media.
+func (c *Client) AttachThumbnail(
+	ctx context.Context, media *Media, r io.Reader, encoder ThumbnailEncoder, opts ThumbnailOptions,
+) error {
+	if opts.MaxWidth <= 0 {
+		opts.MaxWidth = defaultThumbnailWidth
+	}
+	if opts.MaxHeight <= 0 {
+		opts.MaxHeight = defaultThumbnailHeight
+	}
+	if media.Info == nil {
+		media.Info = &MediaInfo{}
+	}
+
+	thumb, err := encoder.Thumbnail(ctx, r, media.Info.MimeType, opts.MaxWidth, opts.MaxHeight)
+	if err != nil {
+		return fmt.Errorf("failed to generate a thumbnail: %w", err)
+	}
+
+	uri, err := c.UploadFile(ctx, thumb.MimeType, thumb.Data)
+	if err != nil {
+		return err
+	}
+
+	media.Info.ThumbnailURI = uri
+	media.Info.ThumbnailInfo = &ThumbnailInfo{
+		MimeType: thumb.MimeType,
+		Size:     int64(len(thumb.Data)),
+		Width:    thumb.Width,
+		Height:   thumb.Height,
+	}
+	return nil
+}
+
+// ImageThumbnailer downscales JPEG, PNG and GIF images and encodes the thumbnails as JPEG.
+type ImageThumbnailer struct {
+	// Quality is the JPEG quality, 80 by default.
+	Quality int
+}
+
+func (t ImageThumbnailer) Thumbnail(
+	_ context.Context, r io.Reader, _ string, maxWidth, maxHeight int,
+) (Thumbnail, error) {
+	src, _, err := image.Decode(r)
+	if err != nil {
+		return Thumbnail{}, fmt.Errorf("failed to decode the image: %w", err)
+	}
+
+	dst := downscale(src, maxWidth, maxHeight)
+
+	quality := t.Quality
+	if quality <= 0 {
+		quality = 80
+	}
+
+	var buf bytes.Buffer
+	err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: quality})
+	if err != nil {
+		return Thumbnail{}, fmt.Errorf("failed to encode the thumbnail: %w", err)
+	}
+
+	return Thumbnail{
+		Data:     buf.Bytes(),
+		MimeType: "image/jpeg",
+		Width:    dst.Bounds().Dx(),
+		Height:   dst.Bounds().Dy(),
+	}, nil
+}
+
+// FFmpegThumbnailer extracts the first frame of a video with the ffmpeg binary and encodes it as JPEG.
+type FFmpegThumbnailer struct {
+	// Path is the ffmpeg binary, looked up in PATH by default.
+	Path string
+}
+
+func (t FFmpegThumbnailer) Thumbnail(
+	ctx context.Context, r io.Reader, _ string, maxWidth, maxHeight int,
+) (Thumbnail, error) {
+	path := t.Path
+	if path == "" {
+		path = "ffmpeg"
+	}
+
+	scale := fmt.Sprintf("scale=w=%d:h=%d:force_original_aspect_ratio=decrease", maxWidth, maxHeight)
+	cmd := exec.CommandContext(
+		ctx, path, "-loglevel", "error", "-i", "pipe:0", "-frames:v", "1", "-vf", scale, "-f", "image2", "-c:v", "mjpeg", "pipe:1",
+	)
+	cmd.Stdin = r
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		return Thumbnail{}, fmt.Errorf("ffmpeg failed: %w; %s", err, stderr.Bytes())
+	}
+
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(stdout.Bytes()))
+	if err != nil {
+		return Thumbnail{}, errors.New("ffmpeg returned an invalid frame")
+	}
+
+	return Thumbnail{
+		Data:     stdout.Bytes(),
+		MimeType: "image/jpeg",
+		Width:    cfg.Width,
+		Height:   cfg.Height,
+	}, nil
+}
+
+// downscale fits the image into the bounds keeping the aspect ratio by averaging the source pixels.
+func downscale(src image.Image, maxWidth, maxHeight int) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= maxWidth && h <= maxHeight {
+		return src
+	}
+
+	dw, dh := maxWidth, h*maxWidth/w
+	if dh > maxHeight {
+		dw, dh = w*maxHeight/h, maxHeight
+	}
+	dw, dh = max(dw, 1), max(dh, 1)
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := range dh {
+		y0, y1 := b.Min.Y+y*h/dh, b.Min.Y+max((y+1)*h/dh, y*h/dh+1)
+		for x := range dw {
+			x0, x1 := b.Min.X+x*w/dw, b.Min.X+max((x+1)*w/dw, x*w/dw+1)
+
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r / n), G: uint16(g / n), B: uint16(bl / n), A: uint16(a / n),
+			})
+		}
+	}
+
+	return dst
+}
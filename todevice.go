@@ -0,0 +1,23 @@
+package gomatrix
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// AllDevices addresses a to-device message to all devices of the user.
+const AllDevices = "*"
+
+// SendToDevice sends the messages to the devices, keyed by user ID and device ID.
+// https://spec.matrix.org/v1.13/client-server-api/#put_matrixclientv3sendtodeviceeventtypetxnid
+func (c *Client) SendToDevice(ctx context.Context, eventType string, messages map[string]map[string]any) error {
+	path := "/_matrix/client/v3/sendToDevice/" + eventType + "/" + uuid.NewString()
+	err := c.doJSON(ctx, http.MethodPut, path, apiSendToDeviceReq{Messages: messages}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to send %s to devices: %w", eventType, err)
+	}
+	return nil
+}
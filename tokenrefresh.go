@@ -0,0 +1,93 @@
+package gomatrix
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	defaultRefreshMargin = time.Minute
+	refreshRetryInterval = 10 * time.Second
+)
+
+// refreshAccessToken renews the access token with the refresh token of the login; c.mux must be held.
+// https://spec.matrix.org/v1.13/client-server-api/#refreshing-access-tokens
+func (c *Client) refreshAccessToken(ctx context.Context) error {
+	var respData apiLoginResp
+	err := c.postAuth(ctx, "/_matrix/client/v3/refresh", apiRefreshReq{RefreshToken: c.refreshToken}, &respData)
+	if err != nil {
+		return err
+	}
+
+	if respData.RefreshToken == "" {
+		respData.RefreshToken = c.refreshToken
+	}
+	return c.setSession(Session{
+		AccessToken:  respData.AccessToken,
+		DeviceID:     c.deviceID,
+		RefreshToken: respData.RefreshToken,
+		ExpiresAt:    expiresAt(time.Duration(respData.ExpiresInMs) * time.Millisecond),
+	})
+}
+
+// StartTokenRefresh renews the expiring access token the margin before it expires, 1 minute by default,
+// sparing the requests the round-trip of a rejected token. The failures are sent to the returned channel
+// and retried; they are dropped while the channel is full. The channel is closed once the context is done
+// or the account turns out to be locked or suspended.
+func (c *Client) StartTokenRefresh(ctx context.Context, margin time.Duration) <-chan error {
+	if margin <= 0 {
+		margin = defaultRefreshMargin
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+
+		for {
+			c.mux.RLock()
+			token, expiresAt := c.token, c.expiresAt
+			c.mux.RUnlock()
+
+			// the token may not expire until the next login
+			wait := margin
+			if !expiresAt.IsZero() {
+				left := time.Until(expiresAt)
+				wait = max(left-margin, left/2)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+
+			c.mux.RLock()
+			due := c.token == token && !c.expiresAt.IsZero() && time.Until(c.expiresAt) <= margin
+			c.mux.RUnlock()
+			if !due {
+				continue
+			}
+
+			err := c.authenticate(token)
+			if err == nil {
+				continue
+			}
+
+			select {
+			case errs <- err:
+			default:
+			}
+			if IsAccountRestricted(err) {
+				// logging in again won't help until the administrators lift the restriction
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(refreshRetryInterval):
+			}
+		}
+	}()
+	return errs
+}
@@ -0,0 +1,71 @@
+package gomatrix
+
+import (
+	"crypto/tls"
+	"net/http"
+	"slices"
+	"time"
+)
+
+const (
+	defaultMaxIdleConnsPerHost = 32
+	defaultIdleConnTimeout     = 90 * time.Second
+)
+
+// TransportConfig tunes the HTTP transport of the client created when Config.HttpClient is not set.
+type TransportConfig struct {
+	// MaxIdleConnsPerHost is 32 by default; the net/http default of 2 makes the concurrent sends
+	// open and close connections all the time.
+	MaxIdleConnsPerHost int
+	// MaxConnsPerHost limits the connections to the homeserver, unlimited by default.
+	MaxConnsPerHost int
+	// IdleConnTimeout closes the connections idle for longer, 90 seconds by default.
+	IdleConnTimeout time.Duration
+	// DisableHTTP2 sticks to HTTP/1.1, which opens a connection per concurrent request instead of multiplexing them.
+	DisableHTTP2      bool
+	DisableKeepAlives bool
+	// TLSConfig is e.g. for a private CA or a client certificate.
+	TLSConfig *tls.Config
+	// ResponseHeaderTimeout is how long a request waits for the response headers once it's sent, a minute on top
+	// of Config.SyncTimeout by default, the syncs holding the response back until there are events. The bodies
+	// aren't limited, so large uploads and downloads are only bounded by the context of the request.
+	ResponseHeaderTimeout time.Duration
+}
+
+func (cfg TransportConfig) newTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	transport.MaxIdleConnsPerHost = defaultMaxIdleConnsPerHost
+	if cfg.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	}
+	transport.MaxConnsPerHost = cfg.MaxConnsPerHost
+	transport.IdleConnTimeout = defaultIdleConnTimeout
+	if cfg.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = cfg.IdleConnTimeout
+	}
+	transport.DisableKeepAlives = cfg.DisableKeepAlives
+	transport.ResponseHeaderTimeout = cfg.ResponseHeaderTimeout
+
+	if cfg.TLSConfig != nil {
+		transport.TLSClientConfig = cfg.TLSConfig.Clone()
+	}
+	if cfg.DisableHTTP2 {
+		// a non-nil empty map keeps net/http from upgrading to HTTP/2
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	}
+	return transport
+}
+
+// defaultHeaders returns the headers added to the requests, in the canonical form.
+func defaultHeaders(userAgent string, headers http.Header) http.Header {
+	h := make(http.Header, len(headers)+1)
+	for key, values := range headers {
+		h[http.CanonicalHeaderKey(key)] = slices.Clone(values)
+	}
+	if userAgent != "" {
+		h.Set("User-Agent", userAgent)
+	}
+	return h
+}
@@ -0,0 +1,223 @@
+package gomatrix
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+)
+
+// https://spec.matrix.org/v1.13/client-server-api/#authentication-types
+const (
+	AuthPassword          = "m.login.password"
+	AuthDummy             = "m.login.dummy"
+	AuthRegistrationToken = "m.login.registration_token"
+	AuthRecaptcha         = "m.login.recaptcha"
+	AuthEmailIdentity     = "m.login.email.identity"
+	AuthMSISDN            = "m.login.msisdn"
+	AuthTerms             = "m.login.terms"
+	AuthSSO               = "m.login.sso"
+)
+
+// maxUIARounds bounds the requests of a single authentication, whatever the server asks for.
+const maxUIARounds = 10
+
+type apiUIAResp struct {
+	Session   string                     `json:"session"`
+	Flows     []apiUIAFlow               `json:"flows"`
+	Completed []string                   `json:"completed"`
+	Params    map[string]json.RawMessage `json:"params"`
+	ErrCode   string                     `json:"errcode"`
+	Error     string                     `json:"error"`
+}
+
+type apiUIAFlow struct {
+	Stages []string `json:"stages"`
+}
+
+// isUIAResponse reports whether the 401 response asks for user-interactive authentication rather than a new token.
+func isUIAResponse(httpErr *HTTPError) bool {
+	var resp apiUIAResp
+	return json.Unmarshal(httpErr.Body, &resp) == nil && len(resp.Flows) > 0
+}
+
+// UIAState is the progress of a user-interactive authentication passed to UIA.Interactive.
+type UIAState struct {
+	Session string
+	// Stage is the stage to complete.
+	Stage     string
+	Completed []string
+	// Params are the parameters of the stages, e.g. the public key of m.login.recaptcha.
+	Params map[string]json.RawMessage
+}
+
+// UIAStageFunc completes an interactive stage, e.g. by asking the user to accept the terms or to follow
+// the fallback web page, and returns the fields of its auth dict; the type and the session are filled in.
+type UIAStageFunc func(ctx context.Context, state UIAState) (map[string]any, error)
+
+// UIA completes the user-interactive authentication of the requests asking for it: the password,
+// the dummy and the registration token stages on its own, the other stages with Interactive.
+// https://spec.matrix.org/v1.13/client-server-api/#user-interactive-authentication-api
+type UIA struct {
+	// User is the user the password stage authenticates as, the user ID of the client by default.
+	User     string
+	Password string
+	// RegistrationToken completes the m.login.registration_token stage of the registration.
+	RegistrationToken string
+	// Interactive completes the stages UIA can't; the flows with such stages are not tried if it's nil.
+	Interactive UIAStageFunc
+}
+
+// canComplete reports whether the stage can be completed, and whether without Interactive.
+func (u UIA) canComplete(stage string) (ok, auto bool) {
+	switch {
+	case stage == AuthDummy,
+		stage == AuthPassword && u.Password != "",
+		stage == AuthRegistrationToken && u.RegistrationToken != "":
+		return true, true
+	}
+	return u.Interactive != nil, false
+}
+
+// pickFlow returns the flow continuing the completed stages with the fewest stages left to Interactive.
+func (u UIA) pickFlow(flows []apiUIAFlow, completed []string) ([]string, bool) {
+	var best []string
+	bestInteractive := -1
+	for _, flow := range flows {
+		if !continuesFlow(flow.Stages, completed) {
+			continue
+		}
+
+		interactive := 0
+		possible := true
+		for _, stage := range flow.Stages[len(completed):] {
+			ok, auto := u.canComplete(stage)
+			possible = possible && ok
+			if !auto {
+				interactive++
+			}
+		}
+		if possible && (bestInteractive < 0 || interactive < bestInteractive) {
+			best, bestInteractive = flow.Stages, interactive
+		}
+	}
+	return best, best != nil
+}
+
+// continuesFlow reports whether the flow starts with the completed stages and has stages left.
+func continuesFlow(flow, completed []string) bool {
+	return len(flow) > len(completed) && slices.Equal(flow[:len(completed)], completed)
+}
+
+func (u UIA) stageAuth(ctx context.Context, state UIAState) (map[string]any, error) {
+	auth := make(map[string]any)
+	switch {
+	case state.Stage == AuthDummy:
+	case state.Stage == AuthPassword && u.Password != "":
+		auth["identifier"] = map[string]string{"type": "m.id.user", "user": u.User}
+		auth["password"] = u.Password
+	case state.Stage == AuthRegistrationToken && u.RegistrationToken != "":
+		auth["token"] = u.RegistrationToken
+	default:
+		var err error
+		auth, err = u.Interactive(ctx, state)
+		if err != nil {
+			return nil, fmt.Errorf("failed to complete the %s stage: %w", state.Stage, err)
+		}
+	}
+
+	auth["type"] = state.Stage
+	if state.Session != "" {
+		auth["session"] = state.Session
+	}
+	return auth, nil
+}
+
+// Do sends the request with send, without the auth dict first, then with the auth dict of every stage
+// the server asks for until it accepts the request. Send must return the *HTTPError of the failed requests.
+func (u UIA) Do(ctx context.Context, send func(ctx context.Context, auth map[string]any) error) error {
+	var auth map[string]any
+	var flow []string
+	for range maxUIARounds {
+		err := send(ctx, auth)
+
+		var httpErr *HTTPError
+		if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusUnauthorized {
+			return err
+		}
+		var resp apiUIAResp
+		if json.Unmarshal(httpErr.Body, &resp) != nil || len(resp.Flows) == 0 {
+			return err
+		}
+
+		if auth != nil && resp.ErrCode != "" {
+			// the stage just submitted was rejected, e.g. the password is wrong
+			return fmt.Errorf("the %s stage failed: %w", auth["type"], err)
+		}
+
+		if !continuesFlow(flow, resp.Completed) {
+			var ok bool
+			flow, ok = u.pickFlow(resp.Flows, resp.Completed)
+			if !ok {
+				return fmt.Errorf("no supported authentication flow among %v: %w", resp.Flows, err)
+			}
+		}
+		auth, err = u.stageAuth(ctx, UIAState{
+			Session:   resp.Session,
+			Stage:     flow[len(resp.Completed)],
+			Completed: resp.Completed,
+			Params:    resp.Params,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return errors.New("too many user-interactive authentication rounds")
+}
+
+// doJSONWithUIA makes the request protected by user-interactive authentication, the auth dict being added to reqData.
+func (c *Client) doJSONWithUIA(ctx context.Context, method, path string, reqData map[string]any, uia UIA, respData any) error {
+	if uia.User == "" && uia.Password != "" {
+		userID, err := c.WhoAmI(ctx)
+		if err != nil {
+			return err
+		}
+		uia.User = userID
+	}
+
+	return uia.Do(ctx, func(ctx context.Context, auth map[string]any) error {
+		if auth != nil {
+			reqData["auth"] = auth
+		}
+		return c.doJSON(ctx, method, path, reqData, respData)
+	})
+}
+
+// DeleteDevices logs the devices out, which usually requires user-interactive authentication.
+func (c *Client) DeleteDevices(ctx context.Context, deviceIDs []string, uia UIA) error {
+	err := c.doJSONWithUIA(ctx, http.MethodPost, "/_matrix/client/v3/delete_devices", map[string]any{"devices": deviceIDs}, uia, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete the devices: %w", err)
+	}
+	return nil
+}
+
+// ChangePassword changes the password of the user, authenticating with uia, usually the current password.
+// The other devices are logged out unless logoutDevices is false.
+func (c *Client) ChangePassword(ctx context.Context, newPassword string, logoutDevices bool, uia UIA) error {
+	reqData := map[string]any{"new_password": newPassword, "logout_devices": logoutDevices}
+	err := c.doJSONWithUIA(ctx, http.MethodPost, "/_matrix/client/v3/account/password", reqData, uia, nil)
+	if err != nil {
+		return fmt.Errorf("failed to change the password: %w", err)
+	}
+
+	// the client logs in again with the new password when its token expires
+	c.mux.Lock()
+	if c.credentials.Password != "" {
+		c.credentials.Password = newPassword
+	}
+	c.mux.Unlock()
+	return nil
+}
@@ -0,0 +1,258 @@
+package gomatrix
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// maxRoomNameLength is the longest room name the spec allows, in bytes.
+const maxRoomNameLength = 255
+
+// InvalidContentError lists how the content of an event breaks the schema of its type.
+type InvalidContentError struct {
+	EventType string
+	// EventID is empty for the events not sent yet.
+	EventID  string
+	Problems []string
+}
+
+func (e *InvalidContentError) Error() string {
+	event := e.EventType
+	if e.EventID != "" {
+		event += " event " + e.EventID
+	}
+	return fmt.Sprintf("invalid %s content: %s", event, strings.Join(e.Problems, "; "))
+}
+
+// contentSchemas check the contents of the known event types, returning the problems found.
+var contentSchemas = map[string]func(content map[string]any) []string{
+	"m.room.message":            validateMessage,
+	"m.sticker":                 validateSticker,
+	"m.reaction":                validateReaction,
+	"m.room.member":             oneOf("membership", MembershipJoin, MembershipInvite, MembershipLeave, MembershipBan, MembershipKnock),
+	"m.room.join_rules":         oneOf("join_rule", JoinPublic, JoinInvite, JoinKnock, JoinRestricted, JoinKnockRestricted, JoinPrivate),
+	"m.room.history_visibility": oneOf("history_visibility", HistoryInvited, HistoryJoined, HistoryShared, HistoryWorldReadable),
+	"m.room.guest_access":       oneOf("guest_access", GuestCanJoin, GuestForbidden),
+	"m.room.name":               validateRoomName,
+	"m.room.topic":              func(content map[string]any) []string { return requireString(content, "topic", false) },
+	"m.room.avatar":             func(content map[string]any) []string { return optionalMXC(content, "url") },
+	"m.room.canonical_alias":    validateCanonicalAlias,
+	"m.room.encryption":         validateEncryption,
+	"m.room.power_levels":       validatePowerLevels,
+}
+
+// ValidateContent checks the content against the schema of the event type: the required fields, their types
+// and their ranges. The contents of the unknown event types are only checked to be JSON objects.
+func ValidateContent(eventType string, content json.RawMessage) error {
+	var fields map[string]any
+	if json.Unmarshal(content, &fields) != nil || fields == nil {
+		return &InvalidContentError{EventType: eventType, Problems: []string{"not a JSON object"}}
+	}
+
+	schema, ok := contentSchemas[eventType]
+	if !ok {
+		return nil
+	}
+	if problems := schema(fields); len(problems) > 0 {
+		return &InvalidContentError{EventType: eventType, Problems: problems}
+	}
+	return nil
+}
+
+// Validate checks the content of the event with ValidateContent. The redacted events pass, their content being empty.
+func (e Event) Validate() error {
+	if isRedacted(e) {
+		return nil
+	}
+
+	err := ValidateContent(e.Type, e.Content)
+	if invalid, ok := err.(*InvalidContentError); ok {
+		invalid.EventID = e.ID
+	}
+	return err
+}
+
+// validateOutgoing checks the content of an event about to be sent in strict mode.
+func (c *Client) validateOutgoing(eventType string, content any) error {
+	if !c.strict {
+		return nil
+	}
+
+	payload, err := json.Marshal(content)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event payload: %w", err)
+	}
+	return ValidateContent(eventType, payload)
+}
+
+// valid reports whether the received event passes the strict mode validation, logging it otherwise.
+func (c *Client) valid(ev Event) bool {
+	err := ev.Validate()
+	if err != nil {
+		c.logger.Warn("dropped an invalid event", "room_id", ev.RoomID, "sender", ev.Sender, "error", err)
+		return false
+	}
+	return true
+}
+
+func validateMessage(content map[string]any) []string {
+	problems := slices.Concat(requireString(content, "msgtype", true), requireString(content, "body", false))
+	if format, ok := content["format"]; ok {
+		if format != "org.matrix.custom.html" {
+			problems = append(problems, fmt.Sprintf("format: unknown format %v", format))
+		}
+		problems = append(problems, requireString(content, "formatted_body", false)...)
+	}
+
+	switch content["msgtype"] {
+	case string(Image), string(File), string(Audio), string(Video):
+		_, encrypted := content["file"].(map[string]any)
+		if !encrypted {
+			problems = append(problems, requireMXC(content, "url")...)
+		}
+	case "m.location":
+		problems = append(problems, requireString(content, "geo_uri", true)...)
+	}
+	return problems
+}
+
+func validateSticker(content map[string]any) []string {
+	problems := slices.Concat(requireString(content, "body", false), requireMXC(content, "url"))
+	if _, ok := content["info"].(map[string]any); !ok {
+		problems = append(problems, "info: required object")
+	}
+	return problems
+}
+
+func validateReaction(content map[string]any) []string {
+	relation, ok := content["m.relates_to"].(map[string]any)
+	if !ok {
+		return []string{"m.relates_to: required object"}
+	}
+	var problems []string
+	for _, problem := range slices.Concat(requireString(relation, "event_id", true), requireString(relation, "key", true)) {
+		problems = append(problems, "m.relates_to."+problem)
+	}
+	if relation["rel_type"] != RelAnnotation {
+		problems = append(problems, "m.relates_to.rel_type: must be "+RelAnnotation)
+	}
+	return problems
+}
+
+func validateRoomName(content map[string]any) []string {
+	problems := requireString(content, "name", false)
+	if name, ok := content["name"].(string); ok && len(name) > maxRoomNameLength {
+		problems = append(problems, fmt.Sprintf("name: longer than %d bytes", maxRoomNameLength))
+	}
+	return problems
+}
+
+func validateCanonicalAlias(content map[string]any) []string {
+	var problems []string
+	aliases := []any{content["alias"]}
+	if alts, ok := content["alt_aliases"].([]any); ok {
+		aliases = append(aliases, alts...)
+	} else if content["alt_aliases"] != nil {
+		problems = append(problems, "alt_aliases: must be an array")
+	}
+
+	for _, alias := range aliases {
+		if alias == nil {
+			continue
+		}
+		s, ok := alias.(string)
+		if _, server, found := strings.Cut(s, ":"); !ok || !strings.HasPrefix(s, "#") || !found || server == "" {
+			problems = append(problems, fmt.Sprintf("alias: %v is not a room alias", alias))
+		}
+	}
+	return problems
+}
+
+func validateEncryption(content map[string]any) []string {
+	problems := requireString(content, "algorithm", true)
+	for _, field := range []string{"rotation_period_ms", "rotation_period_msgs"} {
+		if value, ok := content[field]; ok {
+			if n, isNumber := value.(float64); !isNumber || n <= 0 || n != float64(int64(n)) {
+				problems = append(problems, field+": must be a positive integer")
+			}
+		}
+	}
+	return problems
+}
+
+func validatePowerLevels(content map[string]any) []string {
+	var problems []string
+	for _, field := range []string{"ban", "events_default", "invite", "kick", "redact", "state_default", "users_default"} {
+		if value, ok := content[field]; ok && !isPowerLevel(value) {
+			problems = append(problems, field+": must be an integer power level")
+		}
+	}
+	for _, field := range []string{"events", "users", "notifications"} {
+		levels, ok := content[field]
+		if !ok {
+			continue
+		}
+		object, ok := levels.(map[string]any)
+		if !ok {
+			problems = append(problems, field+": must be an object")
+			continue
+		}
+		for key, value := range object {
+			if !isPowerLevel(value) {
+				problems = append(problems, fmt.Sprintf("%s.%s: must be an integer power level", field, key))
+			}
+		}
+	}
+	slices.Sort(problems)
+	return problems
+}
+
+// isPowerLevel reports whether the value is an integer in the canonical JSON range.
+func isPowerLevel(value any) bool {
+	const maxSafeInteger = 1<<53 - 1
+	n, ok := value.(float64)
+	return ok && n == float64(int64(n)) && n >= -maxSafeInteger && n <= maxSafeInteger
+}
+
+func oneOf[T ~string](field string, values ...T) func(content map[string]any) []string {
+	return func(content map[string]any) []string {
+		value, ok := content[field].(string)
+		if !ok || !slices.Contains(values, T(value)) {
+			return []string{fmt.Sprintf("%s: must be one of %v", field, values)}
+		}
+		return nil
+	}
+}
+
+func requireString(content map[string]any, field string, nonEmpty bool) []string {
+	value, ok := content[field].(string)
+	switch {
+	case !ok:
+		return []string{field + ": required string"}
+	case nonEmpty && value == "":
+		return []string{field + ": must not be empty"}
+	}
+	return nil
+}
+
+func requireMXC(content map[string]any, field string) []string {
+	if _, ok := content[field]; !ok {
+		return []string{field + ": required mxc URI"}
+	}
+	return optionalMXC(content, field)
+}
+
+func optionalMXC(content map[string]any, field string) []string {
+	value, ok := content[field]
+	if !ok {
+		return nil
+	}
+	if s, isString := value.(string); !isString {
+		return []string{field + ": must be an mxc URI"}
+	} else if _, err := ParseMXC(s); err != nil && s != "" {
+		return []string{fmt.Sprintf("%s: %v", field, err)}
+	}
+	return nil
+}
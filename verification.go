@@ -0,0 +1,157 @@
+package gomatrix
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// https://spec.matrix.org/v1.13/client-server-api/#key-verification-framework
+const (
+	VerificationMethodSAS         = "m.sas.v1"
+	VerificationMethodQRShow      = "m.qr_code.show.v1"
+	VerificationMethodQRScan      = "m.qr_code.scan.v1"
+	VerificationMethodReciprocate = "m.reciprocate.v1"
+)
+
+// VerificationTransport carries the verification events between the two parties.
+type VerificationTransport interface {
+	// Send sends the verification event; the transaction ID is set on the content by the transport.
+	Send(ctx context.Context, eventType string, content map[string]any) error
+}
+
+// ToDeviceVerification sends the verification events as to-device messages to the other device.
+type ToDeviceVerification struct {
+	Client        *Client
+	UserID        string
+	DeviceID      string
+	TransactionID string
+}
+
+func (t ToDeviceVerification) Send(ctx context.Context, eventType string, content map[string]any) error {
+	content["transaction_id"] = t.TransactionID
+	return t.Client.SendToDevice(ctx, eventType, map[string]map[string]any{t.UserID: {t.DeviceID: content}})
+}
+
+// VerificationRequest asks the other device to verify the keys with one of the methods.
+func VerificationRequest(ctx context.Context, client *Client, t VerificationTransport, methods []string) error {
+	deviceID, err := client.DeviceID(ctx)
+	if err != nil {
+		return err
+	}
+	return t.Send(ctx, "m.key.verification.request", map[string]any{
+		"from_device": deviceID,
+		"methods":     methods,
+		"timestamp":   time.Now().UnixMilli(),
+	})
+}
+
+// VerificationReady accepts the verification request with the methods the client supports.
+func VerificationReady(ctx context.Context, client *Client, t VerificationTransport, methods []string) error {
+	deviceID, err := client.DeviceID(ctx)
+	if err != nil {
+		return err
+	}
+	return t.Send(ctx, "m.key.verification.ready", map[string]any{
+		"from_device": deviceID,
+		"methods":     methods,
+	})
+}
+
+func VerificationDone(ctx context.Context, t VerificationTransport) error {
+	return t.Send(ctx, "m.key.verification.done", map[string]any{})
+}
+
+func VerificationCancel(ctx context.Context, t VerificationTransport, code, reason string) error {
+	return t.Send(ctx, "m.key.verification.cancel", map[string]any{"code": code, "reason": reason})
+}
+
+// VerificationStartContent is the content of m.key.verification.start.
+type VerificationStartContent struct {
+	FromDevice    string `json:"from_device"`
+	Method        string `json:"method"`
+	TransactionID string `json:"transaction_id,omitempty"`
+	// Secret is the shared secret of the scanned QR code for the reciprocate method.
+	Secret string `json:"secret,omitempty"`
+}
+
+// InRoomVerification sends the verification events to the direct chat with the other user,
+// related to the request message. Element verifies other users this way by default.
+// https://spec.matrix.org/v1.13/client-server-api/#key-verification-framework
+type InRoomVerification struct {
+	Client         *Client
+	RoomID         string
+	RequestEventID string
+}
+
+func (t InRoomVerification) Send(ctx context.Context, eventType string, content map[string]any) error {
+	content["m.relates_to"] = Relation{Type: RelReference, EventID: t.RequestEventID}
+	_, err := t.Client.sendEvent(ctx, t.RoomID, eventType, content)
+	return err
+}
+
+type VerificationRequestContent struct {
+	MsgType    string   `json:"msgtype,omitempty"`
+	Body       string   `json:"body,omitempty"`
+	To         string   `json:"to,omitempty"`
+	FromDevice string   `json:"from_device"`
+	Methods    []string `json:"methods"`
+	Timestamp  int64    `json:"timestamp,omitempty"`
+}
+
+// RequestInRoomVerification sends the verification request to the user as a message in the room,
+// usually the direct chat with them, and returns the transport for the rest of the verification.
+func (c *Client) RequestInRoomVerification(
+	ctx context.Context, roomID, userID string, methods []string,
+) (InRoomVerification, error) {
+	deviceID, err := c.DeviceID(ctx)
+	if err != nil {
+		return InRoomVerification{}, err
+	}
+	ownUserID, err := c.WhoAmI(ctx)
+	if err != nil {
+		return InRoomVerification{}, err
+	}
+
+	eventID, err := c.sendEvent(ctx, roomID, "m.room.message", VerificationRequestContent{
+		MsgType:    "m.key.verification.request",
+		Body:       ownUserID + " is requesting to verify your key, but your client does not support in-chat key verification.",
+		To:         userID,
+		FromDevice: deviceID,
+		Methods:    methods,
+	})
+	if err != nil {
+		return InRoomVerification{}, fmt.Errorf("failed to request the verification: %w", err)
+	}
+
+	return InRoomVerification{Client: c, RoomID: roomID, RequestEventID: eventID}, nil
+}
+
+// ParseInRoomVerificationRequest returns the verification request carried by the message event and the transport
+// to answer it with; ok is false if the event is not a verification request addressed to the user.
+func (c *Client) ParseInRoomVerificationRequest(
+	ev Event, userID string,
+) (content VerificationRequestContent, t InRoomVerification, ok bool) {
+	if ev.Type != "m.room.message" || ev.ParseContent(&content) != nil {
+		return content, t, false
+	}
+	if content.MsgType != "m.key.verification.request" || content.To != userID {
+		return content, t, false
+	}
+	return content, InRoomVerification{Client: c, RoomID: ev.RoomID, RequestEventID: ev.ID}, true
+}
+
+// VerificationTransactionID returns the ID the verification event belongs to: the transaction ID
+// of a to-device event or the request event ID of an in-room one.
+func VerificationTransactionID(ev Event) string {
+	var content struct {
+		TransactionID string `json:"transaction_id"`
+	}
+	if ev.ParseContent(&content) == nil && content.TransactionID != "" {
+		return content.TransactionID
+	}
+	if rel, ok := ev.Relation(); ok && rel.Type == RelReference {
+		return rel.EventID
+	}
+	return ""
+}
@@ -0,0 +1,116 @@
+package gomatrix
+
+import (
+	"context"
+	"errors"
+)
+
+const (
+	// WidgetEventType is the state event type of the room widgets Element and the other clients render.
+	WidgetEventType = "im.vector.modular.widgets"
+	// UnstableWidgetEventType is the state event type of the room widgets proposed for the spec in MSC1236.
+	UnstableWidgetEventType = "m.widget"
+
+	WidgetTypeJitsi  = "jitsi"
+	WidgetTypeCustom = "m.custom"
+
+	// jitsiWidgetURL is the Jitsi wrapper hosted by Element; the clients fill in the $ variables from the widget data.
+	jitsiWidgetURL = "https://app.element.io/jitsi.html#conferenceDomain=$domain&conferenceId=$conferenceId" +
+		"&isAudioOnly=$isAudioOnly&displayName=$matrix_display_name&avatarUrl=$matrix_avatar_url" +
+		"&userId=$matrix_user_id&roomId=$matrix_room_id&theme=$theme&roomName=$roomName"
+)
+
+// Widget is an embedded web application of a room; its state key is the widget ID.
+// https://github.com/matrix-org/matrix-spec-proposals/pull/1236
+type Widget struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	// URL may hold $ variables, e.g. $matrix_user_id, replaced by the clients with the values of Data or of the user.
+	URL               string         `json:"url"`
+	Name              string         `json:"name,omitempty"`
+	Data              map[string]any `json:"data,omitempty"`
+	CreatorUserID     string         `json:"creatorUserId,omitempty"`
+	WaitForIframeLoad bool           `json:"waitForIframeLoad,omitempty"`
+}
+
+// AddWidget adds the widget to the room, replacing the widget of the same ID, and returns the widget ID.
+// A widget ID is generated if it's empty; the creator defaults to the user of the client.
+func (c *Client) AddWidget(ctx context.Context, roomID string, widget Widget) (string, error) {
+	if widget.Type == "" || widget.URL == "" {
+		return "", errors.New("the widget type and URL are required")
+	}
+	if widget.ID == "" {
+		id, err := randomString(16)
+		if err != nil {
+			return "", err
+		}
+		widget.ID = id
+	}
+	if widget.CreatorUserID == "" {
+		userID, err := c.WhoAmI(ctx)
+		if err != nil {
+			return "", err
+		}
+		widget.CreatorUserID = userID
+	}
+
+	_, err := c.SetStateEvent(ctx, roomID, WidgetEventType, widget.ID, widget)
+	if err != nil {
+		return "", err
+	}
+	return widget.ID, nil
+}
+
+// RemoveWidget removes the widget from the room by emptying its state event.
+func (c *Client) RemoveWidget(ctx context.Context, roomID, widgetID string) error {
+	_, err := c.SetStateEvent(ctx, roomID, WidgetEventType, widgetID, struct{}{})
+	return err
+}
+
+// GetWidgets returns the widgets of the room, of both the Element and the MSC1236 state event types.
+func (c *Client) GetWidgets(ctx context.Context, roomID string) ([]Widget, error) {
+	state, err := c.GetRoomState(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	var widgets []Widget
+	for _, ev := range append(state.OfType(WidgetEventType), state.OfType(UnstableWidgetEventType)...) {
+		var widget Widget
+		// the removed widgets have empty content
+		if ev.ParseContent(&widget) != nil || widget.URL == "" {
+			continue
+		}
+		if widget.ID == "" {
+			widget.ID = ev.GetStateKey()
+		}
+		widgets = append(widgets, widget)
+	}
+	return widgets, nil
+}
+
+// AddJitsiCall adds a Jitsi conference widget to the room, e.g. a permanent call of a meeting room, and returns
+// the widget ID. The conference ID is generated if it's empty.
+func (c *Client) AddJitsiCall(ctx context.Context, roomID, domain, conferenceID string) (string, error) {
+	if domain == "" {
+		return "", errors.New("the Jitsi domain is required")
+	}
+	if conferenceID == "" {
+		id, err := randomString(24)
+		if err != nil {
+			return "", err
+		}
+		conferenceID = "Jitsi" + id
+	}
+
+	return c.AddWidget(ctx, roomID, Widget{
+		Type: WidgetTypeJitsi,
+		URL:  jitsiWidgetURL,
+		Name: "Jitsi",
+		Data: map[string]any{
+			"domain":       domain,
+			"conferenceId": conferenceID,
+			"isAudioOnly":  false,
+		},
+	})
+}
@@ -0,0 +1,113 @@
+package gomatrix
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+)
+
+const defaultWorkerQueueDepth = 100
+
+type WorkerPoolConfig struct {
+	// Size is the number of workers running the Listen handler; zero runs it on the sync loop.
+	Size int
+	// QueueDepth is how many events wait for every worker before the sync loop blocks, 100 by default.
+	QueueDepth int
+	// OnPanic is called with the event whose handler panicked and the recovered value; the worker carries on.
+	OnPanic func(ev Event, recovered any)
+}
+
+type queuedEvent struct {
+	ctx   context.Context
+	ev    Event
+	batch *syncBatch
+}
+
+// syncBatch tracks the events of a sync queued for the workers, the sync position being saved once they're handled.
+type syncBatch struct {
+	pending sync.WaitGroup
+	dropped atomic.Bool
+}
+
+// handled waits for the events of the sync to be handled and reports whether none was dropped.
+func (b *syncBatch) handled() bool {
+	b.pending.Wait()
+	return !b.dropped.Load()
+}
+
+// workerPool runs the handler on several workers, the events of a room always going to the same worker
+// so they are handled in order.
+type workerPool struct {
+	cfg     WorkerPoolConfig
+	handler EventHandler
+	client  *Client
+	queues  []chan queuedEvent
+	wg      sync.WaitGroup
+	// batch is the sync being dispatched, only the sync loop touches it
+	batch *syncBatch
+}
+
+func newWorkerPool(c *Client, cfg WorkerPoolConfig, handler EventHandler) *workerPool {
+	if cfg.QueueDepth <= 0 {
+		cfg.QueueDepth = defaultWorkerQueueDepth
+	}
+
+	p := &workerPool{cfg: cfg, handler: handler, client: c, queues: make([]chan queuedEvent, cfg.Size), batch: &syncBatch{}}
+	for i := range p.queues {
+		p.queues[i] = make(chan queuedEvent, cfg.QueueDepth)
+		p.wg.Add(1)
+		go p.work(p.queues[i])
+	}
+	return p
+}
+
+// startBatch makes the events dispatched from now on part of a new sync.
+func (p *workerPool) startBatch() *syncBatch {
+	p.batch = &syncBatch{}
+	return p.batch
+}
+
+// dispatch queues the event for the worker of its room, waiting while the queue is full.
+func (p *workerPool) dispatch(ctx context.Context, ev Event) {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(ev.RoomID))
+	queue := p.queues[hash.Sum32()%uint32(len(p.queues))]
+
+	batch := p.batch
+	batch.pending.Add(1)
+	select {
+	case queue <- queuedEvent{ctx: ctx, ev: ev, batch: batch}:
+	case <-ctx.Done():
+		batch.dropped.Store(true)
+		batch.pending.Done()
+	}
+}
+
+func (p *workerPool) work(queue <-chan queuedEvent) {
+	defer p.wg.Done()
+	for item := range queue {
+		p.handle(item)
+	}
+}
+
+func (p *workerPool) handle(item queuedEvent) {
+	defer item.batch.pending.Done()
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			p.client.logger.Error("event handler panicked", "event_id", item.ev.ID, "room_id", item.ev.RoomID, "panic", recovered)
+			if p.cfg.OnPanic != nil {
+				p.cfg.OnPanic(item.ev, recovered)
+			}
+		}
+	}()
+	p.handler(item.ctx, item.ev)
+}
+
+// stop waits for the workers to handle the queued events.
+func (p *workerPool) stop() {
+	for _, queue := range p.queues {
+		close(queue)
+	}
+	p.wg.Wait()
+}